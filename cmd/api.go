@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mermaidascii"
+	"github.com/gin-gonic/gin"
+	"github.com/mattn/go-runewidth"
+	log "github.com/sirupsen/logrus"
+)
+
+// openAPISpec describes the /api/v1 endpoints.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiRenderRequest is the body of POST /api/v1/render. Config fields that are
+// left out keep the server's defaults.
+type apiRenderRequest struct {
+	Source string          `json:"source"`
+	Format string          `json:"format,omitempty"` // overrides config.outputFormat
+	Config *diagram.Config `json:"config,omitempty"`
+}
+
+type apiRenderResponse struct {
+	Type   string          `json:"type"`
+	Format string          `json:"format"`
+	Output string          `json:"output,omitempty"`
+	Layout json.RawMessage `json:"layout,omitempty"`
+	// Width and Height are in character cells, or pixels for SVG output.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+	// Field is the config field that was rejected, for invalid configs.
+	Field string `json:"field,omitempty"`
+	// Line and Column (1-based) point at the source text the error is about,
+	// when it could be found.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+func abortWithAPIError(c *gin.Context, status int, e apiError) {
+	c.Abort()
+	c.PureJSON(status, gin.H{"error": e})
+}
+
+func serveOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+// apiRender renders the diagram in a JSON request and describes the result.
+func (s *server) apiRender(c *gin.Context) {
+	// Fields the request leaves out keep these values.
+	req := apiRenderRequest{Config: diagram.DefaultConfig()}
+	req.Config.BoxBorderPadding = s.boxBorderPadding
+	req.Config.PaddingBetweenX = s.paddingX
+	req.Config.PaddingBetweenY = s.paddingY
+	req.Config.Verbose = s.verbose
+
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, apiError{Message: "invalid request body: " + err.Error()})
+		return
+	}
+	if req.Config == nil {
+		abortWithAPIError(c, http.StatusBadRequest, apiError{Message: "config must be an object"})
+		return
+	}
+	if req.Format != "" {
+		req.Config.OutputFormat = req.Format
+	}
+	if req.Config.OutputFormat == "" {
+		req.Config.OutputFormat = "text"
+	}
+	if err := req.Config.Validate(); err != nil {
+		e := apiError{Message: err.Error()}
+		var configErr *diagram.ConfigError
+		if errors.As(err, &configErr) {
+			e.Field = configJSONName(configErr.Field)
+		}
+		abortWithAPIError(c, http.StatusBadRequest, e)
+		return
+	}
+
+	source, _ := diagram.StripFrontmatter(req.Source)
+	diag, err := mermaidascii.DiagramFactory(source)
+	if err != nil {
+		abortWithAPIError(c, http.StatusUnprocessableEntity, apiError{Message: err.Error()})
+		return
+	}
+
+	output, err := s.render(c.Request.Context(), req.Source, req.Config)
+	var configErr *diagram.ConfigError
+	if errors.As(err, &configErr) {
+		// Like a layout engine nobody registered, which only the renderer
+		// can tell.
+		abortWithAPIError(c, http.StatusBadRequest, apiError{Message: err.Error(), Field: configJSONName(configErr.Field)})
+		return
+	}
+	if err != nil {
+		log.Debugf("Rendering failed: %v", err)
+		line, column := locateError(req.Source, err)
+		abortWithAPIError(c, http.StatusUnprocessableEntity, apiError{Message: err.Error(), Line: line, Column: column})
+		return
+	}
+
+	resp := apiRenderResponse{Type: diag.Type(), Format: req.Config.OutputFormat}
+	switch resp.Format {
+	case "json":
+		resp.Layout = json.RawMessage(output)
+		var size struct{ Width, Height int }
+		if err := json.Unmarshal(resp.Layout, &size); err == nil {
+			resp.Width, resp.Height = size.Width, size.Height
+		}
+	case "svg":
+		resp.Output = output
+		resp.Width, resp.Height = svgSize(output)
+	default:
+		resp.Output = output
+		resp.Width, resp.Height = textSize(output, req.Config.StyleType == "html")
+	}
+	c.PureJSON(http.StatusOK, resp)
+}
+
+// configJSONName returns the JSON name of the diagram.Config field called
+// field, which is how API clients know it.
+func configJSONName(field string) string {
+	f, ok := reflect.TypeOf(diagram.Config{}).FieldByName(field)
+	if !ok {
+		return field
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// textSize measures a text drawing in character cells, ignoring the colour
+// markup of html output.
+func textSize(output string, html bool) (int, int) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return 0, 0
+	}
+	lines := strings.Split(output, "\n")
+	width := 0
+	for _, l := range lines {
+		if html {
+			l = htmlTagPattern.ReplaceAllString(l, "")
+		}
+		width = max(width, runewidth.StringWidth(l))
+	}
+	return width, len(lines)
+}
+
+// svgSize reads the width and height of an SVG document's root element.
+func svgSize(output string) (int, int) {
+	dec := xml.NewDecoder(strings.NewReader(output))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			var width, height int
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "width":
+					width, _ = strconv.Atoi(attr.Value)
+				case "height":
+					height, _ = strconv.Atoi(attr.Value)
+				}
+			}
+			return width, height
+		}
+	}
+}
+
+var (
+	// Parsers quote the text they reject with %q or in single quotes.
+	doubleQuotedPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	singleQuotedPattern = regexp.MustCompile(`'([^']+)'`)
+)
+
+// locateError finds the 1-based line and column in source that err is
+// about. The line is the one the parser reported; the column is where the
+// text err quotes starts on that line, or zero if it quotes nothing there.
+// It returns zeros when err isn't about a line.
+func locateError(source string, err error) (int, int) {
+	var lineErr *diagram.LineError
+	if !errors.As(err, &lineErr) {
+		return 0, 0
+	}
+	lines := diagram.SplitLines(strings.ReplaceAll(source, "\r\n", "\n"))
+	if lineErr.Line < 1 || lineErr.Line > len(lines) {
+		return lineErr.Line, 0
+	}
+	line := lines[lineErr.Line-1]
+
+	msg := lineErr.Err.Error()
+	var candidates []string
+	for _, q := range doubleQuotedPattern.FindAllString(msg, -1) {
+		if s, err := strconv.Unquote(q); err == nil {
+			candidates = append(candidates, strings.TrimSpace(s))
+		}
+	}
+	for _, m := range singleQuotedPattern.FindAllStringSubmatch(msg, -1) {
+		candidates = append(candidates, strings.TrimSpace(m[1]))
+	}
+	for _, text := range candidates {
+		if text == "" {
+			continue
+		}
+		if idx := strings.Index(line, text); idx != -1 {
+			return lineErr.Line, utf8.RuneCountInString(line[:idx]) + 1
+		}
+	}
+	return lineErr.Line, 0
+}
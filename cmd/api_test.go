@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mermaidascii"
+	"github.com/gin-gonic/gin"
+)
+
+func apiRouter() *gin.Engine {
+	s := newServer(1, 5, 5, false, newRenderCache(1<<20, time.Hour))
+	r := testRouter(s)
+	r.POST("/api/v1/render", s.apiRender)
+	r.GET("/api/v1/openapi.json", serveOpenAPI)
+	return r
+}
+
+func postJSON(t *testing.T, r http.Handler, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/render", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response is not JSON: %v\n%s", err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+func TestAPIRenderText(t *testing.T) {
+	code, resp := postJSON(t, apiRouter(), `{"source": "graph LR\nA --> B", "config": {"useAscii": true, "paddingBetweenX": 2}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d: %v", code, resp)
+	}
+
+	config := diagram.DefaultConfig()
+	config.UseAscii = true
+	config.PaddingBetweenX = 2
+	want, err := mermaidascii.Render(context.Background(), "graph LR\nA --> B", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp["output"] != want {
+		t.Errorf("output:\n%v\nwant:\n%s", resp["output"], want)
+	}
+	lines := strings.Split(strings.TrimRight(want, "\n"), "\n")
+	if resp["type"] != "graph" || resp["format"] != "text" || resp["width"] != float64(len(lines[0])) || resp["height"] != float64(len(lines)) {
+		t.Errorf("type, format, width, height = %v, %v, %v, %v", resp["type"], resp["format"], resp["width"], resp["height"])
+	}
+}
+
+func TestAPIRenderLayoutAndSVG(t *testing.T) {
+	r := apiRouter()
+
+	code, resp := postJSON(t, r, `{"source": "sequenceDiagram\nAlice->>Bob: Hi", "format": "json"}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d: %v", code, resp)
+	}
+	layout, ok := resp["layout"].(map[string]any)
+	if !ok || resp["output"] != nil {
+		t.Fatalf("json format should return a layout object and no output: %v", resp)
+	}
+	if resp["type"] != "sequence" || layout["width"] != resp["width"] || len(layout["participants"].([]any)) != 2 {
+		t.Errorf("unexpected layout response: %v", resp)
+	}
+
+	code, resp = postJSON(t, r, `{"source": "graph TD\nA --> B", "config": {"outputFormat": "svg"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d: %v", code, resp)
+	}
+	if out, _ := resp["output"].(string); !strings.HasPrefix(out, "<svg") || resp["width"].(float64) <= 0 {
+		t.Errorf("unexpected svg response: %v", resp)
+	}
+}
+
+func TestAPIRenderErrors(t *testing.T) {
+	r := apiRouter()
+	for _, tc := range []struct {
+		name, body string
+		status     int
+		want       map[string]any
+	}{
+		{"malformed body", `{"source": `, http.StatusBadRequest, nil},
+		{"invalid config", `{"source": "graph LR\nA --> B", "config": {"paddingBetweenX": -1}}`, http.StatusBadRequest,
+			map[string]any{"field": "paddingBetweenX"}},
+		{"unknown layout", `{"source": "graph LR\nA --> B", "config": {"layout": "circular"}}`, http.StatusBadRequest,
+			map[string]any{"field": "layout"}},
+		{"unknown format", `{"source": "graph LR\nA --> B", "format": "png"}`, http.StatusBadRequest,
+			map[string]any{"field": "outputFormat"}},
+		{"syntax error after a comment", "{\"source\": \"sequenceDiagram\\n%% note\\nAlice->>Bob: Hi\\n  what is this\"}", http.StatusUnprocessableEntity,
+			map[string]any{"line": float64(4), "column": float64(3)}},
+		{"bad graph header", `{"source": "graph sideways\nA --> B"}`, http.StatusUnprocessableEntity,
+			map[string]any{"line": float64(1), "column": float64(7)}},
+		{"quoted text on an earlier line", `{"source": "gantt\n    dateFormat YYYY-MM-DD\n    section Work\n    Prep : 2024-01-01, 1d\n    Work : after nowhere, 1d"}`, http.StatusUnprocessableEntity,
+			map[string]any{"line": float64(5), "column": float64(5)}},
+		{"syntax error after frontmatter", `{"source": "---\ntitle: Pets\n---\npie\n  \"Work\" : 1\n  \"Dogs\" : -3"}`, http.StatusUnprocessableEntity,
+			map[string]any{"line": float64(6), "column": float64(4)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			code, resp := postJSON(t, r, tc.body)
+			if code != tc.status {
+				t.Fatalf("status = %d, want %d: %v", code, tc.status, resp)
+			}
+			e, ok := resp["error"].(map[string]any)
+			if !ok || e["message"] == "" {
+				t.Fatalf("response has no error message: %v", resp)
+			}
+			for k, v := range tc.want {
+				if e[k] != v {
+					t.Errorf("error %s = %v, want %v (%v)", k, e[k], v, e)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocumentDescribesRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	apiRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas struct {
+				Config struct {
+					Properties map[string]any `json:"properties"`
+				} `json:"Config"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not JSON: %v", err)
+	}
+	if _, ok := spec.Paths["/api/v1/render"]["post"]; !ok || !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("spec doesn't describe POST /api/v1/render: %s", w.Body.String())
+	}
+
+	// Every config field the endpoint accepts is documented.
+	fields, err := json.Marshal(diagram.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]any
+	if err := json.Unmarshal(fields, &config); err != nil {
+		t.Fatal(err)
+	}
+	for name := range config {
+		if _, ok := spec.Components.Schemas.Config.Properties[name]; !ok {
+			t.Errorf("config field %s is missing from the OpenAPI Config schema", name)
+		}
+	}
+}
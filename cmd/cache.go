@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// cacheKey identifies a render: a hash of the normalised input and every
+// field of the config it was rendered with.
+type cacheKey [sha256.Size]byte
+
+// entryOverhead approximates what an entry costs beyond its rendered bytes:
+// the key, list element and map slot.
+const entryOverhead = 128
+
+func newCacheKey(input string, config *diagram.Config) cacheKey {
+	h := sha256.New()
+	io.WriteString(h, input)
+	h.Write([]byte{0})
+	// Config is a flat struct of strings, ints and bools, so encoding can't
+	// fail, and a field added later is part of the key without touching this.
+	c, _ := json.Marshal(config)
+	h.Write(c)
+	var key cacheKey
+	h.Sum(key[:0])
+	return key
+}
+
+// normaliseInput drops differences that can't change the drawing, Windows
+// line endings and trailing blank space, so inputs that only differ in them
+// are rendered and cached once.
+func normaliseInput(input string) string {
+	return strings.TrimRight(strings.ReplaceAll(input, "\r\n", "\n"), " \t\r\n")
+}
+
+type renderCacheEntry struct {
+	key     cacheKey
+	value   string
+	expires time.Time
+}
+
+// renderCache is a least-recently-used cache of rendered diagrams, bounded by
+// the bytes it holds, whose entries expire ttl after they were stored.
+type renderCache struct {
+	maxBytes int
+	ttl      time.Duration // zero keeps entries until they're evicted
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List // most recently used first
+	bytes   int
+
+	hits, misses, evicted, expired uint64
+}
+
+// newRenderCache returns a cache holding up to maxBytes; zero disables it.
+func newRenderCache(maxBytes int, ttl time.Duration) *renderCache {
+	return &renderCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func entrySize(value string) int {
+	return len(value) + entryOverhead
+}
+
+func (c *renderCache) get(key cacheKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if ok && c.ttl > 0 && !c.now().Before(el.Value.(*renderCacheEntry).expires) {
+		c.remove(el)
+		c.expired++
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*renderCacheEntry).value, true
+}
+
+func (c *renderCache) put(key cacheKey, value string) {
+	size := entrySize(value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	if size > c.maxBytes {
+		return
+	}
+	for c.bytes+size > c.maxBytes {
+		c.remove(c.order.Back())
+		c.evicted++
+	}
+	entry := &renderCacheEntry{key: key, value: value, expires: c.now().Add(c.ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += size
+}
+
+// remove drops el from the cache; c.mu must be held.
+func (c *renderCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*renderCacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entrySize(entry.value)
+}
+
+// writeMetrics writes the cache's counters and size in the Prometheus text
+// exposition format.
+func (c *renderCache) writeMetrics(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metric := func(name, kind, help string, samples ...string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, s := range samples {
+			fmt.Fprintf(w, "%s%s\n", name, s)
+		}
+	}
+	metric("mermaid_ascii_cache_hits_total", "counter", "Renders served from the cache.",
+		fmt.Sprintf(" %d", c.hits))
+	metric("mermaid_ascii_cache_misses_total", "counter", "Renders not found in the cache.",
+		fmt.Sprintf(" %d", c.misses))
+	metric("mermaid_ascii_cache_evictions_total", "counter", "Entries removed from the cache, by reason.",
+		fmt.Sprintf(`{reason="size"} %d`, c.evicted), fmt.Sprintf(`{reason="ttl"} %d`, c.expired))
+	metric("mermaid_ascii_cache_entries", "gauge", "Renders currently cached.",
+		fmt.Sprintf(" %d", len(c.entries)))
+	metric("mermaid_ascii_cache_bytes", "gauge", "Approximate size of the cached renders.",
+		fmt.Sprintf(" %d", c.bytes))
+	metric("mermaid_ascii_cache_max_bytes", "gauge", "Size the cache is limited to.",
+		fmt.Sprintf(" %d", c.maxBytes))
+}
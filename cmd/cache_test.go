@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+func TestRenderCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	value := strings.Repeat("x", 100)
+	c := newRenderCache(3*entrySize(value), 0)
+	config := diagram.DefaultConfig()
+	a, b, d := newCacheKey("a", config), newCacheKey("b", config), newCacheKey("d", config)
+
+	c.put(a, value)
+	c.put(b, value)
+	c.put(newCacheKey("c", config), value)
+	if _, ok := c.get(a); !ok { // a is now more recent than b
+		t.Fatal("a should be cached")
+	}
+	c.put(d, value)
+
+	if _, ok := c.get(b); ok {
+		t.Error("b was least recently used and should have been evicted")
+	}
+	for _, key := range []cacheKey{a, d} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("%x should still be cached", key)
+		}
+	}
+	if c.evicted != 1 || c.bytes != 3*entrySize(value) {
+		t.Errorf("evicted = %d, bytes = %d; want 1 and %d", c.evicted, c.bytes, 3*entrySize(value))
+	}
+}
+
+func TestRenderCacheExpiresEntries(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newRenderCache(1<<20, time.Minute)
+	c.now = func() time.Time { return now }
+	key := newCacheKey("graph LR\nA --> B", diagram.DefaultConfig())
+
+	c.put(key, "drawing")
+	now = now.Add(59 * time.Second)
+	if _, ok := c.get(key); !ok {
+		t.Fatal("entry should be served before its TTL is up")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.get(key); ok {
+		t.Fatal("entry should expire after its TTL")
+	}
+	if c.expired != 1 || len(c.entries) != 0 || c.bytes != 0 {
+		t.Errorf("expired = %d, entries = %d, bytes = %d; want 1, 0, 0", c.expired, len(c.entries), c.bytes)
+	}
+}
+
+func TestRenderCacheSkipsOversizedAndDisabled(t *testing.T) {
+	key := newCacheKey("a", diagram.DefaultConfig())
+	for _, maxBytes := range []int{0, entrySize("small")} {
+		c := newRenderCache(maxBytes, 0)
+		c.put(key, "too large for the cache")
+		if _, ok := c.get(key); ok || c.bytes != 0 {
+			t.Errorf("maxBytes %d: oversized entry was cached (%d bytes)", maxBytes, c.bytes)
+		}
+	}
+}
+
+func TestCacheKeyCoversInputAndConfig(t *testing.T) {
+	base := diagram.DefaultConfig()
+	key := newCacheKey("graph LR\nA --> B", base)
+
+	if got := newCacheKey(normaliseInput("graph LR\r\nA --> B \n\n"), base); got != key {
+		t.Error("line endings and trailing whitespace should not change the key")
+	}
+	if got := newCacheKey("graph LR\nA --> C", base); got == key {
+		t.Error("a different input should change the key")
+	}
+	for name, change := range map[string]func(*diagram.Config){
+		"UseAscii":         func(c *diagram.Config) { c.UseAscii = true },
+		"BoxBorderPadding": func(c *diagram.Config) { c.BoxBorderPadding = 3 },
+		"OutputFormat":     func(c *diagram.Config) { c.OutputFormat = "svg" },
+		"SequenceSpacing":  func(c *diagram.Config) { c.SequenceParticipantSpacing = 9 },
+	} {
+		config := *base
+		change(&config)
+		if newCacheKey("graph LR\nA --> B", &config) == key {
+			t.Errorf("changing %s should change the key", name)
+		}
+	}
+}
+
+func TestMetricsReportsCacheCounters(t *testing.T) {
+	r := testRouter(newServer(1, 5, 5, false, newRenderCache(1<<20, time.Hour)))
+	form := url.Values{"mermaid": {"graph LR\nA --> B"}}
+	postForm(t, r, "/", form)
+	form.Set("mermaid", "graph LR\r\nA --> B\n")
+	postForm(t, r, "/", form)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("GET /metrics = %d, %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE mermaid_ascii_cache_hits_total counter\nmermaid_ascii_cache_hits_total 1\n",
+		"mermaid_ascii_cache_misses_total 1\n",
+		`mermaid_ascii_cache_evictions_total{reason="size"} 0` + "\n",
+		`mermaid_ascii_cache_evictions_total{reason="ttl"} 0` + "\n",
+		"mermaid_ascii_cache_entries 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mermaidascii"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +20,11 @@ var paddingBetweenX = 5
 var paddingBetweenY = 5
 var graphDirection = "LR"
 var useAscii = false
+var outputFormat = "text"
+var layout = "grid"
+var compact = false
+var ganttWidth = 60
+var timelineWidth = 80
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -63,9 +70,17 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("Invalid configuration: %v", err)
 		}
+		config.OutputFormat = outputFormat
+		config.Layout = layout
+		config.Compact = compact
+		config.GanttWidth = ganttWidth
+		config.TimelineWidth = timelineWidth
+		if err := config.Validate(); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
 
 		// Render diagram (automatically detects type)
-		output, err := RenderDiagram(string(mermaid), config)
+		output, err := mermaidascii.Render(context.Background(), string(mermaid), config)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -93,6 +108,11 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&paddingBetweenX, "paddingX", "x", paddingBetweenX, "Horizontal space between nodes")
 	rootCmd.PersistentFlags().IntVarP(&paddingBetweenY, "paddingY", "y", paddingBetweenY, "Vertical space between nodes")
 	rootCmd.PersistentFlags().IntVarP(&boxBorderPadding, "borderPadding", "p", boxBorderPadding, "Padding between text and border")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", outputFormat, "Output format: text, svg, or json for the diagram's layout")
+	rootCmd.PersistentFlags().StringVar(&layout, "layout", layout, "Graph layout engine: grid, or layered to keep edge crossings down")
+	rootCmd.PersistentFlags().BoolVar(&compact, "compact", compact, "Shrink the space between graph nodes to what the edges need")
+	rootCmd.PersistentFlags().IntVar(&ganttWidth, "ganttWidth", ganttWidth, "Width of the time axis of gantt charts")
+	rootCmd.PersistentFlags().IntVar(&timelineWidth, "timelineWidth", timelineWidth, "Width timeline periods wrap at")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -1,31 +1,48 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mermaidascii"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
 )
 
-// Add the resultCache variable with additional fields
-var (
-	resultCache = struct {
-		sync.RWMutex
-		m map[string]cacheEntry
-	}{m: make(map[string]cacheEntry)}
-	maxCacheSize = 10000 // Maximum number of entries in the cache
-)
+// Web command flags
+var cacheMaxBytes = 64 << 20
+var cacheTTL = time.Hour
+
+// server renders the diagrams posted to the web interface. Everything a
+// request can change is read into that request's diagram.Config, so
+// concurrent requests never see each other's options.
+type server struct {
+	// Defaults from the command line for options a request leaves out.
+	boxBorderPadding int
+	paddingX         int
+	paddingY         int
+	verbose          bool
+
+	cache *renderCache
+}
 
-type cacheEntry struct {
-	value string
+func newServer(boxBorderPadding, paddingX, paddingY int, verbose bool, cache *renderCache) *server {
+	return &server{
+		boxBorderPadding: boxBorderPadding,
+		paddingX:         paddingX,
+		paddingY:         paddingY,
+		verbose:          verbose,
+		cache:            cache,
+	}
 }
 
 var (
@@ -35,6 +52,8 @@ var (
 
 func init() {
 	rootCmd.AddCommand(webCmd)
+	webCmd.Flags().IntVar(&cacheMaxBytes, "cacheMaxBytes", cacheMaxBytes, "Maximum size of cached renders in bytes (0 disables the cache)")
+	webCmd.Flags().DurationVar(&cacheTTL, "cacheTTL", cacheTTL, "How long a cached render is served before it is rendered again (0 keeps it until evicted)")
 }
 
 var webCmd = &cobra.Command{
@@ -46,7 +65,8 @@ var webCmd = &cobra.Command{
 		} else {
 			log.SetLevel(log.InfoLevel)
 		}
-		r := setupRouter()
+		cache := newRenderCache(cacheMaxBytes, cacheTTL)
+		r := setupRouter(newServer(boxBorderPadding, paddingBetweenX, paddingBetweenY, Verbose, cache))
 		// Listen and Server in 0.0.0.0:8080
 		err := r.Run(":3001")
 		if err != nil {
@@ -71,7 +91,7 @@ func getGitVersion() string {
 	return gitVersion
 }
 
-func setupRouter() *gin.Engine {
+func setupRouter(s *server) *gin.Engine {
 	r := gin.Default()
 
 	r.LoadHTMLGlob("templates/*")
@@ -82,85 +102,91 @@ func setupRouter() *gin.Engine {
 		})
 	})
 
-	r.POST("/", renderMermaid)
+	r.POST("/", s.renderMermaid)
 
 	// Backwards compatibility
-	r.POST("/generate", renderMermaid)
+	r.POST("/generate", s.renderMermaid)
+
+	r.GET("/metrics", s.metrics)
+
+	api := r.Group("/api/v1")
+	api.POST("/render", s.apiRender)
+	api.GET("/openapi.json", serveOpenAPI)
 
 	return r
 }
 
-func renderMermaid(c *gin.Context) {
-	mermaidString := c.PostForm("mermaid")
-	// Parse xPadding and yPadding as integers
-	xPadding := c.PostForm("xPadding")
-	if xPadding != "" {
-		if padding, err := strconv.Atoi(xPadding); err == nil {
-			paddingBetweenX = padding
-		} else {
-			log.Warnf("Invalid xPadding value: %s", xPadding)
-		}
-	}
-
-	yPadding := c.PostForm("yPadding")
-	if yPadding != "" {
-		if padding, err := strconv.Atoi(yPadding); err == nil {
-			paddingBetweenY = padding
-		} else {
-			log.Warnf("Invalid yPadding value: %s", yPadding)
-		}
+// intFormValue reads the integer form field name, falling back to def when it
+// is missing or not a number.
+func intFormValue(c *gin.Context, name string, def int) int {
+	value := c.PostForm(name)
+	if value == "" {
+		return def
 	}
-	useExtendedCharsData := c.PostForm("useExtendedChars")
-	useAsciiMode := useExtendedCharsData == ""
-	log.Debugf("Received input %s", c.Request.PostForm.Encode())
-
-	// Create a cache key using the input parameters
-	cacheKey := mermaidString + "x" + xPadding + "y" + yPadding + "e" + useExtendedCharsData
-
-	// Check if the result is already in the cache
-	resultCache.RLock()
-	entry, found := resultCache.m[cacheKey]
-	resultCache.RUnlock()
-
-	if found {
-		log.Infof("Cache hit for key: %s", cacheKey)
-		c.String(http.StatusOK, entry.value)
-		return
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("Invalid %s value: %s", name, value)
+		return def
 	}
+	return n
+}
 
-	// Create render configuration
+// requestConfig builds the render configuration for a request from its form
+// fields and the server's defaults.
+func (s *server) requestConfig(c *gin.Context) (*diagram.Config, error) {
 	config, err := diagram.NewWebConfig(
-		useAsciiMode,
-		boxBorderPadding,
-		paddingBetweenX,
-		paddingBetweenY,
+		c.PostForm("useExtendedChars") == "",
+		intFormValue(c, "borderPadding", s.boxBorderPadding),
+		intFormValue(c, "xPadding", s.paddingX),
+		intFormValue(c, "yPadding", s.paddingY),
 	)
+	if err != nil {
+		return nil, err
+	}
+	config.Verbose = s.verbose // Allow verbose logging in web mode if enabled
+	return config, nil
+}
+
+func (s *server) renderMermaid(c *gin.Context) {
+	mermaidString := c.PostForm("mermaid")
+	log.Debugf("Received input %s", c.Request.PostForm.Encode())
+
+	config, err := s.requestConfig(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid configuration: %v", err)})
 		return
 	}
-	config.Verbose = Verbose // Allow verbose logging in web mode if enabled
-	result, err := RenderDiagram(mermaidString, config)
+
+	result, err := s.render(c.Request.Context(), mermaidString, config)
 	if err != nil {
 		log.Errorf("Rendering failed: %v", err)
 		c.String(http.StatusBadRequest, fmt.Sprintf("Failed to render diagram: %v", err))
 		return
 	}
+	c.String(http.StatusOK, result)
+}
 
-	// Store the result in the cache
-	resultCache.Lock()
-	if len(resultCache.m) >= maxCacheSize {
-		log.Infof("Cache is full, removing oldest entry")
-		// Remove a random entry if cache is full
-		for k := range resultCache.m {
-			delete(resultCache.m, k)
-			break
-		}
+// render renders input with config, serving and storing the result in the
+// server's cache.
+func (s *server) render(ctx context.Context, input string, config *diagram.Config) (string, error) {
+	input = normaliseInput(input)
+	cacheKey := newCacheKey(input, config)
+	if result, found := s.cache.get(cacheKey); found {
+		log.Debugf("Cache hit for key: %x", cacheKey)
+		return result, nil
 	}
-	resultCache.m[cacheKey] = cacheEntry{
-		value: result,
+
+	result, err := mermaidascii.Render(ctx, input, config)
+	if err != nil {
+		return "", err
 	}
-	resultCache.Unlock()
+	s.cache.put(cacheKey, result)
+	return result, nil
+}
 
-	c.String(http.StatusOK, result)
+// metrics reports the render cache's counters in the Prometheus text format.
+func (s *server) metrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	s.cache.writeMetrics(c.Writer)
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mermaidascii"
+	"github.com/gin-gonic/gin"
+)
+
+// testRouter serves the render and metrics handlers of s without the HTML
+// templates, which are only found from the repository root.
+func testRouter(s *server) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", s.renderMermaid)
+	r.POST("/generate", s.renderMermaid)
+	r.GET("/metrics", s.metrics)
+	return r
+}
+
+func postForm(t *testing.T, r http.Handler, path string, form url.Values) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+	return w.Code, string(body)
+}
+
+// TestRenderMermaidConcurrentRequestsKeepTheirOwnOptions posts the same
+// diagrams with different paddings and charsets from many goroutines at once
+// and checks every response was rendered with that request's options. Run with
+// -race to catch state shared between requests.
+func TestRenderMermaidConcurrentRequestsKeepTheirOwnOptions(t *testing.T) {
+	r := testRouter(newServer(1, 5, 5, false, newRenderCache(1<<20, time.Hour)))
+
+	type request struct {
+		form url.Values
+		want string
+	}
+	var requests []request
+	for _, input := range []string{"graph LR\nA --> B\nA --> C", "graph TD\nA --> B\nB --> C", "sequenceDiagram\nA->>B: hi"} {
+		for _, padding := range []int{1, 3, 8} {
+			for _, extended := range []bool{false, true} {
+				form := url.Values{
+					"mermaid":       {input},
+					"xPadding":      {strconv.Itoa(padding)},
+					"yPadding":      {strconv.Itoa(padding + 1)},
+					"borderPadding": {strconv.Itoa(padding % 3)},
+				}
+				if extended {
+					form.Set("useExtendedChars", "on")
+				}
+				config, err := diagram.NewWebConfig(!extended, padding%3, padding, padding+1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := mermaidascii.Render(context.Background(), input, config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				requests = append(requests, request{form, want})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for i, req := range requests {
+			wg.Add(1)
+			go func(path string, req request) {
+				defer wg.Done()
+				code, body := postForm(t, r, path, req.form)
+				if code != http.StatusOK || body != req.want {
+					t.Errorf("POST %s %v = %d\n%s\nwant:\n%s", path, req.form, code, body, req.want)
+				}
+			}([]string{"/", "/generate"}[i%2], req)
+		}
+	}
+	wg.Wait()
+}
+
+func TestRenderMermaidFallsBackToServerDefaults(t *testing.T) {
+	s := newServer(2, 7, 4, false, newRenderCache(1<<20, time.Hour))
+	r := testRouter(s)
+
+	form := url.Values{"mermaid": {"graph LR\nA --> B"}, "xPadding": {"wide"}, "useExtendedChars": {"on"}}
+	code, body := postForm(t, r, "/", form)
+	if code != http.StatusOK {
+		t.Fatalf("POST / = %d: %s", code, body)
+	}
+
+	config, err := diagram.NewWebConfig(false, 2, 7, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := mermaidascii.Render(context.Background(), "graph LR\nA --> B", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != want {
+		t.Errorf("got:\n%s\nwant the server defaults:\n%s", body, want)
+	}
+	if s.paddingX != 7 || s.paddingY != 4 || s.boxBorderPadding != 2 {
+		t.Errorf("request changed the server defaults: %+v", s)
+	}
+}
+
+func TestRenderMermaidRejectsInvalidConfig(t *testing.T) {
+	r := testRouter(newServer(1, 5, 5, false, newRenderCache(1<<20, time.Hour)))
+	code, body := postForm(t, r, "/", url.Values{"mermaid": {"graph LR\nA --> B"}, "xPadding": {"-1"}})
+	if code != http.StatusBadRequest || !strings.Contains(body, "PaddingBetweenX") {
+		t.Errorf("POST / = %d %s, want a 400 naming PaddingBetweenX", code, body)
+	}
+}
@@ -0,0 +1,32 @@
+package class
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		d, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(d, config)
+	}
+}
+
+// TestClassDiagramRendering tests all class diagram golden files with Unicode charset.
+func TestClassDiagramRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "class", renderGolden(false))
+}
+
+// TestClassDiagramRendering_ASCII tests class diagram golden files with ASCII charset.
+func TestClassDiagramRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "class-ascii", renderGolden(true))
+}
@@ -0,0 +1,299 @@
+// Package class parses and renders mermaid class diagrams (classDiagram) as
+// ASCII: UML class boxes with name, field and method compartments, laid out
+// top-down with parents above their children and joined by relations drawn
+// with UML end markers.
+package class
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const classKeyword = "classDiagram"
+
+// Marker is the UML decoration at one end of a relation.
+type Marker int
+
+const (
+	MarkerNone        Marker = iota // plain line end
+	MarkerTriangle                  // <| or |>: inheritance (solid) or realization (dashed)
+	MarkerComposition               // *: filled diamond
+	MarkerAggregation               // o: hollow diamond
+	MarkerArrow                     // < or >: association (solid) or dependency (dashed)
+	MarkerLollipop                  // (): provided interface
+)
+
+// Class is one box of the diagram. ID is the name used in relations; Label is
+// the text given by `class ID["Label"]`, if any.
+type Class struct {
+	ID          string
+	Label       string
+	Generic     string   // type parameter from Name~T~, already in <T> form
+	Annotations []string // <<interface>>, <<abstract>>, …, without the brackets
+	Fields      []string
+	Methods     []string
+}
+
+// Relation connects two classes. FromMarker decorates the From end (the class
+// written first) and ToMarker the To end; FromCard/ToCard are the quoted
+// cardinalities written beside each class.
+type Relation struct {
+	From, To             *Class
+	FromMarker, ToMarker Marker
+	FromCard, ToCard     string
+	Dashed               bool // .. rather than --
+	Label                string
+}
+
+// ClassDiagram is a parsed class diagram. Classes are kept in first-seen
+// order; a relation or member line referencing an undeclared class creates
+// it.
+type ClassDiagram struct {
+	Classes   []*Class
+	Relations []*Relation
+	byID      map[string]*Class
+}
+
+// classNamePattern is a class id: a word (with ~generic~ parts) or a
+// backtick-quoted name.
+const classNamePattern = "(`[^`]+`|[\\p{L}\\p{N}_~]+)"
+
+var (
+	// headerRegex matches the diagram declaration.
+	headerRegex = regexp.MustCompile(`^classDiagram(?:-v2)?\s*$`)
+
+	// directionRegex matches the layout directive; classes are always laid
+	// out top-down, so it is accepted and ignored.
+	directionRegex = regexp.MustCompile(`(?i)^direction\s+(TB|TD|BT|LR|RL)$`)
+
+	// ignoredLineRegex matches styling, interaction, note and accessibility
+	// statements that carry no ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(classDef|cssClass|style|click|callback|link|note|accTitle|accDescr)\b`)
+
+	// namespaceRegex opens a namespace block; its classes are drawn like any
+	// other.
+	namespaceRegex = regexp.MustCompile(`^namespace\s+\S+\s*\{$`)
+
+	// classDeclRegex matches `class Name`, with an optional ["Label"] and an
+	// optional `{` opening a member block.
+	classDeclRegex = regexp.MustCompile("^class\\s+" + classNamePattern + `\s*(?:\["([^"]*)"\])?\s*(\{)?\s*(\})?$`)
+
+	// annotationRegex matches `<<interface>> Name`.
+	annotationRegex = regexp.MustCompile("^<<([^>]+)>>\\s*" + classNamePattern + `$`)
+
+	// blockAnnotationRegex matches a `<<interface>>` line inside a member block.
+	blockAnnotationRegex = regexp.MustCompile(`^<<([^>]+)>>$`)
+
+	// relationRegex matches `A "1" <|-- "*" B : label`: two class names around
+	// a line (-- or ..) with optional markers and quoted cardinalities.
+	relationRegex = regexp.MustCompile("^" + classNamePattern +
+		`\s*(?:"([^"]*)"\s*)?(<\||\*|o|<|\(\))?(--|\.\.)(\|>|\*|o|>|\(\))?\s*(?:"([^"]*)"\s*)?` +
+		classNamePattern + `\s*(?::\s*(.*))?$`)
+
+	// memberRegex matches `Name : +member`.
+	memberRegex = regexp.MustCompile("^" + classNamePattern + `\s*:\s*(.+)$`)
+
+	// classShorthandRegex matches a `:::class` styling decoration.
+	classShorthandRegex = regexp.MustCompile(`:::[\w-]+`)
+)
+
+// IsClassDiagram reports whether the input's first meaningful line declares a
+// class diagram.
+func IsClassDiagram(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// Parse parses a classDiagram into classes and relations.
+func Parse(input string) (*ClassDiagram, error) {
+	if !IsClassDiagram(input) {
+		return nil, fmt.Errorf("expected %q keyword", classKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	d := &ClassDiagram{byID: map[string]*Class{}}
+	var block *Class      // class whose member block is open
+	blockLine := 0        // line block was opened on
+	namespaces := []int{} // lines of the open namespace blocks
+	seenKeyword := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			seenKeyword = true
+			continue
+		}
+
+		if block != nil {
+			switch {
+			case line == "}":
+				block = nil
+			case blockAnnotationRegex.MatchString(line):
+				block.Annotations = append(block.Annotations, blockAnnotationRegex.FindStringSubmatch(line)[1])
+			default:
+				block.addMember(line)
+			}
+			continue
+		}
+
+		line = strings.TrimSpace(classShorthandRegex.ReplaceAllString(line, ""))
+		switch {
+		case directionRegex.MatchString(line), ignoredLineRegex.MatchString(line):
+			continue
+		case namespaceRegex.MatchString(line):
+			namespaces = append(namespaces, i+1)
+			continue
+		case line == "}":
+			if len(namespaces) == 0 {
+				return nil, diagram.LineErrorf(i+1, "unexpected '}'")
+			}
+			namespaces = namespaces[:len(namespaces)-1]
+			continue
+		}
+
+		if m := classDeclRegex.FindStringSubmatch(line); m != nil {
+			c := d.class(m[1])
+			if m[2] != "" {
+				c.Label = m[2]
+			}
+			if m[3] != "" && m[4] == "" {
+				block, blockLine = c, i+1
+			}
+			continue
+		}
+		if m := annotationRegex.FindStringSubmatch(line); m != nil {
+			c := d.class(m[2])
+			c.Annotations = append(c.Annotations, m[1])
+			continue
+		}
+		if m := relationRegex.FindStringSubmatch(line); m != nil {
+			d.Relations = append(d.Relations, &Relation{
+				From:       d.class(m[1]),
+				FromCard:   m[2],
+				FromMarker: parseMarker(m[3]),
+				Dashed:     m[4] == "..",
+				ToMarker:   parseMarker(m[5]),
+				ToCard:     m[6],
+				To:         d.class(m[7]),
+				Label:      strings.TrimSpace(m[8]),
+			})
+			continue
+		}
+		if m := memberRegex.FindStringSubmatch(line); m != nil {
+			d.class(m[1]).addMember(strings.TrimSpace(m[2]))
+			continue
+		}
+		return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
+	}
+	if block != nil {
+		return nil, diagram.LineErrorf(blockLine, "unclosed class block %q (missing '}')", block.ID)
+	}
+	if len(namespaces) > 0 {
+		return nil, diagram.LineErrorf(namespaces[len(namespaces)-1], "unclosed namespace (missing '}')")
+	}
+	return d, nil
+}
+
+// class returns the class with the given name, creating it if needed. A
+// Name~T~ reference names class Name with generic T.
+func (d *ClassDiagram) class(name string) *Class {
+	name = strings.Trim(name, "`")
+	id, generic := name, ""
+	if i := strings.IndexByte(name, '~'); i > 0 && strings.HasSuffix(name, "~") && len(name) > i+1 {
+		id, generic = name[:i], "<"+generics(name[i+1:len(name)-1])+">"
+	}
+	c, ok := d.byID[id]
+	if !ok {
+		c = &Class{ID: id}
+		d.byID[id] = c
+		d.Classes = append(d.Classes, c)
+	}
+	if generic != "" {
+		c.Generic = generic
+	}
+	return c
+}
+
+// Title is the name shown in the class box: its label, or its id with any
+// generic type.
+func (c *Class) Title() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.ID + c.Generic
+}
+
+// addMember files a member line as a method when it has a parameter list,
+// otherwise as a field.
+func (c *Class) addMember(member string) {
+	member = generics(member)
+	if strings.Contains(member, "(") {
+		c.Methods = append(c.Methods, member)
+		return
+	}
+	c.Fields = append(c.Fields, member)
+}
+
+func parseMarker(token string) Marker {
+	switch token {
+	case "<|", "|>":
+		return MarkerTriangle
+	case "*":
+		return MarkerComposition
+	case "o":
+		return MarkerAggregation
+	case "<", ">":
+		return MarkerArrow
+	case "()":
+		return MarkerLollipop
+	}
+	return MarkerNone
+}
+
+// generics rewrites mermaid's ~T~ generic notation to <T>. A tilde between two
+// word characters opens a type parameter; any other closes the innermost
+// open one, so List~List~int~~ becomes List<List<int>>.
+func generics(s string) string {
+	if !strings.Contains(s, "~") {
+		return s
+	}
+	rs := []rune(s)
+	var b strings.Builder
+	depth := 0
+	isWord := func(i int) bool {
+		if i < 0 || i >= len(rs) {
+			return false
+		}
+		return rs[i] == '_' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i])
+	}
+	for i, r := range rs {
+		switch {
+		case r != '~':
+			b.WriteRune(r)
+		case isWord(i-1) && isWord(i+1):
+			b.WriteRune('<')
+			depth++
+		case depth > 0:
+			b.WriteRune('>')
+			depth--
+		default: // e.g. the ~ package-visibility prefix
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
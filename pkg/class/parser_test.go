@@ -0,0 +1,148 @@
+package class
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsClassDiagram(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"classDiagram\n A <|-- B", true},
+		{"classDiagram-v2\n A <|-- B", true},
+		{"%% comment\nclassDiagram", true},
+		{"classDiagramFoo\n A", false},
+		{"stateDiagram-v2\n [*] --> A", false},
+		{"", false},
+	} {
+		if got := IsClassDiagram(c.in); got != c.want {
+			t.Errorf("IsClassDiagram(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseRelationTypes(t *testing.T) {
+	tests := []struct {
+		line     string
+		from, to Marker
+		dashed   bool
+	}{
+		{"A <|-- B", MarkerTriangle, MarkerNone, false},
+		{"A *-- B", MarkerComposition, MarkerNone, false},
+		{"A o-- B", MarkerAggregation, MarkerNone, false},
+		{"A --> B", MarkerNone, MarkerArrow, false},
+		{"A -- B", MarkerNone, MarkerNone, false},
+		{"A ..> B", MarkerNone, MarkerArrow, true},
+		{"A ..|> B", MarkerNone, MarkerTriangle, true},
+		{"A .. B", MarkerNone, MarkerNone, true},
+		{"A <|--|> B", MarkerTriangle, MarkerTriangle, false},
+		{"A ()-- B", MarkerLollipop, MarkerNone, false},
+		{"A<|--B", MarkerTriangle, MarkerNone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			d, err := Parse("classDiagram\n" + tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(d.Relations) != 1 {
+				t.Fatalf("want 1 relation, got %d", len(d.Relations))
+			}
+			r := d.Relations[0]
+			if r.From.ID != "A" || r.To.ID != "B" {
+				t.Errorf("endpoints = %s, %s; want A, B", r.From.ID, r.To.ID)
+			}
+			if r.FromMarker != tt.from || r.ToMarker != tt.to || r.Dashed != tt.dashed {
+				t.Errorf("got from=%v to=%v dashed=%v, want from=%v to=%v dashed=%v",
+					r.FromMarker, r.ToMarker, r.Dashed, tt.from, tt.to, tt.dashed)
+			}
+		})
+	}
+}
+
+func TestParseCardinalitiesAndLabel(t *testing.T) {
+	d, err := Parse(`classDiagram
+    Customer "1" --> "0..*" Ticket : buys`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Relations[0]
+	if r.FromCard != "1" || r.ToCard != "0..*" || r.Label != "buys" {
+		t.Errorf("got cards %q/%q label %q", r.FromCard, r.ToCard, r.Label)
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	d, err := Parse(`classDiagram
+    class Duck {
+        <<abstract>>
+        +String beakColor
+        +swim()
+    }
+    Duck : -List~int~ eggs
+    Duck : +quack() bool
+    <<service>> Duck`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := d.byID["Duck"]
+	if got := strings.Join(c.Fields, "|"); got != "+String beakColor|-List<int> eggs" {
+		t.Errorf("fields = %q", got)
+	}
+	if got := strings.Join(c.Methods, "|"); got != "+swim()|+quack() bool" {
+		t.Errorf("methods = %q", got)
+	}
+	if got := strings.Join(c.Annotations, "|"); got != "abstract|service" {
+		t.Errorf("annotations = %q", got)
+	}
+}
+
+func TestParseTitles(t *testing.T) {
+	d, err := Parse(`classDiagram
+    class Square~Shape~
+    class Box["Big box"]
+    Square <|-- Box`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.byID["Square"].Title(); got != "Square<Shape>" {
+		t.Errorf("Square title = %q", got)
+	}
+	if got := d.byID["Box"].Title(); got != "Big box" {
+		t.Errorf("Box title = %q", got)
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	for in, want := range map[string]string{
+		"List~int~ ids":      "List<int> ids",
+		"List~List~int~~ xs": "List<List<int>> xs",
+		"~String branch":     "~String branch",
+		"Map~K,V~ m":         "Map<K,V> m",
+	} {
+		if got := generics(in); got != want {
+			t.Errorf("generics(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		name, in, want string
+	}{
+		{"missing keyword", "graph TD\n A-->B", "expected"},
+		{"stray brace", "classDiagram\n A <|-- B\n }", "line 3"},
+		{"unclosed block", "classDiagram\n class A {\n +x", "line 2: unclosed class block"},
+		{"unclosed namespace", "classDiagram\n namespace N {\n class A", "line 2: unclosed namespace"},
+		{"invalid syntax", "classDiagram\n A -> B", "line 2: invalid syntax"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse(c.in)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got error %v, want it to contain %q", err, c.want)
+			}
+		})
+	}
+}
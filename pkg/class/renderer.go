@@ -0,0 +1,124 @@
+package class
+
+import (
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a diagram is drawn with (Unicode by default,
+// ASCII when useAscii).
+type glyphs struct {
+	h, v, tl, tr, bl, br, teeD, teeU, teeL, teeR, cross rune
+	hd, vd                                              rune // dashed line, for realization and dependency
+	// Relation end markers. *Up markers sit under the upper class and point
+	// up into it; *Down markers sit over the lower class and point down.
+	triUp, triDown     rune
+	arrowUp, arrowDown rune
+	composition        rune
+	aggregation        rune
+	lollipop           rune
+}
+
+var unicodeGlyphs = glyphs{
+	h: '─', v: '│', tl: '┌', tr: '┐', bl: '└', br: '┘',
+	teeD: '┬', teeU: '┴', teeL: '┤', teeR: '├', cross: '┼',
+	hd: '┈', vd: '┊',
+	triUp: '△', triDown: '▽', arrowUp: '▲', arrowDown: '▼',
+	composition: '◆', aggregation: '◇', lollipop: '○',
+}
+
+var asciiGlyphs = glyphs{
+	h: '-', v: '|', tl: '+', tr: '+', bl: '+', br: '+',
+	teeD: '+', teeU: '+', teeL: '+', teeR: '+', cross: '+',
+	hd: '.', vd: ':',
+	triUp: 'A', triDown: 'V', arrowUp: '^', arrowDown: 'v',
+	composition: '*', aggregation: 'o', lollipop: 'O',
+}
+
+// marker returns the glyph for m at an upper (up) or lower end; 0 for none.
+func (g glyphs) marker(m Marker, up bool) rune {
+	switch m {
+	case MarkerTriangle:
+		if up {
+			return g.triUp
+		}
+		return g.triDown
+	case MarkerArrow:
+		if up {
+			return g.arrowUp
+		}
+		return g.arrowDown
+	case MarkerComposition:
+		return g.composition
+	case MarkerAggregation:
+		return g.aggregation
+	case MarkerLollipop:
+		return g.lollipop
+	}
+	return 0
+}
+
+// renderClass draws a class as a UML box: annotations and the name centred in
+// the header, then a fields compartment and a methods compartment. Empty
+// compartments are left out; methods are told apart by their parameter list.
+// minInner is a lower bound on the box's inner width, used to give every
+// relation touching the box its own attach column.
+func renderClass(c *Class, g glyphs, minInner int) []string {
+	header := make([]string, 0, len(c.Annotations)+1)
+	for _, a := range c.Annotations {
+		header = append(header, "<<"+a+">>")
+	}
+	header = append(header, c.Title())
+
+	inner := minInner
+	for _, l := range header {
+		inner = max(inner, runewidth.StringWidth(l)+2)
+	}
+	for _, l := range append(append([]string(nil), c.Fields...), c.Methods...) {
+		inner = max(inner, runewidth.StringWidth(l)+2)
+	}
+
+	rule := func(left, right rune) string {
+		return string(left) + strings.Repeat(string(g.h), inner) + string(right)
+	}
+	out := []string{rule(g.tl, g.tr)}
+	for _, l := range header {
+		pad := inner - runewidth.StringWidth(l)
+		out = append(out, string(g.v)+strings.Repeat(" ", pad/2)+l+strings.Repeat(" ", pad-pad/2)+string(g.v))
+	}
+	for _, compartment := range [][]string{c.Fields, c.Methods} {
+		if len(compartment) == 0 {
+			continue
+		}
+		out = append(out, rule(g.teeR, g.teeL))
+		for _, l := range compartment {
+			out = append(out, string(g.v)+" "+l+strings.Repeat(" ", inner-1-runewidth.StringWidth(l))+string(g.v))
+		}
+	}
+	return append(out, rule(g.bl, g.br))
+}
+
+// Render lays the classes out top-down and draws the relations between them.
+func Render(d *ClassDiagram, config *diagram.Config) (string, error) {
+	if config == nil {
+		config = diagram.DefaultConfig()
+	}
+	g := unicodeGlyphs
+	if config.UseAscii {
+		g = asciiGlyphs
+	}
+	if d == nil || len(d.Classes) == 0 {
+		return "", nil
+	}
+	return layoutDiagram(d, g).draw().String(), nil
+}
+
+func blockWidth(lines []string) int {
+	w := 0
+	for _, l := range lines {
+		w = max(w, runewidth.StringWidth(l))
+	}
+	return w
+}
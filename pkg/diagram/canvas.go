@@ -0,0 +1,96 @@
+package diagram
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Canvas is a growable 2D grid of runes that diagrams stamp their boxes onto
+// and draw their connectors across.
+type Canvas struct {
+	rows [][]rune
+}
+
+func (c *Canvas) ensure(x, y int) {
+	for len(c.rows) <= y {
+		c.rows = append(c.rows, nil)
+	}
+	for len(c.rows[y]) <= x {
+		c.rows[y] = append(c.rows[y], ' ')
+	}
+}
+
+// Set puts r at (x,y), growing the canvas to fit. Negative coordinates are
+// ignored.
+func (c *Canvas) Set(x, y int, r rune) {
+	if x < 0 || y < 0 {
+		return
+	}
+	c.ensure(x, y)
+	c.rows[y][x] = r
+}
+
+// At returns the rune at (x,y), or a space outside the canvas.
+func (c *Canvas) At(x, y int) rune {
+	if y < 0 || y >= len(c.rows) || x < 0 || x >= len(c.rows[y]) {
+		return ' '
+	}
+	return c.rows[y][x]
+}
+
+// Stamp places a block of pre-rendered lines with its top-left at (x0,y0).
+// Runes advance by display width: a double-width rune (CJK, emoji) occupies
+// its cell plus a sentinel cell, keeping canvas columns aligned with what the
+// terminal shows.
+func (c *Canvas) Stamp(x0, y0 int, block []string) {
+	for dy, line := range block {
+		x := x0
+		for _, r := range line {
+			c.Set(x, y0+dy, r)
+			w := runewidth.RuneWidth(r)
+			if w == 2 {
+				c.Set(x+1, y0+dy, 0)
+			}
+			x += w
+		}
+	}
+}
+
+// Size is the width and height of what String prints: trailing blanks on a
+// row don't count towards the width.
+func (c *Canvas) Size() (int, int) {
+	width := 0
+	for _, row := range c.rows {
+		w := len(row)
+		for w > 0 && row[w-1] == ' ' {
+			w--
+		}
+		width = max(width, w)
+	}
+	return width, len(c.rows)
+}
+
+// Lines returns the canvas rows with trailing spaces trimmed.
+func (c *Canvas) Lines() []string {
+	lines := make([]string, 0, len(c.rows))
+	for _, row := range c.rows {
+		line := make([]rune, 0, len(row))
+		for _, r := range row {
+			if r != 0 { // sentinel: second column of a double-width rune
+				line = append(line, r)
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	return lines
+}
+
+func (c *Canvas) String() string {
+	var b strings.Builder
+	for _, l := range c.Lines() {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
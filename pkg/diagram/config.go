@@ -4,63 +4,98 @@ import "fmt"
 
 // Config holds configuration for diagram rendering.
 // This replaces global variables and makes the rendering functions testable and thread-safe.
+// The JSON names are the ones the web server's render API accepts.
 type Config struct {
 	// UseAscii determines whether to use ASCII characters (true) or Unicode box-drawing characters (false)
-	UseAscii bool
+	UseAscii bool `json:"useAscii"`
 
 	// ShowCoords displays coordinate debugging information (for development)
-	ShowCoords bool
+	ShowCoords bool `json:"showCoords"`
 
 	// Verbose enables detailed logging
-	Verbose bool
+	Verbose bool `json:"verbose"`
+
+	// OutputFormat selects what rendering produces: "text" (the default, also
+	// used when empty) draws the diagram, "json" describes its layout instead
+	// and "svg" draws it as an SVG image
+	OutputFormat string `json:"outputFormat"`
 
 	// --- Graph-specific configuration ---
 
 	// BoxBorderPadding is the padding between text and border in graph nodes
-	BoxBorderPadding int
+	BoxBorderPadding int `json:"boxBorderPadding"`
 
 	// PaddingBetweenX is the horizontal space between nodes in graphs
-	PaddingBetweenX int
+	PaddingBetweenX int `json:"paddingBetweenX"`
 
 	// PaddingBetweenY is the vertical space between nodes in graphs
-	PaddingBetweenY int
+	PaddingBetweenY int `json:"paddingBetweenY"`
+
+	// GraphDirection is the direction of graph layout ("LR", "RL", "TD" or "BT")
+	GraphDirection string `json:"graphDirection"`
 
-	// GraphDirection is the direction of graph layout ("LR" or "TD")
-	GraphDirection string
+	// Layout names the engine that lays graphs out: "grid" (the default,
+	// also used when empty) places nodes rank by rank in the order they're
+	// found, "layered" orders every rank to keep edge crossings down. Other
+	// engines can be added with graph.RegisterEngine.
+	Layout string `json:"layout"`
 
-	// StyleType determines output format for graph diagrams ("cli" or "html")
-	// This controls whether graphs use colored output (html) or plain text (cli)
-	StyleType string
+	// Compact shrinks the space graph layouts leave between nodes to what
+	// the edges running through it need, and drops what nothing uses
+	Compact bool `json:"compact"`
+
+	// StyleType determines output format for graph diagrams ("cli", "html" or "svg")
+	// This controls whether graphs use colored output (html), plain text (cli)
+	// or are drawn as an SVG image (svg, the same as OutputFormat "svg")
+	StyleType string `json:"styleType"`
 
 	// --- Sequence diagram-specific configuration ---
 
 	// SequenceParticipantSpacing is the horizontal space between participants
-	SequenceParticipantSpacing int
+	SequenceParticipantSpacing int `json:"sequenceParticipantSpacing"`
 
 	// SequenceMessageSpacing is the vertical space between messages (lifeline segments)
-	SequenceMessageSpacing int
+	SequenceMessageSpacing int `json:"sequenceMessageSpacing"`
 
 	// SequenceSelfMessageWidth is the width of self-message loops
-	SequenceSelfMessageWidth int
+	SequenceSelfMessageWidth int `json:"sequenceSelfMessageWidth"`
+
+	// --- Gantt chart-specific configuration ---
+
+	// GanttWidth is how many columns the time axis of gantt charts spans; 0
+	// means the default
+	GanttWidth int `json:"ganttWidth"`
+
+	// --- Timeline-specific configuration ---
+
+	// TimelineWidth is how many columns a timeline's rows may take up before
+	// its periods wrap onto the next row; 0 means the default
+	TimelineWidth int `json:"timelineWidth"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
 // The returned config is guaranteed to pass validation.
 func DefaultConfig() *Config {
 	return &Config{
-		UseAscii:   false, // Use Unicode by default for better appearance
-		ShowCoords: false,
-		Verbose:    false,
+		UseAscii:     false, // Use Unicode by default for better appearance
+		ShowCoords:   false,
+		Verbose:      false,
+		OutputFormat: "text",
 		// Graph defaults
 		BoxBorderPadding: 1,
 		PaddingBetweenX:  5,
 		PaddingBetweenY:  5,
 		GraphDirection:   "LR",
+		Layout:           "grid",
 		StyleType:        "cli",
 		// Sequence diagram defaults
 		SequenceParticipantSpacing: 5,
 		SequenceMessageSpacing:     1,
 		SequenceSelfMessageWidth:   4,
+		// Gantt chart defaults
+		GanttWidth: 60,
+		// Timeline defaults
+		TimelineWidth: 80,
 	}
 }
 
@@ -80,6 +115,8 @@ func NewConfig(useAscii bool, graphDirection, styleType string) (*Config, error)
 		SequenceParticipantSpacing: 5,
 		SequenceMessageSpacing:     1,
 		SequenceSelfMessageWidth:   4,
+		GanttWidth:                 60,
+		TimelineWidth:              80,
 	}
 
 	if err := config.Validate(); err != nil {
@@ -103,6 +140,8 @@ func NewCLIConfig(useAscii, showCoords, verbose bool, boxBorderPadding, paddingX
 		SequenceParticipantSpacing: defaults.SequenceParticipantSpacing,
 		SequenceMessageSpacing:     defaults.SequenceMessageSpacing,
 		SequenceSelfMessageWidth:   defaults.SequenceSelfMessageWidth,
+		GanttWidth:                 defaults.GanttWidth,
+		TimelineWidth:              defaults.TimelineWidth,
 	}
 
 	if err := config.Validate(); err != nil {
@@ -126,6 +165,8 @@ func NewWebConfig(useAscii bool, boxBorderPadding, paddingX, paddingY int) (*Con
 		SequenceParticipantSpacing: defaults.SequenceParticipantSpacing,
 		SequenceMessageSpacing:     defaults.SequenceMessageSpacing,
 		SequenceSelfMessageWidth:   defaults.SequenceSelfMessageWidth,
+		GanttWidth:                 defaults.GanttWidth,
+		TimelineWidth:              defaults.TimelineWidth,
 	}
 
 	if err := config.Validate(); err != nil {
@@ -147,6 +188,12 @@ func NewTestConfig(useAscii bool, styleType string) *Config {
 // Validate checks if the configuration values are valid.
 // Returns an error if any values are invalid or would cause rendering issues.
 func (c *Config) Validate() error {
+	switch c.OutputFormat {
+	case "", "text", "json", "svg":
+	default:
+		return &ConfigError{Field: "OutputFormat", Value: c.OutputFormat, Message: "must be \"text\", \"json\" or \"svg\""}
+	}
+
 	// Validate graph configuration
 	if c.BoxBorderPadding < 0 {
 		return &ConfigError{Field: "BoxBorderPadding", Value: c.BoxBorderPadding, Message: "must be non-negative"}
@@ -157,11 +204,13 @@ func (c *Config) Validate() error {
 	if c.PaddingBetweenY < 0 {
 		return &ConfigError{Field: "PaddingBetweenY", Value: c.PaddingBetweenY, Message: "must be non-negative"}
 	}
-	if c.GraphDirection != "LR" && c.GraphDirection != "TD" {
-		return &ConfigError{Field: "GraphDirection", Value: c.GraphDirection, Message: "must be \"LR\" or \"TD\""}
+	switch c.GraphDirection {
+	case "LR", "RL", "TD", "BT":
+	default:
+		return &ConfigError{Field: "GraphDirection", Value: c.GraphDirection, Message: "must be \"LR\", \"RL\", \"TD\" or \"BT\""}
 	}
-	if c.StyleType != "cli" && c.StyleType != "html" {
-		return &ConfigError{Field: "StyleType", Value: c.StyleType, Message: "must be \"cli\" or \"html\""}
+	if c.StyleType != "cli" && c.StyleType != "html" && c.StyleType != "svg" {
+		return &ConfigError{Field: "StyleType", Value: c.StyleType, Message: "must be \"cli\", \"html\" or \"svg\""}
 	}
 
 	// Validate sequence diagram configuration
@@ -175,6 +224,16 @@ func (c *Config) Validate() error {
 		return &ConfigError{Field: "SequenceSelfMessageWidth", Value: c.SequenceSelfMessageWidth, Message: "must be at least 2"}
 	}
 
+	// Validate gantt chart configuration
+	if c.GanttWidth != 0 && c.GanttWidth < 10 {
+		return &ConfigError{Field: "GanttWidth", Value: c.GanttWidth, Message: "must be 0 for the default or at least 10"}
+	}
+
+	// Validate timeline configuration
+	if c.TimelineWidth != 0 && c.TimelineWidth < 20 {
+		return &ConfigError{Field: "TimelineWidth", Value: c.TimelineWidth, Message: "must be 0 for the default or at least 20"}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,40 @@
+package diagram
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateWidths(t *testing.T) {
+	cases := []struct {
+		name      string
+		set       func(c *Config)
+		wantField string
+	}{
+		{"default gantt width", func(c *Config) { c.GanttWidth = 0 }, ""},
+		{"narrow gantt width", func(c *Config) { c.GanttWidth = 10 }, ""},
+		{"gantt width too small", func(c *Config) { c.GanttWidth = 9 }, "GanttWidth"},
+		{"negative gantt width", func(c *Config) { c.GanttWidth = -1 }, "GanttWidth"},
+		{"default timeline width", func(c *Config) { c.TimelineWidth = 0 }, ""},
+		{"narrow timeline width", func(c *Config) { c.TimelineWidth = 20 }, ""},
+		{"timeline width too small", func(c *Config) { c.TimelineWidth = 19 }, "TimelineWidth"},
+		{"negative timeline width", func(c *Config) { c.TimelineWidth = -1 }, "TimelineWidth"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tc.set(c)
+			err := c.Validate()
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != tc.wantField {
+				t.Fatalf("Validate() error = %v, want a ConfigError for %s", err, tc.wantField)
+			}
+		})
+	}
+}
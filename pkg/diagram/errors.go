@@ -0,0 +1,24 @@
+package diagram
+
+import "fmt"
+
+// LineError is an error a parser found at a line of its input, so callers
+// can point at it without picking the message apart.
+type LineError struct {
+	// Line is the 1-based line of the input the error is about.
+	Line int
+	Err  error
+}
+
+// LineErrorf formats an error about the given line of the input.
+func LineErrorf(line int, format string, a ...any) error {
+	return &LineError{Line: line, Err: fmt.Errorf(format, a...)}
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
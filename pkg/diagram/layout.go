@@ -0,0 +1,96 @@
+package diagram
+
+// Layout is the machine-readable geometry of a rendered diagram: where every
+// node, edge, label and container ended up on the text canvas. Coordinates
+// are character cells with (0,0) at the top-left of the rendered diagram,
+// before any frontmatter title is prepended; X grows to the right and Y
+// downwards. Rectangles include their border cells.
+type Layout struct {
+	Type   string `json:"type"`
+	Title  string `json:"title,omitempty"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+
+	// Graph (flowchart) diagrams.
+	Nodes     []Node     `json:"nodes,omitempty"`
+	Edges     []Edge     `json:"edges,omitempty"`
+	Subgraphs []Subgraph `json:"subgraphs,omitempty"`
+
+	// Sequence diagrams.
+	Participants []Participant `json:"participants,omitempty"`
+	Messages     []Message     `json:"messages,omitempty"`
+
+	// ER diagrams. Relationships are reported as Edges.
+	Entities []Node `json:"entities,omitempty"`
+}
+
+// Point is a single cell on the canvas.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Rect is a box on the canvas; X and Y are its top-left cell.
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Node is a box drawn for a graph node or an ER entity.
+type Node struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Box   Rect   `json:"box"`
+}
+
+// Edge is a connector between two nodes, or a node and a subgraph's frame.
+// From and To are node or subgraph IDs. Points is the orthogonal polyline
+// the connector follows, from the source's border to the target's border.
+// LabelAt is the first cell of the label text, when the edge has one and its
+// position is known. A graph edge label with no room on the drawing is
+// listed in a legend below it instead; Footnote is then its number there,
+// and LabelAt is where the numbered marker standing in for it starts.
+type Edge struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Label    string  `json:"label,omitempty"`
+	Points   []Point `json:"points"`
+	LabelAt  *Point  `json:"labelAt,omitempty"`
+	Footnote int     `json:"footnote,omitempty"`
+}
+
+// Subgraph is the frame drawn around a group of graph nodes.
+type Subgraph struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Box   Rect   `json:"box"`
+}
+
+// Participant is a sequence diagram participant: its header box and the
+// column its lifeline runs down.
+type Participant struct {
+	ID       string `json:"id"`
+	Label    string `json:"label,omitempty"`
+	Box      Rect   `json:"box"`
+	Lifeline int    `json:"lifeline"`
+}
+
+// Message is a sequence diagram message. Row is the line its arrow is drawn
+// on (the top of the loop for self-messages) and Points the arrow's path from
+// sender to receiver.
+type Message struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Label  string  `json:"label,omitempty"`
+	Row    int     `json:"row"`
+	Points []Point `json:"points"`
+}
+
+// LayoutDiagram is implemented by diagrams that can report their layout as
+// well as render it.
+type LayoutDiagram interface {
+	Diagram
+	Layout(config *Config) (*Layout, error)
+}
@@ -4,9 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"testing"
 )
 
 // TestCase represents a test case for diagram rendering.
@@ -107,6 +110,71 @@ func ReadSequenceTestCase(filePath string) (*TestCase, error) {
 	}, nil
 }
 
+// ReadIndentedTestCase reads a test case file like ReadSequenceTestCase, but
+// keeps the leading indentation of the expected output, which drawings that
+// open with a centred title or an indented first line depend on.
+func ReadIndentedTestCase(filePath string) (*TestCase, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(string(content), "\n---\n")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("test case file must have exactly one '---' separator (on its own line)")
+	}
+
+	return &TestCase{
+		Mermaid:  strings.TrimSpace(parts[0]),
+		Expected: strings.Trim(parts[1], "\n"),
+		PaddingX: 5,
+		PaddingY: 5,
+	}, nil
+}
+
+// TestDataPath returns the absolute path to a cmd/testdata subdirectory,
+// resolved from this file's location so tests work from any working
+// directory.
+func TestDataPath(subdir string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "..", "..", "..", "cmd", "testdata", subdir)
+}
+
+// RunGoldenDir runs a subtest for every .txt golden file in a cmd/testdata
+// subdirectory, read with ReadIndentedTestCase, comparing what render draws
+// for its mermaid with the expected output.
+func RunGoldenDir(t *testing.T, subdir string, render func(mermaid string) (string, error)) {
+	t.Helper()
+	dir := TestDataPath(subdir)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+		t.Run(file.Name(), func(t *testing.T) {
+			tc, err := ReadIndentedTestCase(filepath.Join(dir, file.Name()))
+			if err != nil {
+				t.Fatalf("Failed to read test case file: %v", err)
+			}
+			actual, err := render(tc.Mermaid)
+			if err != nil {
+				t.Fatalf("Failed to render diagram: %v", err)
+			}
+
+			expectedNormalized := NormalizeWhitespace(tc.Expected)
+			actualNormalized := NormalizeWhitespace(actual)
+			if expectedNormalized != actualNormalized {
+				t.Errorf("Diagram didn't match\nExpected:\n%v\nActual:\n%v",
+					VisualizeWhitespace(expectedNormalized), VisualizeWhitespace(actualNormalized))
+			}
+		})
+	}
+}
+
 // NormalizeWhitespace removes trailing spaces and empty lines for comparison.
 // This is useful for comparing expected vs actual output where trailing whitespace doesn't matter.
 func NormalizeWhitespace(s string) string {
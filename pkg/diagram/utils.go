@@ -31,6 +31,22 @@ func RemoveComments(lines []string) []string {
 	return cleaned
 }
 
+// StripComment drops a %% comment (whole-line or trailing) from a line.
+// %% inside a quoted string (a label or attribute comment) is kept, matching
+// mermaid's lexer, which tokenizes strings before comments.
+func StripComment(line string) string {
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && line[i] == '%' && i+1 < len(line) && line[i+1] == '%':
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
+
 // SplitLines splits input on both actual newlines and escaped newlines (for curl compatibility).
 func SplitLines(input string) []string {
 	newlinePattern := regexp.MustCompile(`\n|\\n`)
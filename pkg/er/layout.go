@@ -2,74 +2,11 @@ package er
 
 import (
 	"math"
-	"strings"
 
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
 	"github.com/mattn/go-runewidth"
 )
 
-// canvas is a growable 2D grid of runes that boxes are stamped onto and
-// connectors are drawn across.
-type canvas struct {
-	rows [][]rune
-}
-
-func (c *canvas) ensure(x, y int) {
-	for len(c.rows) <= y {
-		c.rows = append(c.rows, nil)
-	}
-	for len(c.rows[y]) <= x {
-		c.rows[y] = append(c.rows[y], ' ')
-	}
-}
-
-func (c *canvas) set(x, y int, r rune) {
-	if x < 0 || y < 0 {
-		return
-	}
-	c.ensure(x, y)
-	c.rows[y][x] = r
-}
-
-func (c *canvas) at(x, y int) rune {
-	if y < 0 || y >= len(c.rows) || x < 0 || x >= len(c.rows[y]) {
-		return ' '
-	}
-	return c.rows[y][x]
-}
-
-// stamp places a block of pre-rendered lines with its top-left at (x0,y0).
-// Runes advance by display width: a double-width rune (CJK, emoji) occupies
-// its cell plus a sentinel cell, keeping canvas columns aligned with what the
-// terminal shows.
-func (c *canvas) stamp(x0, y0 int, block []string) {
-	for dy, line := range block {
-		x := x0
-		for _, r := range line {
-			c.set(x, y0+dy, r)
-			w := runewidth.RuneWidth(r)
-			if w == 2 {
-				c.set(x+1, y0+dy, 0)
-			}
-			x += w
-		}
-	}
-}
-
-func (c *canvas) String() string {
-	var b strings.Builder
-	for _, row := range c.rows {
-		line := make([]rune, 0, len(row))
-		for _, r := range row {
-			if r != 0 { // sentinel: second column of a double-width rune
-				line = append(line, r)
-			}
-		}
-		b.WriteString(strings.TrimRight(string(line), " "))
-		b.WriteByte('\n')
-	}
-	return b.String()
-}
-
 // side identifies which face of a box a connector attaches to. Connectors only
 // ever leave through the top or bottom face (see sidesFor).
 type side int
@@ -322,8 +259,9 @@ type endpoint struct {
 	card Cardinality
 }
 
-// drawConnectors routes every relationship and writes the result onto c.
-func drawConnectors(c *canvas, lay *layout, d *ErDiagram, g glyphs) {
+// drawConnectors routes every relationship and writes the result onto c. It
+// returns the routed plans so callers can report where each line went.
+func drawConnectors(c *diagram.Canvas, lay *layout, d *ErDiagram, g glyphs) []routePlan {
 	o := newOverlay()
 
 	// Decide each endpoint's side, then hand out attach slots per box-side so
@@ -391,8 +329,8 @@ func drawConnectors(c *canvas, lay *layout, d *ErDiagram, g glyphs) {
 	for _, p := range plans {
 		p.drawLine(o)
 	}
-	for _, p := range plans {
-		p.decorate(o)
+	for i := range plans {
+		plans[i].decorate(o)
 	}
 
 	composite(c, o, g)
@@ -403,19 +341,20 @@ func drawConnectors(c *canvas, lay *layout, d *ErDiagram, g glyphs) {
 		setAttachTee(c, p.a, g)
 		setAttachTee(c, p.b, g)
 	}
+	return plans
 }
 
 // setAttachTee stamps ┬/┴ where a stub leaves a box; if the border cell already
 // tees the other way (an attribute-table column rule), the two merge into ┼.
-func setAttachTee(c *canvas, ep endpoint, g glyphs) {
+func setAttachTee(c *diagram.Canvas, ep endpoint, g glyphs) {
 	tee, opposite := g.teeD, g.teeU
 	if ep.s == sideT {
 		tee, opposite = g.teeU, g.teeD
 	}
-	if c.at(ep.x, ep.y) == opposite {
+	if c.At(ep.x, ep.y) == opposite {
 		tee = g.cross
 	}
-	c.set(ep.x, ep.y, tee)
+	c.Set(ep.x, ep.y, tee)
 }
 
 // sidesFor picks each box's exit face. Connectors leave through the top/bottom
@@ -474,6 +413,11 @@ type routePlan struct {
 	ya, yb int
 	tx     int  // trunk column, valid only when !merged
 	merged bool // both stubs meet one gutter row: single run, no trunk
+
+	// labelX, labelY is where decorate started writing the label, valid only
+	// when labeled.
+	labelX, labelY int
+	labeled        bool
 }
 
 func newPlan(lay *layout, a, b endpoint, r *Relationship, lane int) routePlan {
@@ -488,28 +432,35 @@ func newPlan(lay *layout, a, b endpoint, r *Relationship, lane int) routePlan {
 // drawLine draws the relationship's orthogonal line in its own lane, so
 // distinct edges never overlap — only genuine crossings share a cell (┼).
 func (p routePlan) drawLine(o *overlay) {
+	o.polyline(p.points(), p.rel.Identifying)
+}
+
+// points is the relationship's line as a polyline from a's attach point to
+// b's.
+func (p routePlan) points() [][2]int {
 	if p.merged {
-		o.polyline([][2]int{
+		return [][2]int{
 			{p.a.x, p.a.y}, {p.a.x, p.ya}, {p.b.x, p.ya}, {p.b.x, p.b.y},
-		}, p.rel.Identifying)
-		return
+		}
 	}
-	o.polyline([][2]int{
+	return [][2]int{
 		{p.a.x, p.a.y}, {p.a.x, p.ya}, {p.tx, p.ya}, {p.tx, p.yb}, {p.b.x, p.yb}, {p.b.x, p.b.y},
-	}, p.rel.Identifying)
+	}
 }
 
 // decorate stamps the crow's-foot tokens and the label. Runs after every line
 // is drawn so the label can dodge cells other relationships pass through.
-func (p routePlan) decorate(o *overlay) {
+func (p *routePlan) decorate(o *overlay) {
 	if p.merged {
 		putToken(o, p.a, p.b.x, p.ya)
 		putToken(o, p.b, p.a.x, p.ya)
 		if p.a.p == p.b.p { // self-loop: the run is at most the box's width, so
 			// the label sits beside the loop instead of inside it
-			writeLabel(o, p.rel.Label, max(p.a.x, p.b.x)+2, p.ya, -1)
+			p.labelX, p.labelY = max(p.a.x, p.b.x)+2, p.ya
+			p.labeled = p.rel.Label != ""
+			writeLabel(o, p.rel.Label, p.labelX, p.labelY, -1)
 		} else {
-			putLabel(o, p.rel.Label, [][3]int{{min(p.a.x, p.b.x), max(p.a.x, p.b.x), p.ya}})
+			p.labelX, p.labelY, p.labeled = putLabel(o, p.rel.Label, [][3]int{{min(p.a.x, p.b.x), max(p.a.x, p.b.x), p.ya}})
 		}
 		return
 	}
@@ -522,7 +473,7 @@ func (p routePlan) decorate(o *overlay) {
 	if runs[1][1]-runs[1][0] > runs[0][1]-runs[0][0] {
 		runs[0], runs[1] = runs[1], runs[0] // longest run first
 	}
-	putLabel(o, p.rel.Label, runs)
+	p.labelX, p.labelY, p.labeled = putLabel(o, p.rel.Label, runs)
 }
 
 // putToken stamps a two-cell crow's-foot marker on the gutter row, next to the
@@ -545,10 +496,11 @@ func putToken(o *overlay, ep endpoint, targetX, y int) {
 // putLabel places a label on one of the candidate runs (each {x0,x1,y}),
 // clipped 3 cells at each end to clear the corner and crow's-foot token.
 // Candidates arrive longest-first; the first run offering the label a spot
-// clear of crossing lines wins, then the first it merely fits on.
-func putLabel(o *overlay, s string, runs [][3]int) {
+// clear of crossing lines wins, then the first it merely fits on. It returns
+// where the label starts, or false when there was no room for it at all.
+func putLabel(o *overlay, s string, runs [][3]int) (int, int, bool) {
 	if s == "" {
-		return
+		return 0, 0, false
 	}
 	lw := runewidth.StringWidth(s)
 	type spot struct{ start, cost, y, hi int }
@@ -569,12 +521,13 @@ func putLabel(o *overlay, s string, runs [][3]int) {
 	if best.cost < 0 { // fits nowhere whole: clip on the longest run
 		lo, hi, y := runs[0][0]+3, runs[0][1]-3, runs[0][2]
 		if lo > hi {
-			return
+			return 0, 0, false
 		}
 		start, _ := labelStart(o, lo, hi, lw, y)
 		best = spot{start, 0, y, hi}
 	}
 	writeLabel(o, s, best.start, best.y, best.hi)
+	return best.start, best.y, true
 }
 
 // labelStart picks where the label begins on [lo,hi]: centred, sliding
@@ -630,7 +583,7 @@ func writeLabel(o *overlay, s string, x, y, limit int) {
 
 // composite renders the overlay onto the canvas: line junctions first (only on
 // blank cells so boxes stay intact), then labels and crow's-foot tokens on top.
-func composite(c *canvas, o *overlay, g glyphs) {
+func composite(c *diagram.Canvas, o *overlay, g glyphs) {
 	seen := map[[2]int]bool{}
 	mark := func(x, y int) {
 		p := [2]int{x, y}
@@ -642,10 +595,10 @@ func composite(c *canvas, o *overlay, g glyphs) {
 		if bits == 0 {
 			return
 		}
-		if c.at(x, y) != ' ' {
+		if c.At(x, y) != ' ' {
 			return // don't scribble over a box
 		}
-		c.set(x, y, glyphFor(bits, o.solid[p] != 0, g))
+		c.Set(x, y, glyphFor(bits, o.solid[p] != 0, g))
 	}
 	for p := range o.solid {
 		mark(p[0], p[1])
@@ -654,10 +607,10 @@ func composite(c *canvas, o *overlay, g glyphs) {
 		mark(p[0], p[1])
 	}
 	for p, r := range o.label {
-		c.set(p[0], p[1], r)
+		c.Set(p[0], p[1], r)
 	}
 	for p, r := range o.token {
-		c.set(p[0], p[1], r)
+		c.Set(p[0], p[1], r)
 	}
 }
 
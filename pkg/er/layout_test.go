@@ -0,0 +1,63 @@
+package er
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLayoutMatchesRender checks that the entity boxes and relationship lines
+// Layout reports are where Render draws them.
+func TestLayoutMatchesRender(t *testing.T) {
+	d, err := Parse("erDiagram\n CUSTOMER ||--o{ ORDER : places\n ORDER ||--|{ LINE_ITEM : contains\n CUSTOMER }|..|{ DELIVERY_ADDRESS : uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := Render(d, false)
+	layout := Layout(d, false)
+
+	var rows [][]rune
+	for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		rows = append(rows, []rune(l))
+	}
+	at := func(x, y int) rune {
+		if y < 0 || y >= len(rows) || x < 0 || x >= len(rows[y]) {
+			return ' '
+		}
+		return rows[y][x]
+	}
+	if layout.Height != len(rows) {
+		t.Errorf("height = %d, render has %d rows", layout.Height, len(rows))
+	}
+
+	if len(layout.Entities) != 4 {
+		t.Fatalf("got %d entities, want 4", len(layout.Entities))
+	}
+	for _, e := range layout.Entities {
+		b := e.Box
+		if got := at(b.X, b.Y); got != '┌' {
+			t.Errorf("entity %s top-left = %q", e.ID, got)
+		}
+		if got := at(b.X+b.Width-1, b.Y+b.Height-1); got != '┘' {
+			t.Errorf("entity %s bottom-right = %q", e.ID, got)
+		}
+	}
+
+	if len(layout.Edges) != 3 {
+		t.Fatalf("got %d relationships, want 3", len(layout.Edges))
+	}
+	for _, e := range layout.Edges {
+		for _, p := range []int{0, len(e.Points) - 1} {
+			pt := e.Points[p]
+			if got := at(pt.X, pt.Y); got != '┬' && got != '┴' {
+				t.Errorf("%s-%s endpoint %v is %q, want an attach tee", e.From, e.To, pt, got)
+			}
+		}
+		if e.LabelAt == nil {
+			t.Fatalf("%s-%s has no label position", e.From, e.To)
+		}
+		got := string(rows[e.LabelAt.Y][e.LabelAt.X : e.LabelAt.X+len(e.Label)])
+		if got != e.Label {
+			t.Errorf("%s-%s label reads %q, want %q", e.From, e.To, got, e.Label)
+		}
+	}
+}
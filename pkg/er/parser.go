@@ -147,9 +147,9 @@ func Parse(input string) (*ErDiagram, error) {
 	}
 	// Comments are stripped in place (not filtered out as whole lines) so error
 	// messages report the caller's real line numbers.
-	lines := diagram.SplitLines(strings.TrimSpace(input))
+	lines := diagram.SplitLines(input)
 	for i, l := range lines {
-		lines[i] = stripComment(l)
+		lines[i] = diagram.StripComment(l)
 	}
 
 	d := &ErDiagram{byName: map[string]*Entity{}}
@@ -181,7 +181,7 @@ func Parse(input string) (*ErDiagram, error) {
 		// er subgraphs (unreleased upstream) would otherwise misparse into
 		// bogus entity boxes — reject them loudly instead.
 		if subgraphRegex.MatchString(line) || line == "end" {
-			return nil, fmt.Errorf("line %d: er subgraphs are not supported", i+1)
+			return nil, diagram.LineErrorf(i+1, "er subgraphs are not supported")
 		}
 
 		// `:::class` styling decorations carry no ASCII meaning; strip them
@@ -235,7 +235,7 @@ func Parse(input string) (*ErDiagram, error) {
 			continue
 		}
 
-		return nil, fmt.Errorf("line %d: invalid syntax: %q", i+1, line)
+		return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
 	}
 
 	// A statement-less erDiagram is valid mermaid; it renders as empty output.
@@ -262,7 +262,7 @@ func parseAttributeBlock(lines []string, start int) ([]Attribute, int, error) {
 		if line != "" {
 			attr, err := parseAttribute(line)
 			if err != nil {
-				return nil, i, fmt.Errorf("line %d: %w", i+1, err)
+				return nil, i, diagram.LineErrorf(i+1, "%w", err)
 			}
 			attrs = append(attrs, attr)
 		}
@@ -318,22 +318,6 @@ func stripClassShorthand(line string) string {
 	return strings.Join(parts, `"`)
 }
 
-// stripComment drops a %% comment (whole-line or trailing) from a line.
-// %% inside a quoted string (a label or attribute comment) is kept, matching
-// mermaid's lexer, which tokenizes strings before comments.
-func stripComment(line string) string {
-	inQuote := false
-	for i := 0; i < len(line); i++ {
-		switch {
-		case line[i] == '"':
-			inQuote = !inQuote
-		case !inQuote && line[i] == '%' && i+1 < len(line) && line[i+1] == '%':
-			return strings.TrimRight(line[:i], " \t")
-		}
-	}
-	return line
-}
-
 func firstNonEmpty(a, b string) string {
 	if a != "" {
 		return a
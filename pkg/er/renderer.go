@@ -3,6 +3,7 @@ package er
 import (
 	"strings"
 
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
 	"github.com/mattn/go-runewidth"
 )
 
@@ -125,12 +126,49 @@ func Render(d *ErDiagram, useAscii bool) string {
 	if useAscii {
 		g = asciiGlyphs
 	}
+	c, _, _ := draw(d, g)
+	return c.String()
+}
+
+// Layout draws the diagram the same way Render does and reports where every
+// entity box and relationship line ended up on the canvas.
+func Layout(d *ErDiagram, useAscii bool) *diagram.Layout {
+	g := unicodeGlyphs
+	if useAscii {
+		g = asciiGlyphs
+	}
+	c, lay, plans := draw(d, g)
+	width, height := c.Size()
+	out := &diagram.Layout{Type: "er", Width: width, Height: height}
+	for _, p := range lay.placed {
+		out.Entities = append(out.Entities, diagram.Node{
+			ID:    p.entity.Name,
+			Label: p.entity.Display,
+			Box:   diagram.Rect{X: p.x, Y: p.y, Width: p.w, Height: p.h},
+		})
+	}
+	for _, p := range plans {
+		e := diagram.Edge{From: p.rel.Left, To: p.rel.Right, Label: p.rel.Label}
+		for _, pt := range p.points() {
+			e.Points = append(e.Points, diagram.Point{X: pt[0], Y: pt[1]})
+		}
+		if p.labeled {
+			e.LabelAt = &diagram.Point{X: p.labelX, Y: p.labelY}
+		}
+		out.Edges = append(out.Edges, e)
+	}
+	return out
+}
+
+// draw places the entities, stamps them onto a fresh canvas and routes the
+// relationships between them.
+func draw(d *ErDiagram, g glyphs) (*diagram.Canvas, *layout, []routePlan) {
 	lay := placeEntities(d, g)
 
-	c := &canvas{}
+	c := &diagram.Canvas{}
 	for _, p := range lay.placed {
-		c.stamp(p.x, p.y, p.lines)
+		c.Stamp(p.x, p.y, p.lines)
 	}
-	drawConnectors(c, lay, d, g)
-	return c.String()
+	plans := drawConnectors(c, lay, d, g)
+	return c, lay, plans
 }
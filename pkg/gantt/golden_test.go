@@ -0,0 +1,32 @@
+package gantt
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		g, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(g, config)
+	}
+}
+
+// TestGanttChartRendering tests all gantt chart golden files with Unicode charset.
+func TestGanttChartRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "gantt", renderGolden(false))
+}
+
+// TestGanttChartRendering_ASCII tests gantt chart golden files with ASCII charset.
+func TestGanttChartRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "gantt-ascii", renderGolden(true))
+}
@@ -0,0 +1,545 @@
+// Package gantt parses and renders mermaid gantt charts as ASCII: one row per
+// task, grouped under their sections, with a bar spanning the task's dates on
+// a shared time axis drawn below them.
+package gantt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const ganttKeyword = "gantt"
+
+// defaultDateFormat is the dateFormat tasks' dates are written in unless the
+// chart says otherwise, and defaultAxisFormat the one axis labels are
+// written in.
+const (
+	defaultDateFormat = "YYYY-MM-DD"
+	defaultAxisFormat = "%Y-%m-%d"
+)
+
+// Task is one bar of the chart. A milestone marks a single moment, halfway
+// between its start and end.
+type Task struct {
+	Name       string
+	ID         string
+	Start, End time.Time
+	Done       bool
+	Active     bool
+	Crit       bool
+	Milestone  bool
+}
+
+// Section groups tasks under a heading. Tasks written before the first
+// section statement go in a section without a name.
+type Section struct {
+	Name  string
+	Tasks []*Task
+}
+
+// GanttChart is a parsed gantt chart, with every task's dates worked out.
+type GanttChart struct {
+	Title string
+	// DateFormat is the dayjs-style format task dates are written in and
+	// AxisFormat the strftime-style one axis labels are written in.
+	DateFormat string
+	AxisFormat string
+	// TickInterval is how far apart the axis ticks are, like "1week"; when
+	// empty the renderer picks an interval its labels fit.
+	TickInterval string
+	// WeekStart is the day week ticks fall on: Sunday, as in mermaid,
+	// unless a weekday statement says otherwise.
+	WeekStart time.Weekday
+	Sections  []*Section
+
+	tick *interval
+}
+
+var (
+	// headerRegex matches the diagram declaration.
+	headerRegex = regexp.MustCompile(`^gantt\s*$`)
+
+	// ignoredLineRegex matches display, interaction and accessibility
+	// statements that carry no ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(todayMarker|displayMode|topAxis|click|accTitle|accDescr)\b`)
+
+	// statementRegex matches the `keyword value` statements that set up the
+	// chart.
+	statementRegex = regexp.MustCompile(`^(title|dateFormat|axisFormat|tickInterval|excludes|includes|weekday|weekend|section)\s+(.+)$`)
+
+	// taskRegex matches `Task name : metadata`.
+	taskRegex = regexp.MustCompile(`^([^:]+?)\s*:\s*(.+)$`)
+
+	// durationRegex matches a task length such as 3d, 12h or 1.5w.
+	durationRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ms|[smhdwMy])$`)
+
+	// tickIntervalRegex matches a tickInterval such as 1day or 2week.
+	tickIntervalRegex = regexp.MustCompile(`^([1-9]\d*)(minute|hour|day|week|month|year)$`)
+)
+
+// IsGanttChart reports whether the input's first meaningful line declares a
+// gantt chart.
+func IsGanttChart(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// pendingTask is a task whose dates are still as written. start is empty
+// when the task starts where the one before it, prev, ends.
+type pendingTask struct {
+	task       *Task
+	line       int
+	start, end string
+	prev       *pendingTask
+	resolved   bool
+}
+
+// parser holds the chart-wide settings task dates are worked out with.
+type parser struct {
+	dateLayout        string
+	inclusiveEndDates bool
+	weekend           [2]time.Weekday
+	excludeWeekends   bool
+	excludeDays       map[time.Weekday]bool
+	excludeDates      map[string]bool
+	includeDates      map[string]bool
+	byID              map[string]*pendingTask
+}
+
+// Parse parses a gantt chart and works out when each of its tasks starts and
+// ends.
+func Parse(input string) (*GanttChart, error) {
+	if !IsGanttChart(input) {
+		return nil, fmt.Errorf("expected %q keyword", ganttKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	g := &GanttChart{DateFormat: defaultDateFormat, AxisFormat: defaultAxisFormat}
+	p := &parser{
+		weekend:      [2]time.Weekday{time.Saturday, time.Sunday},
+		excludeDays:  map[time.Weekday]bool{},
+		excludeDates: map[string]bool{},
+		includeDates: map[string]bool{},
+		byID:         map[string]*pendingTask{},
+	}
+	var excludes, includes []listEntry
+	var tasks []*pendingTask
+	var section *Section
+	seenKeyword := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			seenKeyword = true
+			continue
+		}
+		if ignoredLineRegex.MatchString(line) {
+			continue
+		}
+		if line == "inclusiveEndDates" {
+			p.inclusiveEndDates = true
+			continue
+		}
+
+		if m := statementRegex.FindStringSubmatch(line); m != nil {
+			value := strings.TrimSpace(m[2])
+			switch m[1] {
+			case "title":
+				g.Title = value
+			case "dateFormat":
+				g.DateFormat = value
+			case "axisFormat":
+				g.AxisFormat = value
+			case "tickInterval":
+				iv, ok := parseInterval(value)
+				if !ok {
+					return nil, diagram.LineErrorf(i+1, "invalid tickInterval %q", value)
+				}
+				g.TickInterval, g.tick = value, &iv
+			case "excludes":
+				excludes = append(excludes, listEntries(value, i+1)...)
+			case "includes":
+				includes = append(includes, listEntries(value, i+1)...)
+			case "weekday":
+				// The week ticks start on this day.
+				day, ok := parseWeekday(value)
+				if !ok {
+					return nil, diagram.LineErrorf(i+1, "invalid weekday %q", value)
+				}
+				g.WeekStart = day
+			case "weekend":
+				switch strings.ToLower(value) {
+				case "friday":
+					p.weekend = [2]time.Weekday{time.Friday, time.Saturday}
+				case "saturday":
+					p.weekend = [2]time.Weekday{time.Saturday, time.Sunday}
+				default:
+					return nil, diagram.LineErrorf(i+1, "invalid weekend %q", value)
+				}
+			case "section":
+				section = &Section{Name: value}
+				g.Sections = append(g.Sections, section)
+			}
+			continue
+		}
+
+		if m := taskRegex.FindStringSubmatch(line); m != nil {
+			if section == nil {
+				section = &Section{}
+				g.Sections = append(g.Sections, section)
+			}
+			t, err := p.newTask(m[1], m[2], i+1, len(tasks)+1)
+			if err != nil {
+				return nil, err
+			}
+			if len(tasks) > 0 {
+				t.prev = tasks[len(tasks)-1]
+			}
+			tasks = append(tasks, t)
+			section.Tasks = append(section.Tasks, t.task)
+			continue
+		}
+		return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
+	}
+
+	layout, err := dateLayout(g.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	p.dateLayout = layout
+	if err := p.setExcludes(excludes, includes); err != nil {
+		return nil, err
+	}
+	if err := p.resolve(tasks); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// newTask reads a task line's metadata: any done, active, crit and milestone
+// tags, then its start and end as mermaid reads them. One item is the end of
+// a task that starts where the previous one ends, two are its start and end,
+// and three an id, start and end.
+func (p *parser) newTask(name, metadata string, line, n int) (*pendingTask, error) {
+	t := &pendingTask{task: &Task{Name: name, ID: fmt.Sprintf("task%d", n)}, line: line}
+	items := splitList(metadata)
+tags:
+	for len(items) > 0 {
+		switch items[0] {
+		case "done":
+			t.task.Done = true
+		case "active":
+			t.task.Active = true
+		case "crit":
+			t.task.Crit = true
+		case "milestone":
+			t.task.Milestone = true
+		default:
+			break tags
+		}
+		items = items[1:]
+	}
+	switch len(items) {
+	case 1:
+		t.end = items[0]
+	case 2:
+		t.start, t.end = items[0], items[1]
+	case 3:
+		t.task.ID, t.start, t.end = items[0], items[1], items[2]
+	default:
+		return nil, diagram.LineErrorf(line, "task %q needs an end, a start and end, or an id, start and end", name)
+	}
+	if _, ok := p.byID[t.task.ID]; ok {
+		return nil, diagram.LineErrorf(line, "duplicate task id %q", t.task.ID)
+	}
+	p.byID[t.task.ID] = t
+	return t, nil
+}
+
+// resolve works out the dates of every task. Tasks may refer to ones written
+// after them, so it goes over them until every task's dates are known.
+func (p *parser) resolve(tasks []*pendingTask) error {
+	for _, t := range tasks {
+		for _, id := range p.refs(t) {
+			if _, ok := p.byID[id]; !ok {
+				return diagram.LineErrorf(t.line, "task %q refers to unknown task %q", t.task.Name, id)
+			}
+		}
+	}
+	for left := len(tasks); left > 0; {
+		progress := false
+		for _, t := range tasks {
+			if t.resolved || !p.ready(t) {
+				continue
+			}
+			if err := p.resolveTask(t); err != nil {
+				return err
+			}
+			t.resolved = true
+			progress = true
+			left--
+		}
+		if !progress {
+			for _, t := range tasks {
+				if !t.resolved {
+					return diagram.LineErrorf(t.line, "task %q depends on itself", t.task.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// refs returns the ids of the tasks t's start or end refers to.
+func (p *parser) refs(t *pendingTask) []string {
+	var ids []string
+	for _, spec := range []string{t.start, t.end} {
+		for _, keyword := range []string{"after", "until"} {
+			if rest, ok := strings.CutPrefix(spec, keyword+" "); ok {
+				ids = append(ids, strings.Fields(rest)...)
+			}
+		}
+	}
+	return ids
+}
+
+// ready reports whether the dates of every task t's depend on are known.
+func (p *parser) ready(t *pendingTask) bool {
+	if t.start == "" && t.prev != nil && !t.prev.resolved {
+		return false
+	}
+	for _, id := range p.refs(t) {
+		if !p.byID[id].resolved {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTask works out t's dates, once those of the tasks it depends on are
+// known.
+func (p *parser) resolveTask(t *pendingTask) error {
+	task := t.task
+	switch rest, after := strings.CutPrefix(t.start, "after "); {
+	case t.start == "":
+		if t.prev == nil {
+			return diagram.LineErrorf(t.line, "task %q has no start date", task.Name)
+		}
+		task.Start = t.prev.task.End
+	case after:
+		// A task after several others starts once the last of them ends.
+		for i, id := range strings.Fields(rest) {
+			if end := p.byID[id].task.End; i == 0 || end.After(task.Start) {
+				task.Start = end
+			}
+		}
+	default:
+		start, err := time.Parse(p.dateLayout, t.start)
+		if err != nil {
+			return diagram.LineErrorf(t.line, "task %q: invalid start date %q", task.Name, t.start)
+		}
+		task.Start = start
+	}
+
+	if rest, until := strings.CutPrefix(t.end, "until "); until {
+		// A task until several others ends once the first of them starts.
+		for i, id := range strings.Fields(rest) {
+			if start := p.byID[id].task.Start; i == 0 || start.Before(task.End) {
+				task.End = start
+			}
+		}
+	} else if end, err := time.Parse(p.dateLayout, t.end); err == nil {
+		task.End = end
+		if p.inclusiveEndDates {
+			task.End = task.End.AddDate(0, 0, 1)
+		}
+	} else if m := durationRegex.FindStringSubmatch(t.end); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		task.End = p.skipExcluded(task.Start, addDuration(task.Start, n, m[2]))
+	} else {
+		return diagram.LineErrorf(t.line, "task %q: invalid end date or duration %q", task.Name, t.end)
+	}
+	if task.End.Before(task.Start) {
+		return diagram.LineErrorf(t.line, "task %q ends before it starts", task.Name)
+	}
+	return nil
+}
+
+// addDuration adds n of the given unit to t. Months and years are calendar
+// months and years; any fraction of one is taken as 30 or 365 days.
+func addDuration(t time.Time, n float64, unit string) time.Time {
+	const day = 24 * time.Hour
+	switch unit {
+	case "ms":
+		return t.Add(time.Duration(n * float64(time.Millisecond)))
+	case "s":
+		return t.Add(time.Duration(n * float64(time.Second)))
+	case "m":
+		return t.Add(time.Duration(n * float64(time.Minute)))
+	case "h":
+		return t.Add(time.Duration(n * float64(time.Hour)))
+	case "d":
+		return t.Add(time.Duration(n * float64(day)))
+	case "w":
+		return t.Add(time.Duration(n * 7 * float64(day)))
+	case "M":
+		whole := int(n)
+		return t.AddDate(0, whole, 0).Add(time.Duration((n - float64(whole)) * 30 * float64(day)))
+	}
+	whole := int(n)
+	return t.AddDate(whole, 0, 0).Add(time.Duration((n - float64(whole)) * 365 * float64(day)))
+}
+
+// listEntry is an entry of an excludes or includes list, with the line it's
+// on.
+type listEntry struct {
+	text string
+	line int
+}
+
+// listEntries splits the list value on line into its entries.
+func listEntries(value string, line int) []listEntry {
+	var entries []listEntry
+	for _, text := range splitList(value) {
+		entries = append(entries, listEntry{text: text, line: line})
+	}
+	return entries
+}
+
+// setExcludes reads the days tasks don't run on: "weekends", names of the
+// days of the week and dates, less the dates in includes.
+func (p *parser) setExcludes(excludes, includes []listEntry) error {
+	for _, e := range excludes {
+		if strings.EqualFold(e.text, "weekends") {
+			p.excludeWeekends = true
+			continue
+		}
+		if day, ok := parseWeekday(e.text); ok {
+			p.excludeDays[day] = true
+			continue
+		}
+		date, err := time.Parse(p.dateLayout, e.text)
+		if err != nil {
+			return diagram.LineErrorf(e.line, "invalid excludes entry %q: want weekends, a day of the week or a date", e.text)
+		}
+		p.excludeDates[date.Format(time.DateOnly)] = true
+	}
+	for _, e := range includes {
+		date, err := time.Parse(p.dateLayout, e.text)
+		if err != nil {
+			return diagram.LineErrorf(e.line, "invalid includes entry %q: want a date", e.text)
+		}
+		p.includeDates[date.Format(time.DateOnly)] = true
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if !p.excluded(time.Date(2000, 1, 2+int(day), 0, 0, 0, 0, time.UTC)) {
+			return nil
+		}
+	}
+	// Only days of the week can leave none, so there's an excludes list.
+	return diagram.LineErrorf(excludes[len(excludes)-1].line, "excludes leaves no day of the week to work on")
+}
+
+// excluded reports whether no task runs on the day of t.
+func (p *parser) excluded(t time.Time) bool {
+	date := t.Format(time.DateOnly)
+	if p.includeDates[date] {
+		return false
+	}
+	if p.excludeWeekends && (t.Weekday() == p.weekend[0] || t.Weekday() == p.weekend[1]) {
+		return true
+	}
+	return p.excludeDays[t.Weekday()] || p.excludeDates[date]
+}
+
+// skipExcluded moves the end of a task that runs from start to end a day
+// later for every excluded day it runs over, as mermaid does.
+func (p *parser) skipExcluded(start, end time.Time) time.Time {
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if p.excluded(day) {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+	return end
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(s, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// dayjsTokens maps the dayjs format tokens dateFormat may use to the Go
+// layout elements that parse them, longest first so MMMM wins over MM.
+var dayjsTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"MMMM", "January"}, {"dddd", "Monday"},
+	{"MMM", "Jan"}, {"ddd", "Mon"}, {"SSS", "000"},
+	{"YY", "06"}, {"MM", "01"}, {"DD", "02"}, {"HH", "15"}, {"hh", "03"},
+	{"mm", "04"}, {"ss", "05"},
+	{"M", "1"}, {"D", "2"}, {"H", "15"}, {"h", "3"}, {"m", "4"}, {"s", "5"},
+	{"A", "PM"}, {"a", "pm"}, {"Z", "-07:00"},
+}
+
+// dateLayout converts a dayjs dateFormat into a Go time layout. Text in
+// [brackets] and anything that isn't a token is kept as it is.
+func dateLayout(format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		if format[i] == '[' {
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("invalid dateFormat %q: unclosed '['", format)
+			}
+			b.WriteString(format[i+1 : i+end])
+			i += end + 1
+			continue
+		}
+		matched := false
+		for _, t := range dayjsTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				b.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("invalid dateFormat %q", format)
+	}
+	return b.String(), nil
+}
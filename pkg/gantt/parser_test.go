@@ -0,0 +1,157 @@
+package gantt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsGanttChart(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"gantt\n A : 1d", true},
+		{"%% leading comment\ngantt", true},
+		{"ganttChart\n A", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsGanttChart(c.in); got != c.want {
+			t.Errorf("IsGanttChart(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// tasks returns the chart's tasks by name.
+func tasks(g *GanttChart) map[string]*Task {
+	byName := map[string]*Task{}
+	for _, s := range g.Sections {
+		for _, t := range s.Tasks {
+			byName[t.Name] = t
+		}
+	}
+	return byName
+}
+
+func TestParseTaskDates(t *testing.T) {
+	g, err := Parse(`gantt
+    dateFormat YYYY-MM-DD
+    section One
+    A : a, 2024-01-01, 3d
+    B : 2d
+    C : c, after a task2, 1w
+    section Two
+    D : d, 2024-01-02, until c
+    E : after later, 2024-01-20
+    F : later, 2024-01-10, 12h`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][2]time.Time{
+		"A": {date("2024-01-01"), date("2024-01-04")},
+		"B": {date("2024-01-04"), date("2024-01-06")},
+		"C": {date("2024-01-06"), date("2024-01-13")},
+		"D": {date("2024-01-02"), date("2024-01-06")},
+		"E": {date("2024-01-10").Add(12 * time.Hour), date("2024-01-20")},
+		"F": {date("2024-01-10"), date("2024-01-10").Add(12 * time.Hour)},
+	} {
+		task := tasks(g)[name]
+		if !task.Start.Equal(want[0]) || !task.End.Equal(want[1]) {
+			t.Errorf("%s runs %v to %v, want %v to %v", name, task.Start, task.End, want[0], want[1])
+		}
+	}
+	if len(g.Sections) != 2 || len(g.Sections[0].Tasks) != 3 {
+		t.Errorf("want sections of 3 and 3 tasks, got %+v", g.Sections)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	g, err := Parse("gantt\n A : crit, done, a, 2024-01-01, 1d\n B : active, milestone, 2024-01-02, 0d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := tasks(g)["A"], tasks(g)["B"]
+	if !a.Crit || !a.Done || a.Active || a.Milestone || a.ID != "a" {
+		t.Errorf("A = %+v, want crit, done with id a", a)
+	}
+	if !b.Active || !b.Milestone || b.Crit || b.Done || b.ID != "task2" {
+		t.Errorf("B = %+v, want active milestone with id task2", b)
+	}
+}
+
+func TestParseExcludes(t *testing.T) {
+	for _, c := range []struct {
+		name, statements string
+		end              string
+	}{
+		// Friday plus a weekend; the end moves past the weekend.
+		{"weekends", "excludes weekends", "2024-01-09"},
+		{"weekday names", "excludes saturday, sunday", "2024-01-09"},
+		{"dates", "excludes 2024-01-06, 2024-01-07", "2024-01-09"},
+		{"includes", "excludes weekends\n includes 2024-01-06", "2024-01-08"},
+		{"friday weekend", "weekend friday\n excludes weekends", "2024-01-09"},
+		{"inclusive end dates", "excludes weekends\n inclusiveEndDates", "2024-01-09"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			g, err := Parse("gantt\n " + c.statements + "\n A : 2024-01-05, 2d")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tasks(g)["A"].End; !got.Equal(date(c.end)) {
+				t.Errorf("A ends %v, want %s", got, c.end)
+			}
+		})
+	}
+
+	g, err := Parse("gantt\n excludes weekends\n inclusiveEndDates\n A : 2024-01-05, 2024-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An end date is kept as written, a day later for inclusive end dates.
+	if got := tasks(g)["A"].End; !got.Equal(date("2024-01-07")) {
+		t.Errorf("A ends %v, want 2024-01-07", got)
+	}
+}
+
+func TestParseDateFormat(t *testing.T) {
+	g, err := Parse("gantt\n dateFormat DD/MM/YYYY HH:mm\n A : 05/01/2024 13:30, 90m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 5, 13, 30, 0, 0, time.UTC)
+	if a := tasks(g)["A"]; !a.Start.Equal(want) || !a.End.Equal(want.Add(90*time.Minute)) {
+		t.Errorf("A runs %v to %v, want 90 minutes from %v", a.Start, a.End, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"gantt\n A : 1d", "no start date"},
+		{"gantt\n A : 2024-01-01, 1d\n B : after x, 1d", `unknown task "x"`},
+		{"gantt\n A : a, after b, 1d\n B : b, after a, 1d", "depends on itself"},
+		{"gantt\n A : a, 2024-01-01, 1d\n B : a, 2024-01-01, 1d", `duplicate task id "a"`},
+		{"gantt\n A : 2024-01-01, soon", "invalid end date or duration"},
+		{"gantt\n A : 01/01/2024, 1d", "invalid start date"},
+		{"gantt\n A : 2024-01-05, 2024-01-01", "ends before it starts"},
+		{"gantt\n tickInterval 3fortnight", "invalid tickInterval"},
+		{"gantt\n excludes weekends, monday, tuesday, wednesday, thursday, friday\n A : 2024-01-01, 1d", "line 2: excludes leaves no day of the week"},
+		{"gantt\n title T\n excludes someday", "line 3: invalid excludes entry"},
+		{"gantt\n what is this", "invalid syntax"},
+	} {
+		_, err := Parse(c.in)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", c.in, err, c.want)
+		}
+	}
+}
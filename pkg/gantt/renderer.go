@@ -0,0 +1,304 @@
+package gantt
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a chart is drawn with (Unicode by default,
+// ASCII when useAscii).
+type glyphs struct {
+	axis, corner, tick, separator rune
+	// Bar fills: a task still to do, one in progress, one done and a
+	// milestone.
+	bar, active, done, milestone rune
+	crit                         rune // beside the name of a critical task
+}
+
+var unicodeGlyphs = glyphs{
+	axis: '─', corner: '└', tick: '┬', separator: '│',
+	bar: '█', active: '▒', done: '░', milestone: '◆', crit: '!',
+}
+
+var asciiGlyphs = glyphs{
+	axis: '-', corner: '+', tick: '+', separator: '|',
+	bar: '#', active: '=', done: '.', milestone: '*', crit: '!',
+}
+
+// interval is the spacing of the axis ticks: n of unit, which is one of
+// minute, hour, day, week, month and year.
+type interval struct {
+	n    int
+	unit string
+}
+
+// tickIntervals are the spacings the axis ticks may be given, from the
+// closest to the farthest apart.
+var tickIntervals = []interval{
+	{1, "minute"}, {5, "minute"}, {15, "minute"}, {30, "minute"},
+	{1, "hour"}, {3, "hour"}, {6, "hour"}, {12, "hour"},
+	{1, "day"}, {2, "day"}, {1, "week"}, {2, "week"},
+	{1, "month"}, {3, "month"}, {6, "month"},
+	{1, "year"}, {2, "year"}, {5, "year"}, {10, "year"}, {25, "year"}, {50, "year"}, {100, "year"},
+}
+
+func parseInterval(s string) (interval, bool) {
+	m := tickIntervalRegex.FindStringSubmatch(s)
+	if m == nil {
+		return interval{}, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return interval{}, false
+	}
+	return interval{n, m[2]}, true
+}
+
+// Render draws the chart: a title, then a row per section heading and per
+// task, the task's bar spanning its dates on an axis config.GanttWidth
+// columns wide, and the axis itself with its date labels below them.
+// Critical tasks are flagged beside their name.
+func Render(g *GanttChart, config *diagram.Config) (string, error) {
+	gl := unicodeGlyphs
+	if config.UseAscii {
+		gl = asciiGlyphs
+	}
+	width := config.GanttWidth
+	if width <= 0 {
+		width = diagram.DefaultConfig().GanttWidth
+	}
+
+	var tasks []*Task
+	nameWidth := 0
+	for _, s := range g.Sections {
+		nameWidth = max(nameWidth, runewidth.StringWidth(s.Name))
+		for _, t := range s.Tasks {
+			tasks = append(tasks, t)
+			nameWidth = max(nameWidth, runewidth.StringWidth(taskIndent(s)+t.Name))
+		}
+	}
+	if len(tasks) == 0 {
+		return g.Title + "\n", nil
+	}
+
+	from, to := tasks[0].Start, tasks[0].End
+	for _, t := range tasks {
+		if t.Start.Before(from) {
+			from = t.Start
+		}
+		if t.End.After(to) {
+			to = t.End
+		}
+	}
+	if !to.After(from) {
+		to = from.Add(24 * time.Hour)
+	}
+	a := axis{from: from, to: to, width: width}
+
+	// Every row starts with the names, a column for the critical flag and
+	// the separator the bars hang off.
+	prefix := func(name string, crit bool) string {
+		flag := " "
+		if crit {
+			flag = string(gl.crit)
+		}
+		return name + strings.Repeat(" ", nameWidth-runewidth.StringWidth(name)) + " " + flag + string(gl.separator)
+	}
+
+	var lines []string
+	if g.Title != "" {
+		total := nameWidth + 3 + width
+		pad := max(0, (total-runewidth.StringWidth(g.Title))/2)
+		lines = append(lines, strings.Repeat(" ", pad)+g.Title, "")
+	}
+	for _, s := range g.Sections {
+		if s.Name != "" {
+			lines = append(lines, prefix(s.Name, false))
+		}
+		for _, t := range s.Tasks {
+			lines = append(lines, prefix(taskIndent(s)+t.Name, t.Crit)+string(a.bar(t, gl)))
+		}
+	}
+
+	axisLine := []rune(strings.Repeat(string(gl.axis), width))
+	labels := []rune(strings.Repeat(" ", width))
+	for _, tk := range a.ticks(g) {
+		axisLine[tk.column] = gl.tick
+		for len(labels) < tk.column+len([]rune(tk.label)) {
+			labels = append(labels, ' ')
+		}
+		copy(labels[tk.column:], []rune(tk.label))
+	}
+	indent := strings.Repeat(" ", nameWidth+2)
+	lines = append(lines, indent+string(gl.corner)+string(axisLine), indent+" "+string(labels))
+
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// taskIndent is what a task's name is indented by under its section's
+// heading.
+func taskIndent(s *Section) string {
+	if s.Name == "" {
+		return ""
+	}
+	return "  "
+}
+
+// axis maps the times from..to onto width columns.
+type axis struct {
+	from, to time.Time
+	width    int
+}
+
+// column is the column time t falls in; to is just past the last one.
+func (a axis) column(t time.Time) int {
+	return int(float64(t.Sub(a.from)) * float64(a.width) / float64(a.to.Sub(a.from)))
+}
+
+// bar draws t's bar across the axis: a fill from its start to its end, at
+// least a column wide, or a single mark halfway for a milestone.
+func (a axis) bar(t *Task, gl glyphs) []rune {
+	row := []rune(strings.Repeat(" ", a.width))
+	if t.Milestone {
+		at := min(a.column(t.Start.Add(t.End.Sub(t.Start)/2)), a.width-1)
+		row[at] = gl.milestone
+		return row
+	}
+	fill := gl.bar
+	switch {
+	case t.Done:
+		fill = gl.done
+	case t.Active:
+		fill = gl.active
+	}
+	start := min(a.column(t.Start), a.width-1)
+	end := max(a.column(t.End), start+1)
+	for c := start; c < end; c++ {
+		row[c] = fill
+	}
+	return row
+}
+
+// tick is a labelled mark on the axis.
+type tick struct {
+	column int
+	label  string
+}
+
+// ticks returns the marks along the axis, at g's tickInterval or else the
+// closest spacing that leaves room between every label.
+func (a axis) ticks(g *GanttChart) []tick {
+	if g.tick != nil {
+		return a.ticksEvery(*g.tick, g, true)
+	}
+	for _, iv := range tickIntervals {
+		if ticks := a.ticksEvery(iv, g, false); ticks != nil {
+			return ticks
+		}
+	}
+	return []tick{{0, formatAxis(a.from, g.AxisFormat)}}
+}
+
+// ticksEvery returns a mark for every iv along the axis. Labels that would
+// run into the one before are left out when dropCrowded is set; otherwise
+// ticksEvery gives up and returns nil, as it does when no mark falls on the
+// axis.
+func (a axis) ticksEvery(iv interval, g *GanttChart, dropCrowded bool) []tick {
+	var ticks []tick
+	taken := -1 // the last column a label takes up
+	for t := floorTime(a.from, iv, g.WeekStart); t.Before(a.to); t = nextTime(t, iv) {
+		if t.Before(a.from) {
+			continue
+		}
+		c := a.column(t)
+		if c >= a.width {
+			break
+		}
+		label := formatAxis(t, g.AxisFormat)
+		if c <= taken {
+			if !dropCrowded {
+				return nil
+			}
+			continue
+		}
+		ticks = append(ticks, tick{c, label})
+		taken = c + runewidth.StringWidth(label)
+	}
+	return ticks
+}
+
+// floorTime returns the last time at or before t that a tick every iv falls
+// on: the start of a minute, hour, day, week, month or year, lined up on a
+// multiple of n.
+func floorTime(t time.Time, iv interval, weekStart time.Weekday) time.Time {
+	y, mo, d := t.Date()
+	switch iv.unit {
+	case "minute":
+		return t.Truncate(time.Duration(iv.n) * time.Minute)
+	case "hour":
+		return t.Truncate(time.Duration(iv.n) * time.Hour)
+	case "day":
+		return time.Date(y, mo, d, 0, 0, 0, 0, t.Location())
+	case "week":
+		back := (int(t.Weekday()) - int(weekStart) + 7) % 7
+		return time.Date(y, mo, d-back, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(y, mo-time.Month((int(mo)-1)%iv.n), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y-y%iv.n, 1, 1, 0, 0, 0, 0, t.Location())
+}
+
+// nextTime returns the time a tick every iv after t falls on.
+func nextTime(t time.Time, iv interval) time.Time {
+	switch iv.unit {
+	case "minute":
+		return t.Add(time.Duration(iv.n) * time.Minute)
+	case "hour":
+		return t.Add(time.Duration(iv.n) * time.Hour)
+	case "day":
+		return t.AddDate(0, 0, iv.n)
+	case "week":
+		return t.AddDate(0, 0, 7*iv.n)
+	case "month":
+		return t.AddDate(0, iv.n, 0)
+	}
+	return t.AddDate(iv.n, 0, 0)
+}
+
+// strftimeLayouts maps the strftime directives axisFormat may use to the Go
+// layout elements that write them.
+var strftimeLayouts = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2",
+	'b': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+	'H': "15", 'I': "03", 'p': "PM", 'M': "04", 'S': "05", 'j': "002",
+}
+
+// formatAxis writes t in a strftime-style axisFormat. Unknown directives are
+// written as they are.
+func formatAxis(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; {
+		case c == '%':
+			b.WriteByte('%')
+		case strftimeLayouts[c] != "":
+			b.WriteString(t.Format(strftimeLayouts[c]))
+		default:
+			b.WriteByte('%')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package gitgraph
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		g, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(g, config)
+	}
+}
+
+// TestGitGraphRendering tests all gitGraph golden files with Unicode charset.
+func TestGitGraphRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "gitgraph", renderGolden(false))
+}
+
+// TestGitGraphRendering_ASCII tests gitGraph golden files with ASCII charset.
+func TestGitGraphRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "gitgraph-ascii", renderGolden(true))
+}
@@ -0,0 +1,365 @@
+// Package gitgraph parses mermaid gitGraph diagrams and renders them as
+// ASCII in the style of `git log --graph`: a lane per branch, commits as
+// marks along their branch's lane, and lines joining each commit to its
+// parents where branches fork off and merge back.
+package gitgraph
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const gitGraphKeyword = "gitGraph"
+
+// mainBranch is the branch a graph starts on.
+const mainBranch = "main"
+
+// CommitType is how a commit is marked.
+type CommitType int
+
+const (
+	CommitNormal    CommitType = iota
+	CommitReverse              // a commit undoing another
+	CommitHighlight            // a commit to draw attention to
+)
+
+// Commit is one commit of the graph. Parents holds the commit it was made
+// on top of and, for a merge, the head of the branch merged in.
+type Commit struct {
+	ID      string
+	Message string
+	Type    CommitType
+	Tags    []string
+	Branch  *Branch
+	Parents []*Commit
+	// CherryPicked is the commit a cherry-pick copied, if this is one.
+	CherryPicked *Commit
+	seq          int
+}
+
+// IsMerge reports whether c joins two branches.
+func (c *Commit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+// Branch is a line of commits. Head is its latest commit, or the commit it
+// was created on until it has one of its own.
+type Branch struct {
+	Name  string
+	Head  *Commit
+	order int
+}
+
+// GitGraph is a parsed gitGraph. Commits are in the order they were made;
+// Branches are in the order their lanes are drawn.
+type GitGraph struct {
+	// Direction is "LR" (the default), "TB" or "BT".
+	Direction string
+	Commits   []*Commit
+	Branches  []*Branch
+}
+
+var (
+	// headerRegex matches the diagram declaration with its orientation.
+	headerRegex = regexp.MustCompile(`^gitGraph(?:\s+(LR|TB|BT))?\s*:?\s*$`)
+
+	// ignoredLineRegex matches accessibility statements, which carry no
+	// ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(accTitle|accDescr)\b`)
+
+	// commandRegex splits a line into its command and the rest.
+	commandRegex = regexp.MustCompile(`^(commit|branch|checkout|switch|merge|cherry-pick)\b\s*(.*)$`)
+
+	// optionRegex matches one `key: value` option; values are quoted or a
+	// single word.
+	optionRegex = regexp.MustCompile(`^(id|tag|type|msg|order|parent)\s*:\s*(?:"([^"]*)"|(\S+))\s*`)
+
+	// nameRegex matches a branch name, quoted or not, with the options
+	// after it.
+	nameRegex = regexp.MustCompile(`^(?:"([^"]+)"|([^\s"]+))\s*(.*)$`)
+)
+
+// IsGitGraph reports whether the input's first meaningful line declares a
+// gitGraph.
+func IsGitGraph(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// parser is the state the commands of a gitGraph are run against.
+type parser struct {
+	g        *GitGraph
+	current  *Branch
+	branches map[string]*Branch
+	commits  map[string]*Commit
+}
+
+// Parse runs the commands of a gitGraph and returns the commits and
+// branches they leave. Commands that git would refuse, like merging a
+// branch into itself, are errors.
+func Parse(input string) (*GitGraph, error) {
+	if !IsGitGraph(input) {
+		return nil, fmt.Errorf("expected %q keyword", gitGraphKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	p := &parser{
+		g:        &GitGraph{Direction: "LR"},
+		branches: map[string]*Branch{},
+		commits:  map[string]*Commit{},
+	}
+	p.current = p.addBranch(mainBranch)
+	seenKeyword := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			if m := headerRegex.FindStringSubmatch(line); m[1] != "" {
+				p.g.Direction = m[1]
+			}
+			seenKeyword = true
+			continue
+		}
+		if ignoredLineRegex.MatchString(line) {
+			continue
+		}
+		m := commandRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
+		}
+		if err := p.run(m[1], m[2]); err != nil {
+			return nil, diagram.LineErrorf(i+1, "%w", err)
+		}
+	}
+
+	// Lanes are drawn by their order option, then in the order the branches
+	// were created.
+	branches := p.g.Branches
+	for i := 1; i < len(branches); i++ {
+		for j := i; j > 0 && branches[j].order < branches[j-1].order; j-- {
+			branches[j], branches[j-1] = branches[j-1], branches[j]
+		}
+	}
+	return p.g, nil
+}
+
+// run runs one command with the arguments written after it.
+func (p *parser) run(command, args string) error {
+	switch command {
+	case "commit":
+		opts, err := parseOptions(args, "id", "tag", "type", "msg")
+		if err != nil {
+			return err
+		}
+		_, err = p.commit(opts, p.current.Head)
+		return err
+
+	case "branch":
+		name, opts, err := parseName(args, "order")
+		if err != nil {
+			return err
+		}
+		if _, ok := p.branches[name]; ok {
+			return fmt.Errorf("branch %q already exists", name)
+		}
+		b := p.addBranch(name)
+		if order, ok := opts["order"]; ok {
+			n, err := strconv.Atoi(order)
+			if err != nil {
+				return fmt.Errorf("invalid order %q for branch %q", order, name)
+			}
+			b.order = n
+		}
+		b.Head = p.current.Head
+		p.current = b
+		return nil
+
+	case "checkout", "switch":
+		name, _, err := parseName(args)
+		if err != nil {
+			return err
+		}
+		b, ok := p.branches[name]
+		if !ok {
+			return fmt.Errorf("cannot %s to unknown branch %q", command, name)
+		}
+		p.current = b
+		return nil
+
+	case "merge":
+		name, opts, err := parseName(args, "id", "tag", "type")
+		if err != nil {
+			return err
+		}
+		other, ok := p.branches[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("cannot merge unknown branch %q", name)
+		case other == p.current:
+			return fmt.Errorf("cannot merge branch %q into itself", name)
+		case p.current.Head == nil:
+			return fmt.Errorf("cannot merge into branch %q, which has no commits", p.current.Name)
+		case other.Head == nil:
+			return fmt.Errorf("cannot merge branch %q, which has no commits", name)
+		case isAncestor(other.Head, p.current.Head):
+			return fmt.Errorf("branch %q has nothing to merge into %q", name, p.current.Name)
+		}
+		_, err = p.commit(opts, p.current.Head, other.Head)
+		return err
+
+	case "cherry-pick":
+		opts, err := parseOptions(args, "id", "tag", "parent")
+		if err != nil {
+			return err
+		}
+		source, ok := p.commits[opts["id"]]
+		switch {
+		case opts["id"] == "":
+			return fmt.Errorf("cherry-pick needs the id of the commit to pick")
+		case !ok:
+			return fmt.Errorf("cannot cherry-pick unknown commit %q", opts["id"])
+		case source.Branch == p.current:
+			return fmt.Errorf("cannot cherry-pick %q onto its own branch %q", source.ID, p.current.Name)
+		case p.current.Head == nil:
+			return fmt.Errorf("cannot cherry-pick onto branch %q, which has no commits", p.current.Name)
+		}
+		tag := "cherry-pick:" + source.ID
+		if source.IsMerge() {
+			parent, ok := opts["parent"]
+			if !ok {
+				return fmt.Errorf("cherry-picking merge commit %q needs a parent", source.ID)
+			}
+			if source.Parents[0].ID != parent && source.Parents[1].ID != parent {
+				return fmt.Errorf("%q is not a parent of merge commit %q", parent, source.ID)
+			}
+			tag += "|parent:" + parent
+		}
+		if _, ok := opts["tag"]; !ok {
+			opts["tag"] = tag
+		}
+		delete(opts, "id")
+		c, err := p.commit(opts, p.current.Head)
+		if err != nil {
+			return err
+		}
+		c.CherryPicked = source
+		return nil
+	}
+	return nil
+}
+
+func (p *parser) addBranch(name string) *Branch {
+	b := &Branch{Name: name, order: len(p.g.Branches)}
+	p.branches[name] = b
+	p.g.Branches = append(p.g.Branches, b)
+	return b
+}
+
+// commit adds a commit with the given options on top of parents to the
+// current branch. Without an id option it gets an id hashed from its place
+// in the graph, so it reads like a git one but stays the same from one run
+// to the next.
+func (p *parser) commit(opts map[string]string, parents ...*Commit) (*Commit, error) {
+	c := &Commit{Branch: p.current, Message: opts["msg"], seq: len(p.g.Commits)}
+	for _, parent := range parents {
+		if parent != nil {
+			c.Parents = append(c.Parents, parent)
+		}
+	}
+	if tag, ok := opts["tag"]; ok && tag != "" {
+		c.Tags = []string{tag}
+	}
+	switch opts["type"] {
+	case "", "NORMAL":
+	case "REVERSE":
+		c.Type = CommitReverse
+	case "HIGHLIGHT":
+		c.Type = CommitHighlight
+	default:
+		return nil, fmt.Errorf("invalid commit type %q: want NORMAL, REVERSE or HIGHLIGHT", opts["type"])
+	}
+
+	c.ID = opts["id"]
+	if c.ID == "" {
+		for n := 0; c.ID == "" || p.commits[c.ID] != nil; n++ {
+			sum := sha1.Sum([]byte(fmt.Sprintf("%d %s %d", c.seq, p.current.Name, n)))
+			c.ID = hex.EncodeToString(sum[:])[:7]
+		}
+	} else if _, ok := p.commits[c.ID]; ok {
+		return nil, fmt.Errorf("duplicate commit id %q", c.ID)
+	}
+
+	p.commits[c.ID] = c
+	p.g.Commits = append(p.g.Commits, c)
+	p.current.Head = c
+	return c, nil
+}
+
+// isAncestor reports whether a is c or one of its ancestors.
+func isAncestor(a, c *Commit) bool {
+	seen := map[*Commit]bool{}
+	for todo := []*Commit{c}; len(todo) > 0; {
+		next := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if next == nil || seen[next] {
+			continue
+		}
+		if next == a {
+			return true
+		}
+		seen[next] = true
+		todo = append(todo, next.Parents...)
+	}
+	return false
+}
+
+// parseName reads the branch name at the start of args and the options
+// after it, which must be among allowed.
+func parseName(args string, allowed ...string) (string, map[string]string, error) {
+	m := nameRegex.FindStringSubmatch(args)
+	if m == nil {
+		return "", nil, fmt.Errorf("missing branch name")
+	}
+	name := m[1] + m[2]
+	opts, err := parseOptions(m[3], allowed...)
+	return name, opts, err
+}
+
+// parseOptions reads `key: value` options, each of which must be among
+// allowed.
+func parseOptions(args string, allowed ...string) (map[string]string, error) {
+	opts := map[string]string{}
+	for rest := strings.TrimSpace(args); rest != ""; {
+		m := optionRegex.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("invalid option %q", rest)
+		}
+		ok := false
+		for _, a := range allowed {
+			ok = ok || a == m[1]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unexpected option %q", m[1])
+		}
+		opts[m[1]] = m[2] + m[3]
+		rest = rest[len(m[0]):]
+	}
+	return opts, nil
+}
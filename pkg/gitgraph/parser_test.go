@@ -0,0 +1,132 @@
+package gitgraph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsGitGraph(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"gitGraph\n commit", true},
+		{"gitGraph:\n commit", true},
+		{"gitGraph TB:\n commit", true},
+		{"%% leading comment\ngitGraph", true},
+		{"gitGraphs\n commit", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsGitGraph(c.in); got != c.want {
+			t.Errorf("IsGitGraph(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	for in, want := range map[string]string{
+		"gitGraph":     "LR",
+		"gitGraph LR:": "LR",
+		"gitGraph TB:": "TB",
+		"gitGraph BT":  "BT",
+	} {
+		g, err := Parse(in)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", in, err)
+		}
+		if g.Direction != want {
+			t.Errorf("Parse(%q).Direction = %q, want %q", in, g.Direction, want)
+		}
+	}
+}
+
+func TestParseCommitsAndBranches(t *testing.T) {
+	g, err := Parse(`gitGraph
+    commit id: "a" tag: "v1" msg: "first"
+    branch "develop" order: 2
+    commit id: "b" type: REVERSE
+    branch feature order: 1
+    commit id: "c" type: HIGHLIGHT
+    switch main
+    merge feature id: "m"
+    checkout develop
+    cherry-pick id: "m" parent: "c"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]*Commit{}
+	for _, c := range g.Commits {
+		byID[c.ID] = c
+	}
+	a, b, c, m := byID["a"], byID["b"], byID["c"], byID["m"]
+	if a.Message != "first" || len(a.Tags) != 1 || a.Tags[0] != "v1" || len(a.Parents) != 0 {
+		t.Errorf("a = %+v, want a root commit tagged v1", a)
+	}
+	if b.Type != CommitReverse || b.Branch.Name != "develop" || b.Parents[0] != a {
+		t.Errorf("b = %+v, want a reverse commit on develop after a", b)
+	}
+	if c.Type != CommitHighlight || c.Branch.Name != "feature" || c.Parents[0] != b {
+		t.Errorf("c = %+v, want a highlight commit on feature after b", c)
+	}
+	if !m.IsMerge() || m.Branch.Name != "main" || m.Parents[0] != a || m.Parents[1] != c {
+		t.Errorf("m = %+v, want a merge of c into main after a", m)
+	}
+	pick := g.Commits[len(g.Commits)-1]
+	if pick.CherryPicked != m || pick.Branch.Name != "develop" || pick.Parents[0] != b ||
+		len(pick.Tags) != 1 || pick.Tags[0] != "cherry-pick:m|parent:c" {
+		t.Errorf("cherry-pick = %+v, want m picked onto develop after b", pick)
+	}
+
+	var lanes []string
+	for _, br := range g.Branches {
+		lanes = append(lanes, br.Name)
+	}
+	if got := strings.Join(lanes, " "); got != "main feature develop" {
+		t.Errorf("lanes = %s, want main feature develop", got)
+	}
+}
+
+func TestParseGeneratesStableIDs(t *testing.T) {
+	input := "gitGraph\n commit\n commit\n branch b\n commit"
+	first, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := Parse(input)
+	seen := map[string]bool{}
+	for i, c := range first.Commits {
+		if len(c.ID) != 7 || seen[c.ID] {
+			t.Errorf("commit %d has id %q, want 7 hex digits of its own", i, c.ID)
+		}
+		seen[c.ID] = true
+		if second.Commits[i].ID != c.ID {
+			t.Errorf("commit %d is %q once and %q the next time", i, c.ID, second.Commits[i].ID)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"gitGraph\n commit\n branch main", `branch "main" already exists`},
+		{"gitGraph\n checkout nowhere", `unknown branch "nowhere"`},
+		{"gitGraph\n commit\n merge main", "into itself"},
+		{"gitGraph\n commit\n branch b\n checkout main\n merge b", "nothing to merge"},
+		{"gitGraph\n branch b\n commit\n checkout main\n merge b", "has no commits"},
+		{"gitGraph\n commit id: \"a\"\n commit id: \"a\"", `duplicate commit id "a"`},
+		{"gitGraph\n commit type: BOLD", "invalid commit type"},
+		{"gitGraph\n commit colour: red", "invalid option"},
+		{"gitGraph\n commit order: 1", `unexpected option "order"`},
+		{"gitGraph\n commit id: \"a\"\n cherry-pick id: \"a\"", "onto its own branch"},
+		{"gitGraph\n commit\n cherry-pick id: \"zz\"", `unknown commit "zz"`},
+		{"gitGraph\n commit id: \"a\"\n branch b\n commit\n checkout main\n merge b id: \"m\"\n checkout b\n cherry-pick id: \"m\"", "needs a parent"},
+		{"gitGraph\n push", "invalid syntax"},
+	} {
+		_, err := Parse(c.in)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", c.in, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package gitgraph
+
+import (
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a graph is drawn with (Unicode by default,
+// ASCII when useAscii).
+type glyphs struct {
+	// lines maps the arms of a cell, up, right, down and left, to the glyph
+	// drawing them.
+	lines map[arms]string
+	// Commit marks: a normal commit, a merge, a reverse, a highlight and a
+	// cherry-pick.
+	commit, merge, reverse, highlight, cherryPick string
+}
+
+// arms describes a line glyph by whether a line leaves its centre up, right,
+// down and left.
+type arms [4]bool
+
+// unicodeGlyphs joins lines with the same light box-drawing glyphs
+// flowcharts use for their junctions.
+var unicodeGlyphs = glyphs{
+	lines: map[arms]string{
+		{false, true, false, true}: "─", {true, false, true, false}: "│",
+		{false, true, true, false}: "┌", {false, false, true, true}: "┐",
+		{true, true, false, false}: "└", {true, false, false, true}: "┘",
+		{true, true, true, false}: "├", {true, false, true, true}: "┤",
+		{false, true, true, true}: "┬", {true, true, false, true}: "┴",
+		{true, true, true, true}:    "┼",
+		{true, false, false, false}: "╵", {false, true, false, false}: "╶",
+		{false, false, true, false}: "╷", {false, false, false, true}: "╴",
+	},
+	commit: "●", merge: "◉", reverse: "✕", highlight: "■", cherryPick: "◈",
+}
+
+var asciiGlyphs = glyphs{
+	lines: map[arms]string{
+		{false, true, false, true}: "-", {true, false, true, false}: "|",
+		{true, false, false, false}: "|", {false, false, true, false}: "|",
+		{false, true, false, false}: "-", {false, false, false, true}: "-",
+	},
+	commit: "*", merge: "M", reverse: "X", highlight: "#", cherryPick: "C",
+}
+
+// line returns the glyph drawing a, which ASCII draws as '+' wherever lines
+// meet.
+func (g glyphs) line(a arms) string {
+	if s, ok := g.lines[a]; ok {
+		return s
+	}
+	return "+"
+}
+
+// mark returns the glyph c is marked with.
+func (g glyphs) mark(c *Commit) string {
+	switch {
+	case c.Type == CommitReverse:
+		return g.reverse
+	case c.Type == CommitHighlight:
+		return g.highlight
+	case c.CherryPicked != nil:
+		return g.cherryPick
+	case c.IsMerge():
+		return g.merge
+	}
+	return g.commit
+}
+
+type coord struct{ x, y int }
+
+// canvas collects the lines and text of a drawing. Lines drawn through the
+// same cell join into the glyph that has all their arms; text covers them.
+type canvas struct {
+	arms map[coord]arms
+	text map[coord]string
+}
+
+func newCanvas() *canvas {
+	return &canvas{arms: map[coord]arms{}, text: map[coord]string{}}
+}
+
+// line draws a straight line from a to b, which share a row or a column.
+func (c *canvas) line(a, b coord) {
+	if a == b {
+		return
+	}
+	dx, dy := sign(b.x-a.x), sign(b.y-a.y)
+	for p := a; ; p = (coord{p.x + dx, p.y + dy}) {
+		cell := c.arms[p]
+		if p != b {
+			cell[armTowards(dx, dy)] = true
+		}
+		if p != a {
+			cell[armTowards(-dx, -dy)] = true
+		}
+		c.arms[p] = cell
+		if p == b {
+			return
+		}
+	}
+}
+
+// armTowards is the index in arms of the arm pointing in the direction dx,
+// dy.
+func armTowards(dx, dy int) int {
+	switch {
+	case dy < 0:
+		return 0
+	case dx > 0:
+		return 1
+	case dy > 0:
+		return 2
+	}
+	return 3
+}
+
+// write writes s from at onwards, a cell per column it takes up.
+func (c *canvas) write(at coord, s string) {
+	x := at.x
+	for _, r := range s {
+		c.text[coord{x, at.y}] = string(r)
+		w := max(runewidth.RuneWidth(r), 1)
+		for i := 1; i < w; i++ {
+			c.text[coord{x + i, at.y}] = ""
+		}
+		x += w
+	}
+}
+
+// String draws the canvas with g, leaving out blank lines at the top.
+func (c *canvas) String(g glyphs) string {
+	width, height := 0, 0
+	for p := range c.arms {
+		width, height = max(width, p.x+1), max(height, p.y+1)
+	}
+	for p := range c.text {
+		width, height = max(width, p.x+1), max(height, p.y+1)
+	}
+	lines := make([]string, height)
+	for y := range lines {
+		var b strings.Builder
+		for x := 0; x < width; x++ {
+			p := coord{x, y}
+			if s, ok := c.text[p]; ok {
+				b.WriteString(s)
+			} else if a, ok := c.arms[p]; ok {
+				b.WriteString(g.line(a))
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// label is the text written beside a commit: its id and tags.
+func label(c *Commit) string {
+	s := c.ID
+	for _, t := range c.Tags {
+		s += " [" + t + "]"
+	}
+	return s
+}
+
+// layout places the commits and lanes of a graph on a drawing: at gives the
+// spot of the commit made seq-th on lane.
+type layout struct {
+	g     *GitGraph
+	lanes map[*Branch]int
+	at    func(seq, lane int) coord
+}
+
+// Render draws the graph with a lane per branch: left to right with the
+// branch names before the lanes for LR, top to bottom (or bottom to top for
+// BT) with the names above (or below) them for TB. Each commit is joined to
+// its parents: a branch's first commit forks off where its parent is and
+// runs along its own lane, a merged branch runs along its lane up to the
+// merge. In LR graphs commit labels are written beside each commit, clear
+// of the lines leaving it; in TB and BT graphs they're written to the right
+// of the lanes, as in `git log --graph`.
+func Render(g *GitGraph, config *diagram.Config) (string, error) {
+	gl := unicodeGlyphs
+	if config.UseAscii {
+		gl = asciiGlyphs
+	}
+	l := &layout{g: g, lanes: map[*Branch]int{}}
+	for i, b := range g.Branches {
+		l.lanes[b] = i
+	}
+	c := newCanvas()
+	if g.Direction == "LR" {
+		l.renderLR(c)
+	} else {
+		l.renderTB(c)
+	}
+	for _, commit := range g.Commits {
+		for i, parent := range commit.Parents {
+			l.join(c, parent, commit, i == 0)
+		}
+	}
+	for _, commit := range g.Commits {
+		c.write(l.at(commit.seq, l.lanes[commit.Branch]), gl.mark(commit))
+	}
+	return c.String(gl), nil
+}
+
+// join draws the line from parent to child. A child on another lane than
+// its first parent forks off it: the line crosses over to the child's lane
+// at the parent, then runs along it. A merged-in parent's line runs along
+// its own lane and crosses over at the child.
+func (l *layout) join(c *canvas, parent, child *Commit, first bool) {
+	from, to := l.lanes[parent.Branch], l.lanes[child.Branch]
+	corner := l.at(parent.seq, to)
+	if !first {
+		corner = l.at(child.seq, from)
+	}
+	c.line(l.at(parent.seq, from), corner)
+	c.line(corner, l.at(child.seq, to))
+}
+
+// renderLR lays the graph out left to right and writes its branch names
+// and commit labels. Neighbouring commits are far enough apart for their
+// labels, each of which goes below its commit unless a line leaves the
+// commit downwards.
+func (l *layout) renderLR(c *canvas) {
+	nameWidth := 0
+	for _, b := range l.g.Branches {
+		nameWidth = max(nameWidth, runewidth.StringWidth(b.Name))
+	}
+	xs := make([]int, len(l.g.Commits))
+	for i, commit := range l.g.Commits {
+		half := (runewidth.StringWidth(label(commit)) + 1) / 2
+		if i == 0 {
+			xs[i] = nameWidth + 2 + half
+			continue
+		}
+		before := (runewidth.StringWidth(label(l.g.Commits[i-1])) + 1) / 2
+		xs[i] = xs[i-1] + max(4, before+half+2)
+	}
+	l.at = func(seq, lane int) coord {
+		x := nameWidth + 2
+		if seq < len(xs) {
+			x = xs[seq]
+		}
+		return coord{x, 1 + 3*lane}
+	}
+	for _, b := range l.g.Branches {
+		c.write(coord{0, l.at(0, l.lanes[b]).y}, b.Name)
+	}
+
+	// up and down note the commits that lines leave upwards and downwards.
+	up, down := map[*Commit]bool{}, map[*Commit]bool{}
+	for _, commit := range l.g.Commits {
+		for i, parent := range commit.Parents {
+			from, to := l.lanes[parent.Branch], l.lanes[commit.Branch]
+			switch {
+			case i == 0 && to > from:
+				down[parent] = true
+			case i == 0 && to < from:
+				up[parent] = true
+			case i > 0 && from > to:
+				down[commit] = true
+			case i > 0 && from < to:
+				up[commit] = true
+			}
+		}
+	}
+	for _, commit := range l.g.Commits {
+		at := l.at(commit.seq, l.lanes[commit.Branch])
+		text := label(commit)
+		y := at.y + 1
+		if down[commit] && !up[commit] {
+			y = at.y - 1
+		}
+		c.write(coord{at.x - runewidth.StringWidth(text)/2, y}, text)
+	}
+}
+
+// renderTB lays the graph out top to bottom, or bottom to top for BT, and
+// writes its branch names and commit labels. Lanes are as far apart as the
+// longest branch name needs.
+func (l *layout) renderTB(c *canvas) {
+	nameWidth := 0
+	for _, b := range l.g.Branches {
+		nameWidth = max(nameWidth, runewidth.StringWidth(b.Name))
+	}
+	spacing := nameWidth + 2
+	rows := 2 * len(l.g.Commits)
+	namesAt := 0
+	l.at = func(seq, lane int) coord {
+		return coord{nameWidth/2 + lane*spacing, 2 + 2*seq}
+	}
+	if l.g.Direction == "BT" {
+		namesAt = rows
+		l.at = func(seq, lane int) coord {
+			return coord{nameWidth/2 + lane*spacing, rows - 2 - 2*seq}
+		}
+	}
+	for _, b := range l.g.Branches {
+		x := l.at(0, l.lanes[b]).x
+		c.write(coord{x - runewidth.StringWidth(b.Name)/2, namesAt}, b.Name)
+	}
+	labelsAt := l.at(0, len(l.g.Branches)-1).x + 3
+	for _, commit := range l.g.Commits {
+		c.write(coord{labelsAt, l.at(commit.seq, 0).y}, label(commit))
+	}
+}
@@ -1,4 +1,4 @@
-package cmd
+package graph
 
 import (
 	"container/heap"
@@ -56,7 +56,10 @@ func heuristic(a, b gridCoord) int {
 	}
 }
 
-func (g *graph) getPath(from gridCoord, to gridCoord) ([]gridCoord, error) {
+// getPath finds the cheapest path from one cell to another around the nodes
+// and subgraph labels. It may only run over the labels of frames, the
+// subgraphs the path starts or ends at or runs inside of.
+func (g *graph) getPath(from gridCoord, to gridCoord, frames ...*subgraph) ([]gridCoord, error) {
 	pq := &priorityQueue{}
 	heap.Init(pq)
 	heap.Push(pq, &priorityQueueItem{coord: from, priority: 0})
@@ -65,6 +68,11 @@ func (g *graph) getPath(from gridCoord, to gridCoord) ([]gridCoord, error) {
 	cameFrom := map[gridCoord]*gridCoord{from: nil}
 
 	directions := []gridCoord{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	// The grid has no far end, so give up on paths that stray well past the
+	// nodes rather than search on forever for a cell that can't be reached.
+	limit := g.gridExtent(from, to)
+	limit.x += pathMargin
+	limit.y += pathMargin
 
 	for pq.Len() > 0 {
 		current := heap.Pop(pq).(*priorityQueueItem).coord
@@ -80,11 +88,14 @@ func (g *graph) getPath(from gridCoord, to gridCoord) ([]gridCoord, error) {
 
 		for _, dir := range directions {
 			next := gridCoord{x: current.x + dir.x, y: current.y + dir.y}
-			if !g.isFreeInGrid(next) && !next.Equals(to) {
+			if next.x > limit.x || next.y > limit.y {
+				continue
+			}
+			if !next.Equals(to) && (!g.isFreeInGrid(next) || g.isOtherLabel(next, frames)) {
 				continue
 			}
 
-			newCost := costSoFar[current] + 1
+			newCost := costSoFar[current] + 1 + g.stepPenalty(current, next, from, to)
 			if cost, ok := costSoFar[next]; !ok || newCost < cost {
 				costSoFar[next] = newCost
 				priority := newCost + heuristic(next, to)
@@ -97,6 +108,30 @@ func (g *graph) getPath(from gridCoord, to gridCoord) ([]gridCoord, error) {
 	return nil, fmt.Errorf("no path found")
 }
 
+// pathMargin is how far past the nodes and its ends a path may run.
+const pathMargin = 4
+
+// gridExtent is the bottom right-most cell taken up by a node or one of cs.
+func (g *graph) gridExtent(cs ...gridCoord) gridCoord {
+	extent := gridCoord{}
+	for _, c := range cs {
+		extent.x = Max(extent.x, c.x)
+		extent.y = Max(extent.y, c.y)
+	}
+	for c := range g.grid {
+		extent.x = Max(extent.x, c.x)
+		extent.y = Max(extent.y, c.y)
+	}
+	return extent
+}
+
+// isOtherLabel reports whether the label of a subgraph other than frames is
+// drawn in c.
+func (g *graph) isOtherLabel(c gridCoord, frames []*subgraph) bool {
+	sg := g.subgraphLabelCells[c]
+	return sg != nil && !slices.Contains(frames, sg)
+}
+
 func (g *graph) isFreeInGrid(c gridCoord) bool {
 	// We'll fix it later if we overshoot the grid size
 	if c.x < 0 || c.y < 0 {
@@ -105,13 +140,28 @@ func (g *graph) isFreeInGrid(c gridCoord) bool {
 	return g.grid[c] == nil
 }
 
-func (g *graph) drawArrow(from gridCoord, to gridCoord, e *edge) (*drawing, *drawing, *drawing, *drawing, *drawing) {
+func (g *graph) drawArrow(from gridCoord, to gridCoord, e *edge) (*drawing, *drawing, *drawing, *drawing) {
 	if len(e.path) == 0 {
-		return nil, nil, nil, nil, nil
+		return nil, nil, nil, nil
 	}
 	log.Debugf("Drawing arrow from %v to %v with path %v", from, to, e.path)
-	dLabel := g.drawArrowLabel(e)
-	dPath, linesDrawn, lineDirs := g.drawPath(e.path, e.stroke)
+	dPath, linesDrawn, lineDirs := g.drawPath(e)
+	// A shape with slanted or inset sides leaves a gap between the node's
+	// bounding box, where the path stops, and its border; carry both ends of
+	// the line on up to the border.
+	if len(linesDrawn) > 0 {
+		last := len(linesDrawn) - 1
+		if e.fromSubgraph != nil {
+			linesDrawn[0] = g.fitToFrame(dPath, e.fromSubgraph, linesDrawn[0], lineDirs[0].getOpposite(), e.stroke, true)
+		} else {
+			linesDrawn[0] = g.extendToBorder(dPath, e.from, linesDrawn[0], lineDirs[0].getOpposite(), e.stroke, true)
+		}
+		if e.toSubgraph != nil {
+			linesDrawn[last] = g.fitToFrame(dPath, e.toSubgraph, linesDrawn[last], lineDirs[last], e.stroke, false)
+		} else {
+			linesDrawn[last] = g.extendToBorder(dPath, e.to, linesDrawn[last], lineDirs[last], e.stroke, false)
+		}
+	}
 	dBoxStart := g.drawBoxStart(e.path, linesDrawn[0], e.stroke)
 	skipHead := e.head == headNone
 	if skipHead && len(linesDrawn) > 0 {
@@ -132,8 +182,8 @@ func (g *graph) drawArrow(from gridCoord, to gridCoord, e *edge) (*drawing, *dra
 		dStartArrowHead := g.drawArrowHead(reverseDrawingLine(linesDrawn[0]), lineDirs[0].getOpposite(), e.head)
 		dArrowHead = g.mergeDrawings(dArrowHead, drawingCoord{0, 0}, dStartArrowHead)
 	}
-	dCorners := g.drawCorners(e.path, e.stroke)
-	return dPath, dBoxStart, dArrowHead, dCorners, dLabel
+	dCorners := g.drawCorners(e)
+	return dPath, dBoxStart, dArrowHead, dCorners
 }
 
 func reverseDrawingLine(line []drawingCoord) []drawingCoord {
@@ -174,20 +224,22 @@ func mergePath(path []gridCoord) []gridCoord {
 	return newPath
 }
 
-func (g *graph) drawPath(path []gridCoord, stroke edgeStroke) (*drawing, [][]drawingCoord, []direction) {
+func (g *graph) drawPath(e *edge) (*drawing, [][]drawingCoord, []direction) {
 	d := copyCanvas(g.drawing)
+	path, stroke := e.path, e.stroke
+	drawingPath := g.pathToDrawing(e)
 	previousCoord := path[0]
 	linesDrawn := make([][]drawingCoord, 0)
 	lineDirs := make([]direction, 0)
 	var previousDrawingCoord drawingCoord
-	for _, nextCoord := range path[1:] {
-		previousDrawingCoord = g.gridToDrawingCoord(previousCoord, nil)
-		nextDrawingCoord := g.gridToDrawingCoord(nextCoord, nil)
+	for i, nextCoord := range path[1:] {
+		previousDrawingCoord = drawingPath[i]
+		nextDrawingCoord := drawingPath[i+1]
 		if previousDrawingCoord.Equals(nextDrawingCoord) {
 			log.Debugf("Skipping drawing identical line on %v", nextCoord)
 			continue
 		}
-		dir := determineDirection(genericCoord(previousCoord), genericCoord(nextCoord))
+		dir := g.onDrawing(determineDirection(genericCoord(previousCoord), genericCoord(nextCoord)))
 		s := g.drawLine(d, previousDrawingCoord, nextDrawingCoord, 1, -1, stroke)
 		if len(s) == 0 {
 			// drawLine may return no coords if offsets collapse the line. Use at least one point so arrow and junction logic
@@ -201,36 +253,147 @@ func (g *graph) drawPath(path []gridCoord, stroke edgeStroke) (*drawing, [][]dra
 	return d, linesDrawn, lineDirs
 }
 
+// extendToBorder lengthens line, which ends just outside n's bounding box,
+// through the blank cells between the box and the node's actual border,
+// travelling in dir. atStart says whether the line leaves n (and is extended
+// at its start) or enters it. Lines meeting a border on the bounding box are
+// returned unchanged.
+func (g *graph) extendToBorder(d *drawing, n *node, line []drawingCoord, dir direction, stroke edgeStroke, atStart bool) []drawingCoord {
+	if len(line) == 0 || n.drawingCoord == nil || n.drawing == nil {
+		return line
+	}
+	step := drawingCoord{x: dir.x - 1, y: dir.y - 1}
+	end := line[len(line)-1]
+	if atStart {
+		end = line[0]
+	}
+	minX, minY := n.drawingCoord.x, n.drawingCoord.y
+	maxX, maxY := minX+len(*n.drawing)-1, minY+len((*n.drawing)[0])-1
+	inside := func(c drawingCoord) bool {
+		return c.x >= minX && c.x <= maxX && c.y >= minY && c.y <= maxY
+	}
+
+	extension := []drawingCoord{}
+	c := drawingCoord{x: end.x + step.x, y: end.y + step.y}
+	for inside(c) && (*g.drawing)[c.x][c.y] == " " {
+		extension = append(extension, c)
+		c = drawingCoord{x: c.x + step.x, y: c.y + step.y}
+	}
+	if len(extension) == 0 || !inside(c) {
+		// Either the border is on the bounding box, or there is no border
+		// on this side to reach.
+		return line
+	}
+
+	tip := extension[len(extension)-1]
+	if atStart {
+		g.drawLine(d, tip, end, 0, 0, stroke)
+		slices.Reverse(extension)
+		return append(extension, line...)
+	}
+	g.drawLine(d, end, tip, 0, 0, stroke)
+	return append(line, extension...)
+}
+
+// frameStop is the cell just outside sg's frame that a line travelling in
+// dir stops at, on the frame's near side.
+func frameStop(sg *subgraph, c drawingCoord, dir direction) drawingCoord {
+	switch dir {
+	case Up:
+		c.y = sg.maxY + 1
+	case Down:
+		c.y = sg.minY - 1
+	case Left:
+		c.x = sg.maxX + 1
+	case Right:
+		c.x = sg.minX - 1
+	}
+	return c
+}
+
+// fitToFrame is extendToBorder for a line that ends at a subgraph: the path
+// stops at the edge of the subgraph's nodes, so line is cut back or carried on
+// to end right next to sg's frame, travelling in dir.
+func (g *graph) fitToFrame(d *drawing, sg *subgraph, line []drawingCoord, dir direction, stroke edgeStroke, atStart bool) []drawingCoord {
+	if len(line) == 0 {
+		return line
+	}
+	// Work on the line as if it ended at sg, whichever way it runs.
+	line = slices.Clone(line)
+	if atStart {
+		slices.Reverse(line)
+	}
+	end := line[len(line)-1]
+	stop := frameStop(sg, end, dir)
+	step := drawingCoord{x: dir.x - 1, y: dir.y - 1}
+	// How far the line has to go on to reach stop; negative when it already
+	// runs into the frame.
+	togo := (stop.x-end.x)*step.x + (stop.y-end.y)*step.y
+
+	for ; togo < 0 && len(line) > 1; togo++ {
+		c := line[len(line)-1]
+		(*d)[c.x][c.y] = " "
+		line = line[:len(line)-1]
+	}
+	if togo > 0 {
+		g.drawLine(d, end, stop, 0, 0, stroke)
+		for c := end; !c.Equals(stop); {
+			c = drawingCoord{x: c.x + step.x, y: c.y + step.y}
+			line = append(line, c)
+		}
+	}
+	if atStart {
+		slices.Reverse(line)
+	}
+	return line
+}
+
 func (g *graph) drawBoxStart(path []gridCoord, firstLine []drawingCoord, stroke edgeStroke) *drawing {
 	d := *(copyCanvas(g.drawing))
 	from := firstLine[0]
-	dir := determineDirection(genericCoord(path[0]), genericCoord(path[1]))
+	dir := g.onDrawing(determineDirection(genericCoord(path[0]), genericCoord(path[1])))
 	log.Debugf("Drawing box start at %v with direction %v for line %v", from, dir, path)
 
 	if g.useAscii {
 		return &d
 	}
 
-	// A heavy line meeting a light box border looks mismatched through a
-	// light T-junction glyph (e.g. "├"); leave the box's own light border
-	// character in place instead.
-	if stroke == strokeThick {
-		return &d
-	}
-
 	switch dir {
 	case Up:
-		d[from.x][from.y+1] = "┴"
+		g.setBorderTee(d, from.x, from.y+1, "─", edgeStub(Up, stroke))
 	case Down:
-		d[from.x][from.y-1] = "┬"
+		g.setBorderTee(d, from.x, from.y-1, "─", edgeStub(Down, stroke))
 	case Left:
-		d[from.x+1][from.y] = "┤"
+		g.setBorderTee(d, from.x+1, from.y, "│", edgeStub(Left, stroke))
 	case Right:
-		d[from.x-1][from.y] = "├"
+		g.setBorderTee(d, from.x-1, from.y, "│", edgeStub(Right, stroke))
 	}
 	return &d
 }
 
+// edgeStub is the half line pointing in dir that an edge with stroke adds to
+// the border it leaves from: heavy for thick edges, so a thick edge joins a
+// light border through a mixed tee like "┝".
+func edgeStub(dir direction, stroke edgeStroke) string {
+	light := map[direction]string{Up: "╵", Down: "╷", Left: "╴", Right: "╶"}
+	heavy := map[direction]string{Up: "╹", Down: "╻", Left: "╸", Right: "╺"}
+	if stroke == strokeThick {
+		return heavy[dir]
+	}
+	return light[dir]
+}
+
+// setBorderTee turns the straight node border at (x, y) into the tee an edge
+// joins it with, by merging in the edge's stub. Borders that aren't a
+// straight line there (a rounded corner, a slanted or curved side) are left
+// as they are.
+func (g *graph) setBorderTee(d drawing, x, y int, border, stub string) {
+	if x < 0 || y < 0 || x >= len(*g.drawing) || y >= len((*g.drawing)[x]) || (*g.drawing)[x][y] != border {
+		return
+	}
+	d[x][y] = mergeJunctions(border, stub)
+}
+
 // drawBoxEnd is drawBoxStart's mirror for the destination end of a path.
 func (g *graph) drawBoxEnd(lastLine []drawingCoord, dir direction, stroke edgeStroke) *drawing {
 	d := *(copyCanvas(g.drawing))
@@ -239,19 +402,19 @@ func (g *graph) drawBoxEnd(lastLine []drawingCoord, dir direction, stroke edgeSt
 	}
 	to := lastLine[len(lastLine)-1]
 
-	if g.useAscii || stroke == strokeThick {
+	if g.useAscii {
 		return &d
 	}
 
 	switch dir {
 	case Up:
-		d[to.x][to.y-1] = "┬"
+		g.setBorderTee(d, to.x, to.y-1, "─", edgeStub(Down, stroke))
 	case Down:
-		d[to.x][to.y+1] = "┴"
+		g.setBorderTee(d, to.x, to.y+1, "─", edgeStub(Up, stroke))
 	case Left:
-		d[to.x-1][to.y] = "├"
+		g.setBorderTee(d, to.x-1, to.y, "│", edgeStub(Right, stroke))
 	case Right:
-		d[to.x+1][to.y] = "┤"
+		g.setBorderTee(d, to.x+1, to.y, "│", edgeStub(Left, stroke))
 	}
 	return &d
 }
@@ -275,7 +438,7 @@ func (g *graph) drawArrowHead(line []drawingCoord, fallback direction, head edge
 			char = "x"
 		}
 		if !g.useAscii {
-			char = "●"
+			char = "○"
 			if head == headCross {
 				char = "✕"
 			}
@@ -355,8 +518,10 @@ func (g *graph) drawArrowHead(line []drawingCoord, fallback direction, head edge
 	return &d
 }
 
-func (g *graph) drawCorners(path []gridCoord, stroke edgeStroke) *drawing {
+func (g *graph) drawCorners(e *edge) *drawing {
 	d := copyCanvas(g.drawing)
+	path, stroke := e.path, e.stroke
+	drawingPath := g.pathToDrawing(e)
 	// Thick edges get the heavy box-drawing corner glyphs to match their
 	// heavy line/arrowhead; ASCII has no distinct "thick corner" (still
 	// "+"), and dotted corners are left as plain light corners.
@@ -366,10 +531,10 @@ func (g *graph) drawCorners(path []gridCoord, stroke edgeStroke) *drawing {
 		if idx == 0 || idx == len(path)-1 {
 			continue
 		}
-		drawingCoord := g.gridToDrawingCoord(coord, nil)
+		drawingCoord := drawingPath[idx]
 
-		prevDir := determineDirection(genericCoord(path[idx-1]), genericCoord(coord))
-		nextDir := determineDirection(genericCoord(coord), genericCoord(path[idx+1]))
+		prevDir := g.onDrawing(determineDirection(genericCoord(path[idx-1]), genericCoord(coord)))
+		nextDir := g.onDrawing(determineDirection(genericCoord(coord), genericCoord(path[idx+1])))
 
 		var corner string
 		if !g.useAscii {
@@ -406,24 +571,6 @@ func (g *graph) drawCorners(path []gridCoord, stroke edgeStroke) *drawing {
 	return d
 }
 
-func (g *graph) drawArrowLabel(e *edge) *drawing {
-	d := copyCanvas(g.drawing)
-	lenLabel := len(e.text)
-	if lenLabel == 0 {
-		return d
-	}
-
-	log.Debugf("Drawing text '%s' on gridline %v", e.text, e.labelLine)
-	line := g.lineToDrawing(e.labelLine)
-	if e.isBidirectional {
-		line = insetLine(line, 2, 2) // reserve for label placement calc: <- (2 char) + {label} + -> (2 char)
-	} else {
-		line = insetLine(line, 1, 2) // reserve for label placement calc: - (1 char) + {label} + -> (2 char)
-	}
-	d.drawTextOnLine(line, e.text)
-	return d
-}
-
 // insetLine returns a sub-segment with each endpoint moved inward along the line.
 func insetLine(line []drawingCoord, insetStart, insetEnd int) []drawingCoord {
 	if len(line) < 2 || (insetStart == 0 && insetEnd == 0) {
@@ -454,29 +601,10 @@ func insetLine(line []drawingCoord, insetStart, insetEnd int) []drawingCoord {
 	return []drawingCoord{a, b}
 }
 
-func (d *drawing) drawTextOnLine(line []drawingCoord, label string) {
-	// Write text in middle of the line
-	//  123456789
-	// |---------|
-	//     123
-	log.Debugf("Drawing text '%s' on drawingline %v", label, line)
-	var minX, maxX, minY, maxY int
-	if line[0].x > line[1].x {
-		minX = line[1].x
-		maxX = line[0].x
-	} else {
-		minX = line[0].x
-		maxX = line[1].x
-	}
-	if line[0].y > line[1].y {
-		minY = line[1].y
-		maxY = line[0].y
-	} else {
-		minY = line[0].y
-		maxY = line[1].y
+// lineMiddle is the middle of a straight line.
+func lineMiddle(line []drawingCoord) drawingCoord {
+	return drawingCoord{
+		x: Min(line[0].x, line[1].x) + Abs(line[0].x-line[1].x)/2,
+		y: Min(line[0].y, line[1].y) + Abs(line[0].y-line[1].y)/2,
 	}
-	middleX := minX + (maxX-minX)/2
-	middleY := minY + (maxY-minY)/2
-	startLabelCoord := drawingCoord{x: middleX - len(label)/2, y: middleY}
-	d.drawText(startLabelCoord, label)
 }
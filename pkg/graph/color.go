@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// cellColor is the colour of one cell of a drawing. Colours are kept apart
+// from the glyphs until the drawing is done, so that styled lines still
+// merge into junctions with the lines they meet.
+type cellColor struct {
+	fg string
+	bg string
+}
+
+// cssColors holds the hex values of the CSS colour names styles use most.
+// Terminals need hex; HTML output passes the names on as they are.
+var cssColors = map[string]string{
+	"black": "#000000", "white": "#ffffff", "gray": "#808080", "grey": "#808080",
+	"silver": "#c0c0c0", "lightgray": "#d3d3d3", "lightgrey": "#d3d3d3", "darkgray": "#a9a9a9",
+	"darkgrey": "#a9a9a9", "red": "#ff0000", "darkred": "#8b0000", "maroon": "#800000",
+	"crimson": "#dc143c", "tomato": "#ff6347", "coral": "#ff7f50", "salmon": "#fa8072",
+	"orange": "#ffa500", "gold": "#ffd700", "yellow": "#ffff00", "khaki": "#f0e68c",
+	"beige": "#f5f5dc", "olive": "#808000", "lime": "#00ff00", "green": "#008000",
+	"darkgreen": "#006400", "lightgreen": "#90ee90", "teal": "#008080", "cyan": "#00ffff",
+	"aqua": "#00ffff", "blue": "#0000ff", "lightblue": "#add8e6", "darkblue": "#00008b",
+	"navy": "#000080", "purple": "#800080", "indigo": "#4b0082", "violet": "#ee82ee",
+	"magenta": "#ff00ff", "fuchsia": "#ff00ff", "pink": "#ffc0cb", "brown": "#a52a2a",
+}
+
+// cssColorHex returns c as a hex colour, or "" when it isn't a hex colour or
+// one of cssColors.
+func cssColorHex(c string) string {
+	if strings.HasPrefix(c, "#") {
+		return c
+	}
+	return cssColors[strings.ToLower(c)]
+}
+
+// setNodeColors colours n's cells in colors after its style: its border with
+// stroke, its label with color and everything within its outline with fill.
+func (g *graph) setNodeColors(n *node, colors map[drawingCoord]cellColor) {
+	styles := n.styleClass.styles
+	fill, stroke, text := styles["fill"], styles["stroke"], styles["color"]
+	if fill == "" && stroke == "" && text == "" {
+		return
+	}
+
+	label := map[drawingCoord]bool{}
+	for lineIdx, start := range n.labelLineStarts(*g) {
+		width := runewidth.StringWidth(n.label.lines[lineIdx])
+		for x := start.x; x < start.x+width; x++ {
+			label[drawingCoord{x, start.y}] = true
+		}
+	}
+
+	d := *n.drawing
+	for y := 0; y < len(d[0]); y++ {
+		// The outline is whatever is drawn furthest left and right on a row.
+		left, right := -1, -1
+		for x := range d {
+			if d[x][y] != " " && !label[drawingCoord{x, y}] {
+				if left == -1 {
+					left = x
+				}
+				right = x
+			}
+		}
+		for x := range d {
+			c := drawingCoord{n.drawingCoord.x + x, n.drawingCoord.y + y}
+			switch {
+			case label[drawingCoord{x, y}]:
+				colors[c] = cellColor{fg: text, bg: fill}
+			case d[x][y] != " ":
+				colors[c] = cellColor{fg: stroke}
+			case x > left && x < right:
+				colors[c] = cellColor{bg: fill}
+			}
+		}
+	}
+}
+
+// setEdgeColors colours the cells an edge was drawn on in colors after its
+// linkStyle: its line and heads with stroke and its label with color.
+func setEdgeColors(e *edge, colors map[drawingCoord]cellColor, label *drawing, lines ...*drawing) {
+	stroke, text := e.styles["stroke"], e.styles["color"]
+	paint := func(d *drawing, fg string) {
+		if d == nil || fg == "" {
+			return
+		}
+		for x := range *d {
+			for y, cell := range (*d)[x] {
+				if cell != " " {
+					colors[drawingCoord{x, y}] = cellColor{fg: fg}
+				}
+			}
+		}
+	}
+	for _, d := range lines {
+		paint(d, stroke)
+	}
+	paint(label, text)
+}
+
+// colorDrawing wraps every coloured cell of d in its colours.
+func (g *graph) colorDrawing(d *drawing, colors map[drawingCoord]cellColor) {
+	for c, cc := range colors {
+		if c.x < 0 || c.y < 0 || c.x >= len(*d) || c.y >= len((*d)[c.x]) || (*d)[c.x][c.y] == "" {
+			continue
+		}
+		(*d)[c.x][c.y] = wrapTextInColor((*d)[c.x][c.y], cc.fg, cc.bg, g.styleType)
+	}
+}
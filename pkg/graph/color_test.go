@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+func TestRenderHTMLAppliesStyleStatements(t *testing.T) {
+	input := "graph LR\nA --> B\nB --> C\nstyle A fill:#f9f,stroke:#333,color:#fff\nclassDef hot color:red\nclass B,C hot\nlinkStyle 1 stroke:blue,stroke-dasharray: 5 5"
+	gd, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	out, err := Render(gd, diagram.NewTestConfig(false, "html"))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"<span style='color: #333'>┌</span>",
+		"<span style='background-color: #f9f'> </span>",
+		"<span style='color: #fff; background-color: #f9f'>A</span>",
+		"<span style='color: red'>B</span>",
+		"<span style='color: red'>C</span>",
+		"<span style='color: blue'>┄</span>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s\n%s", want, out)
+		}
+	}
+	// Only the second link is styled.
+	if strings.Contains(out, "<span style='color: blue'>─</span>") {
+		t.Errorf("linkStyle 1 coloured the first link too\n%s", out)
+	}
+}
+
+func TestRenderWithoutStylesIsPlain(t *testing.T) {
+	gd, err := Parse("graph LR\nA --> B")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	out, err := Render(gd, diagram.NewTestConfig(false, "html"))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(out, "<span") {
+		t.Errorf("unstyled graph should have no colour markup:\n%s", out)
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+// The grid leaves more room than a drawing needs: nodes are placed four grid
+// lines apart, whether or not anything ends up between them, and every line
+// in between is as wide as the configured padding whatever runs through it.
+// compactGrid shrinks the lines no node sits on to what is drawn in them,
+// once the edges are routed and before labels and lanes make room for
+// themselves again.
+
+const (
+	// compactPadding is what a line that edges only cross shrinks to: room
+	// for a line and an arrowhead.
+	compactPadding = 2
+	// compactCorridor is what a line edges run along shrinks to, keeping
+	// them a cell clear of either side.
+	compactCorridor = 3
+)
+
+// compactGrid shrinks every grid column and row that no node sits on: lines
+// that edges run along or cross shrink to the minimum padding, empty ones
+// collapse. The lines subgraph frames are drawn in keep their size.
+func (g *graph) compactGrid() {
+	columnUse, rowUse := map[int]cellUse{}, map[int]cellUse{}
+	for c, routed := range g.routedCells {
+		for _, r := range routed {
+			columnUse[c.x] |= r.use
+			rowUse[c.y] |= r.use
+		}
+	}
+	frameColumns, frameRows := map[int]bool{}, map[int]bool{}
+	for _, sg := range g.subgraphs {
+		if len(sg.nodes) == 0 {
+			continue
+		}
+		box := edgeEndBox(sg.nodes[0], sg)
+		frameColumns[box.minX-1], frameColumns[box.maxX+1] = true, true
+		frameRows[box.minY-1], frameRows[box.maxY+1] = true, true
+	}
+	compactLines(g.columnWidth, columnUse, usedVertically, g.isNodeColumn, frameColumns)
+	compactLines(g.rowHeight, rowUse, usedHorizontally, g.isNodeRow, frameRows)
+}
+
+// compactLines shrinks the lines in sizes, given how edges use each of them
+// and which way along runs, skipping node lines and the ones in keep.
+func compactLines(sizes map[int]int, use map[int]cellUse, along cellUse, isNodeLine func(int) bool, keep map[int]bool) {
+	for i, size := range sizes {
+		if isNodeLine(i) || keep[i] {
+			continue
+		}
+		switch {
+		case use[i]&along != 0:
+			sizes[i] = Min(size, compactCorridor)
+		case use[i] != 0:
+			sizes[i] = Min(size, compactPadding)
+		case i > 0 && isNodeLine(i+1):
+			// Keep a node apart from whatever is before it.
+			sizes[i] = Min(size, 1)
+		default:
+			sizes[i] = 0
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package cmd
+package graph
 
 type direction genericCoord
 
@@ -45,8 +45,27 @@ func (c drawingCoord) Direction(dir direction) drawingCoord {
 	return drawingCoord{x: c.x + dir.x, y: c.y + dir.y}
 }
 
+// isHorizontal reports whether the graph's ranks run along the x axis (LR and
+// RL) rather than the y axis (TD and BT).
+func (g graph) isHorizontal() bool {
+	return g.graphDirection == "LR" || g.graphDirection == "RL"
+}
+
+// onDrawing maps a direction on the layout grid to the direction it points on
+// the drawing. RL and BT graphs are laid out as LR and TD and mirrored when the
+// grid is turned into drawing coordinates, so their directions mirror too.
+func (g graph) onDrawing(d direction) direction {
+	if g.graphDirection == "RL" {
+		d.x = 2 - d.x
+	}
+	if g.graphDirection == "BT" {
+		d.y = 2 - d.y
+	}
+	return d
+}
+
 func (g graph) selfReferenceDirection() (direction, direction, direction, direction) {
-	if g.graphDirection == "LR" {
+	if g.isHorizontal() {
 		return Right, Down, Down, Right
 	}
 	return Down, Right, Right, Down
@@ -61,7 +80,7 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 
 	// Check if this is a backwards flowing edge
 	isBackwards := false
-	if g.graphDirection == "LR" {
+	if g.isHorizontal() {
 		// In LR mode, backwards flow is when edge goes from right to left (Left direction)
 		isBackwards = (d == Left || d == UpperLeft || d == LowerLeft)
 	} else { // TD mode
@@ -75,7 +94,7 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 	// For backwards edges, use special start positions: Down in LR mode, Right in TD mode
 	switch d {
 	case LowerRight:
-		if g.graphDirection == "LR" {
+		if g.isHorizontal() {
 			preferredDir = Down
 			preferredOppositeDir = Left
 			alternativeDir = Right
@@ -87,7 +106,7 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 			alternativeOppositeDir = Left
 		}
 	case UpperRight:
-		if g.graphDirection == "LR" {
+		if g.isHorizontal() {
 			preferredDir = Up
 			preferredOppositeDir = Left
 			alternativeDir = Right
@@ -99,7 +118,7 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 			alternativeOppositeDir = Left
 		}
 	case LowerLeft:
-		if g.graphDirection == "LR" {
+		if g.isHorizontal() {
 			// Backwards flow in LR mode - start from Down, arrive at Down
 			preferredDir = Down
 			preferredOppositeDir = Down // Edge goes to bottom of destination
@@ -112,7 +131,7 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 			alternativeOppositeDir = Right
 		}
 	case UpperLeft:
-		if g.graphDirection == "LR" {
+		if g.isHorizontal() {
 			// Backwards flow in LR mode - start from Down, arrive at Down
 			preferredDir = Down
 			preferredOppositeDir = Down // Edge goes to bottom of destination
@@ -128,13 +147,13 @@ func (g graph) determineStartAndEndDir(e *edge) (direction, direction, direction
 	default:
 		// Handle direct backwards flow cases
 		if isBackwards {
-			if g.graphDirection == "LR" && d == Left {
+			if g.isHorizontal() && d == Left {
 				// Direct left flow in LR mode - start from Down, arrive at Down
 				preferredDir = Down
 				preferredOppositeDir = Down // Edge goes to bottom of destination
 				alternativeDir = Left
 				alternativeOppositeDir = Right
-			} else if g.graphDirection == "TD" && d == Up {
+			} else if !g.isHorizontal() && d == Up {
 				// Direct up flow in TD mode - start from Right, arrive at Right
 				preferredDir = Right
 				preferredOppositeDir = Right // Edge goes to right of destination
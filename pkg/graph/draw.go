@@ -1,4 +1,4 @@
-package cmd
+package graph
 
 import (
 	"fmt"
@@ -9,24 +9,48 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var junctionChars = []string{
-	"─", // Horizontal line
-	"│", // Vertical line
-	"┌", // Top-left corner
-	"┐", // Top-right corner
-	"└", // Bottom-left corner
-	"┘", // Bottom-right corner
-	"├", // T-junction pointing right
-	"┤", // T-junction pointing left
-	"┬", // T-junction pointing down
-	"┴", // T-junction pointing up
-	"┼", // Cross junction
-	"╴", // Left end of horizontal line
-	"╵", // Top end of vertical line
-	"╶", // Right end of horizontal line
-	"╷", // Bottom end of vertical line
+// boxArms describes a box-drawing glyph by the lines leaving its centre,
+// up, right, down and left: 0 for none, 1 for a light and 2 for a heavy line.
+type boxArms [4]int
+
+// junctionArms holds every light and heavy box-drawing glyph, so lines of
+// either weight can be merged into the glyph that has all their arms.
+var junctionArms = map[string]boxArms{
+	"─": {0, 1, 0, 1}, "━": {0, 2, 0, 2}, "│": {1, 0, 1, 0}, "┃": {2, 0, 2, 0},
+	"┌": {0, 1, 1, 0}, "┍": {0, 2, 1, 0}, "┎": {0, 1, 2, 0}, "┏": {0, 2, 2, 0},
+	"┐": {0, 0, 1, 1}, "┑": {0, 0, 1, 2}, "┒": {0, 0, 2, 1}, "┓": {0, 0, 2, 2},
+	"└": {1, 1, 0, 0}, "┕": {1, 2, 0, 0}, "┖": {2, 1, 0, 0}, "┗": {2, 2, 0, 0},
+	"┘": {1, 0, 0, 1}, "┙": {1, 0, 0, 2}, "┚": {2, 0, 0, 1}, "┛": {2, 0, 0, 2},
+	"├": {1, 1, 1, 0}, "┝": {1, 2, 1, 0}, "┞": {2, 1, 1, 0}, "┟": {1, 1, 2, 0},
+	"┠": {2, 1, 2, 0}, "┡": {2, 2, 1, 0}, "┢": {1, 2, 2, 0}, "┣": {2, 2, 2, 0},
+	"┤": {1, 0, 1, 1}, "┥": {1, 0, 1, 2}, "┦": {2, 0, 1, 1}, "┧": {1, 0, 2, 1},
+	"┨": {2, 0, 2, 1}, "┩": {2, 0, 1, 2}, "┪": {1, 0, 2, 2}, "┫": {2, 0, 2, 2},
+	"┬": {0, 1, 1, 1}, "┭": {0, 1, 1, 2}, "┮": {0, 2, 1, 1}, "┯": {0, 2, 1, 2},
+	"┰": {0, 1, 2, 1}, "┱": {0, 1, 2, 2}, "┲": {0, 2, 2, 1}, "┳": {0, 2, 2, 2},
+	"┴": {1, 1, 0, 1}, "┵": {1, 1, 0, 2}, "┶": {1, 2, 0, 1}, "┷": {1, 2, 0, 2},
+	"┸": {2, 1, 0, 1}, "┹": {2, 1, 0, 2}, "┺": {2, 2, 0, 1}, "┻": {2, 2, 0, 2},
+	"┼": {1, 1, 1, 1}, "┽": {1, 1, 1, 2}, "┾": {1, 2, 1, 1}, "┿": {1, 2, 1, 2},
+	"╀": {2, 1, 1, 1}, "╁": {1, 1, 2, 1}, "╂": {2, 1, 2, 1}, "╃": {2, 1, 1, 2},
+	"╄": {2, 2, 1, 1}, "╅": {1, 1, 2, 2}, "╆": {1, 2, 2, 1}, "╇": {2, 2, 1, 2},
+	"╈": {1, 2, 2, 2}, "╉": {2, 1, 2, 2}, "╊": {2, 2, 2, 1}, "╋": {2, 2, 2, 2},
+	"╴": {0, 0, 0, 1}, "╵": {1, 0, 0, 0}, "╶": {0, 1, 0, 0}, "╷": {0, 0, 1, 0},
+	"╸": {0, 0, 0, 2}, "╹": {2, 0, 0, 0}, "╺": {0, 2, 0, 0}, "╻": {0, 0, 2, 0},
+	"╼": {0, 2, 0, 1}, "╽": {1, 0, 2, 0}, "╾": {0, 1, 0, 2}, "╿": {2, 0, 1, 0},
 }
 
+// dashedArms lets dotted lines join others like light lines do. The glyph
+// where they meet is solid, as there are no dashed junctions.
+var dashedArms = map[string]boxArms{"┄": {0, 1, 0, 1}, "┆": {1, 0, 1, 0}}
+
+// junctionGlyphs maps arms back to the glyph drawing them.
+var junctionGlyphs = func() map[boxArms]string {
+	glyphs := make(map[boxArms]string, len(junctionArms))
+	for c, arms := range junctionArms {
+		glyphs[arms] = c
+	}
+	return glyphs
+}()
+
 type drawing [][]string
 
 type styleClass struct {
@@ -40,7 +64,7 @@ func (g *graph) drawNode(n *node) {
 	g.drawing = m
 }
 
-func (g *graph) drawEdge(e *edge) (*drawing, *drawing, *drawing, *drawing, *drawing) {
+func (g *graph) drawEdge(e *edge) (*drawing, *drawing, *drawing, *drawing) {
 	from := e.from.gridCoord.Direction(e.startDir)
 	to := e.to.gridCoord.Direction(e.endDir)
 	log.Debugf("Drawing edge between %v (direction %v) and %v (direction %v)", *e.from, e.startDir, *e.to, e.endDir)
@@ -196,21 +220,35 @@ func (g *graph) drawLine(d *drawing, from drawingCoord, to drawingCoord, offsetF
 	return drawnCoords
 }
 
-func drawMap(properties *graphProperties) string {
+// mapGraph builds the graph described by properties and lays it out, ready to
+// be drawn.
+func mapGraph(properties *graphProperties) (*graph, error) {
 	g := mkGraph(properties.data, properties.nodeSpecs)
 	g.setStyleClasses(properties)
 	g.paddingX = properties.paddingX
 	g.paddingY = properties.paddingY
 	g.useAscii = properties.useAscii
+	g.layout = properties.layout
+	g.compact = properties.compact
 	g.setSubgraphs(properties.subgraphs)
-	g.createMapping()
+	if err := g.createMapping(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func drawMap(properties *graphProperties) (string, error) {
+	g, err := mapGraph(properties)
+	if err != nil {
+		return "", err
+	}
 	d := g.draw()
-	if Coords {
+	if properties.showCoords {
 		d = d.debugDrawingWrapper()
-		d = d.debugCoordWrapper(g)
+		d = d.debugCoordWrapper(*g)
 	}
 	s := drawingToString(d)
-	return s
+	return s, nil
 }
 
 func drawBox(n *node, g graph) *drawing {
@@ -273,25 +311,260 @@ func drawBox(n *node, g graph) *drawing {
 		boxDrawing[from.x][to.y] = "+"   // Bottom left corner
 		boxDrawing[to.x][to.y] = "+"     // Bottom right corner
 	}
-	// Draw label lines inside the padded content area.
-	innerTop := from.y + 1
-	innerHeight := h - 1
-	contentTop := innerTop + (innerHeight-n.label.contentHeight())/2
-	for lineIdx, line := range n.label.lines {
-		textY := contentTop + lineIdx*(graphLabelLineGap+1)
-		textWidth := runewidth.StringWidth(line)
-		textX := from.x + w/2 - CeilDiv(textWidth, 2) + 1
-		for _, r := range line {
+	drawNodeLabel(boxDrawing, n, g)
+
+	return &boxDrawing
+}
+
+// drawNodeLabel draws a node's label lines centred in its label area.
+func drawNodeLabel(d drawing, n *node, g graph) {
+	for lineIdx, start := range n.labelLineStarts(g) {
+		textX, textY := start.x, start.y
+		for _, r := range n.label.lines[lineIdx] {
 			runeWidth := Max(runewidth.RuneWidth(r), 1)
-			boxDrawing[textX][textY] = wrapTextInColor(string(r), n.styleClass.styles["color"], g.styleType)
+			d[textX][textY] = string(r)
 			for offset := 1; offset < runeWidth; offset++ {
-				boxDrawing[textX+offset][textY] = ""
+				d[textX+offset][textY] = ""
 			}
 			textX += runeWidth
 		}
 	}
+}
 
-	return &boxDrawing
+// shapeGlyphs is the character set the node shapes other than the plain
+// rectangle are drawn with.
+type shapeGlyphs struct {
+	h, v, tl, tr, bl, br   string // straight border
+	rtl, rtr, rbl, rbr     string // rounded corners
+	dh, dv                 string // double border, for the double circle
+	teeD, teeU, teeR, teeL string // subroutine bars and cylinder rim
+	rise, fall             string // slanted sides
+	tipL, tipR             string // pointed sides
+	parenL, parenR         string // stadium sides
+}
+
+var unicodeShapeGlyphs = shapeGlyphs{
+	h: "─", v: "│", tl: "┌", tr: "┐", bl: "└", br: "┘",
+	rtl: "╭", rtr: "╮", rbl: "╰", rbr: "╯",
+	dh: "═", dv: "║",
+	teeD: "┬", teeU: "┴", teeR: "├", teeL: "┤",
+	rise: "╱", fall: "╲",
+	tipL: "<", tipR: ">",
+	parenL: "(", parenR: ")",
+}
+
+var asciiShapeGlyphs = shapeGlyphs{
+	h: "-", v: "|", tl: "+", tr: "+", bl: "+", br: "+",
+	rtl: ".", rtr: ".", rbl: "'", rbr: "'",
+	dh: "=", dv: "|",
+	teeD: "+", teeU: "+", teeR: "+", teeL: "+",
+	rise: "/", fall: "\\",
+	tipL: "<", tipR: ">",
+	parenL: "(", parenR: ")",
+}
+
+// drawShape draws a node with any shape other than the rectangle drawBox
+// handles. The shape fills the same bounding box a rectangle would, with its
+// left and right sides meeting that box at the row edges attach to; shapes
+// whose sides are inset elsewhere rely on the edge router carrying lines on
+// up to the border.
+func drawShape(n *node, g graph) *drawing {
+	w := g.columnWidth[n.gridCoord.x] + g.columnWidth[n.gridCoord.x+1]
+	h := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]
+	// The row edges attach to on the left and right.
+	m := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]/2
+	gl := unicodeShapeGlyphs
+	if g.useAscii {
+		gl = asciiShapeGlyphs
+	}
+
+	shapeDrawing := *(mkDrawing(w, h))
+	log.Debug("Drawing ", n.shape, " shape of size ", w, "x", h)
+	top, bottom := shapeSpan(n.shape, w, h, m)
+	for y := top; y <= bottom; y++ {
+		left, leftGlyph, right, rightGlyph := shapeRow(n.shape, y, w, h, m, gl)
+		left = Max(0, Min(left, w))
+		right = Max(left, Min(right, w))
+		if y == top || y == bottom {
+			edge := gl.h
+			if n.shape == shapeDoubleCircle {
+				edge = gl.dh
+			}
+			for x := left + 1; x < right; x++ {
+				shapeDrawing[x][y] = edge
+			}
+		}
+		shapeDrawing[left][y] = leftGlyph
+		shapeDrawing[right][y] = rightGlyph
+	}
+
+	switch n.shape {
+	case shapeSubroutine:
+		// Inner bars just inside both sides.
+		for y := 0; y <= h; y++ {
+			bar := gl.v
+			if y == 0 {
+				bar = gl.teeD
+			} else if y == h {
+				bar = gl.teeU
+			}
+			shapeDrawing[1][y] = bar
+			shapeDrawing[w-1][y] = bar
+		}
+	case shapeCylinder:
+		// The rim of the top ellipse.
+		shapeDrawing[0][1] = gl.teeR
+		for x := 1; x < w; x++ {
+			shapeDrawing[x][1] = gl.h
+		}
+		shapeDrawing[w][1] = gl.teeL
+	}
+
+	drawNodeLabel(shapeDrawing, n, g)
+	return &shapeDrawing
+}
+
+// labelLineStarts is where each of the node's label lines starts, relative to
+// the node's top-left corner: centred between the columns left and right of
+// its label area, inside the rows from innerTop down to the bottom border.
+func (n *node) labelLineStarts(g graph) []drawingCoord {
+	w := g.columnWidth[n.gridCoord.x] + g.columnWidth[n.gridCoord.x+1]
+	h := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]
+	m := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]/2
+	left, right := 0, w
+	if n.shape == shapeAsymmetric {
+		left = m // centre the label right of the notch
+	}
+	top, bottom := shapeSpan(n.shape, w, h, m)
+	innerTop := top + 1 + n.shape.extraHeight()
+	innerHeight := bottom - innerTop
+
+	contentTop := innerTop + (innerHeight-n.label.contentHeight())/2
+	starts := make([]drawingCoord, len(n.label.lines))
+	for lineIdx, line := range n.label.lines {
+		textWidth := runewidth.StringWidth(line)
+		starts[lineIdx] = drawingCoord{
+			x: left + (right-left)/2 - CeilDiv(textWidth, 2) + 1,
+			y: contentTop + lineIdx*(graphLabelLineGap+1),
+		}
+	}
+	return starts
+}
+
+// shapeSpan returns the first and last rows of a w x h bounding box a shape is
+// drawn on. Shapes that narrow towards their top and bottom are sized for the
+// label's own height; in a row made taller by a neighbour they keep their
+// slope and leave the rows beyond their flat top and bottom edges blank.
+func shapeSpan(s nodeShape, w, h, m int) (int, int) {
+	top, bottom := 0, h
+	if s != shapeRhombus && s != shapeHexagon {
+		return top, bottom
+	}
+	// The rhombus closes in a point, its two sides side by side; the hexagon
+	// keeps at least one edge cell between them.
+	slope, narrowest := 1, 2
+	if s == shapeRhombus {
+		slope, narrowest = 2, 1
+	}
+	for top < m && w-2*slope*(m-top) < narrowest {
+		top++
+	}
+	for bottom > m && w-2*slope*(bottom-m) < narrowest {
+		bottom--
+	}
+	return top, bottom
+}
+
+// shapeRow returns where a shape's left and right borders sit on row y of its
+// w x h bounding box, and the glyph drawing each. m is the row edges attach to
+// on either side. drawShape joins the two with an edge on the top and bottom
+// rows.
+func shapeRow(s nodeShape, y, w, h, m int, gl shapeGlyphs) (int, string, int, string) {
+	top, bottom := y == 0, y == h
+	switch s {
+	case shapeRound, shapeCylinder:
+		switch {
+		case top:
+			return 0, gl.rtl, w, gl.rtr
+		case bottom:
+			return 0, gl.rbl, w, gl.rbr
+		}
+	case shapeStadium:
+		switch {
+		case top:
+			return 0, gl.rtl, w, gl.rtr
+		case bottom:
+			return 0, gl.rbl, w, gl.rbr
+		}
+		return 0, gl.parenL, w, gl.parenR
+	case shapeCircle, shapeDoubleCircle:
+		side := gl.v
+		if s == shapeDoubleCircle {
+			side = gl.dv
+		}
+		// Slanted shoulders need a row between the top edge and the
+		// attach row; shorter circles are drawn with rounded corners.
+		inset := 0
+		if m >= 2 {
+			inset = 1
+		}
+		switch {
+		case top:
+			return inset, gl.rtl, w - inset, gl.rtr
+		case bottom:
+			return inset, gl.rbl, w - inset, gl.rbr
+		case inset == 1 && y == 1:
+			return 0, gl.rise, w, gl.fall
+		case inset == 1 && y == h-1:
+			return 0, gl.fall, w, gl.rise
+		}
+		return 0, side, w, side
+	case shapeRhombus, shapeHexagon:
+		// The rhombus narrows two columns per row, the hexagon one, so the
+		// rhombus ends up pointed top and bottom and the hexagon flat-topped.
+		slope := 1
+		if s == shapeRhombus {
+			slope = 2
+		}
+		off := slope * Abs(y-m)
+		switch {
+		case y < m:
+			return off, gl.rise, w - off, gl.fall
+		case y > m:
+			return off, gl.fall, w - off, gl.rise
+		}
+		return 0, gl.tipL, w, gl.tipR
+	case shapeLeanRight:
+		return h - y, gl.rise, w - y, gl.rise
+	case shapeLeanLeft:
+		return y, gl.fall, w - h + y, gl.fall
+	case shapeTrapezoid:
+		return h - y, gl.rise, w - h + y, gl.fall
+	case shapeInvTrapezoid:
+		return y, gl.fall, w - y, gl.rise
+	case shapeAsymmetric:
+		right := gl.v
+		if top {
+			right = gl.tr
+		} else if bottom {
+			right = gl.br
+		}
+		off := m - Abs(y-m)
+		switch {
+		case y < m:
+			return off, gl.fall, w, right
+		case y > m:
+			return off, gl.rise, w, right
+		}
+		return off, gl.tipR, w, right
+	}
+	switch {
+	case top:
+		return 0, gl.tl, w, gl.tr
+	case bottom:
+		return 0, gl.bl, w, gl.br
+	}
+	return 0, gl.v, w, gl.v
 }
 
 func drawSubgraph(sg *subgraph, g graph) *drawing {
@@ -397,15 +670,33 @@ func drawSubgraphLabel(sg *subgraph, g graph) (*drawing, drawingCoord) {
 	return &labelDrawing, offset
 }
 
-func wrapTextInColor(text, c, styleType string) string {
-	if c == "" {
+// wrapTextInColor colours text with the fg and bg colours, either of which
+// may be empty, the way styleType output shows colour.
+func wrapTextInColor(text, fg, bg, styleType string) string {
+	if fg == "" && bg == "" {
 		return text
 	}
 	if styleType == "html" {
-		return fmt.Sprintf("<span style='color: %s'>%s</span>", c, text)
+		css := []string{}
+		if fg != "" {
+			css = append(css, "color: "+fg)
+		}
+		if bg != "" {
+			css = append(css, "background-color: "+bg)
+		}
+		return fmt.Sprintf("<span style='%s'>%s</span>", strings.Join(css, "; "), text)
 	} else if styleType == "cli" {
-		cliColor := color.HEX(c)
+		cliColor := &color.RGBStyle{}
+		if hex := cssColorHex(fg); hex != "" {
+			cliColor.SetFg(color.HEX(hex))
+		}
+		if hex := cssColorHex(bg); hex != "" {
+			cliColor.SetBg(color.HEX(hex, true))
+		}
 		return cliColor.Sprint(text)
+	} else if styleType == "svg" {
+		// The SVG renderer paints labels itself.
+		return text
 	} else {
 		log.Warnf("Unknown style type %s", styleType)
 		return text
@@ -426,6 +717,8 @@ func (d *drawing) increaseSize(x int, y int) {
 	*d = *drawingWithNewSize
 }
 
+// setDrawingSizeToGridConstraints grows the drawing to fit the grid and
+// every edge's path, lanes and the room made for subgraphs included.
 func (g *graph) setDrawingSizeToGridConstraints() {
 	// Get largest column and row size
 	maxX := 0
@@ -436,33 +729,47 @@ func (g *graph) setDrawingSizeToGridConstraints() {
 	for _, h := range g.rowHeight {
 		maxY += h
 	}
-	// Increase size of drawing to fit all nodes
-	g.drawing.increaseSize(maxX-1, maxY-1)
+	maxX, maxY = maxX-1, maxY-1
+	for _, e := range g.edges {
+		for _, c := range g.pathToDrawing(e) {
+			maxX = Max(maxX, c.x)
+			maxY = Max(maxY, c.y)
+		}
+	}
+	// Increase size of drawing to fit all nodes and edges
+	g.drawing.increaseSize(maxX, maxY)
 }
 
-func mergeJunctions(c1, c2 string) string {
-	// Define all possible junction combinations
-	junctionMap := map[string]map[string]string{
-		"─": {"│": "┼", "┌": "┬", "┐": "┬", "└": "┴", "┘": "┴", "├": "┼", "┤": "┼", "┬": "┬", "┴": "┴"},
-		"│": {"─": "┼", "┌": "├", "┐": "┤", "└": "├", "┘": "┤", "├": "├", "┤": "┤", "┬": "┼", "┴": "┼"},
-		"┌": {"─": "┬", "│": "├", "┐": "┬", "└": "├", "┘": "┼", "├": "├", "┤": "┼", "┬": "┬", "┴": "┼"},
-		"┐": {"─": "┬", "│": "┤", "┌": "┬", "└": "┼", "┘": "┤", "├": "┼", "┤": "┤", "┬": "┬", "┴": "┼"},
-		"└": {"─": "┴", "│": "├", "┌": "├", "┐": "┼", "┘": "┴", "├": "├", "┤": "┼", "┬": "┼", "┴": "┴"},
-		"┘": {"─": "┴", "│": "┤", "┌": "┼", "┐": "┤", "└": "┴", "├": "┼", "┤": "┤", "┬": "┼", "┴": "┴"},
-		"├": {"─": "┼", "│": "├", "┌": "├", "┐": "┼", "└": "├", "┘": "┼", "┤": "┼", "┬": "┼", "┴": "┼"},
-		"┤": {"─": "┼", "│": "┤", "┌": "┼", "┐": "┤", "└": "┼", "┘": "┤", "├": "┼", "┬": "┼", "┴": "┼"},
-		"┬": {"─": "┬", "│": "┼", "┌": "┬", "┐": "┬", "└": "┼", "┘": "┼", "├": "┼", "┤": "┼", "┴": "┼"},
-		"┴": {"─": "┴", "│": "┼", "┌": "┼", "┐": "┼", "└": "┴", "┘": "┴", "├": "┼", "┤": "┼", "┬": "┼"},
+func glyphArms(c string) (boxArms, bool) {
+	if arms, ok := junctionArms[c]; ok {
+		return arms, true
 	}
+	arms, ok := dashedArms[c]
+	return arms, ok
+}
 
-	// Check if there's a defined merge for the two characters
-	if merged, ok := junctionMap[c1][c2]; ok {
-		log.Debugf("Merging %s and %s to %s", c1, c2, merged)
-		return merged
+// mergeJunctions returns the glyph for c2 drawn over c1: the one with the
+// arms of both, heavy where either is heavy. A glyph that already has all
+// the arms is kept as it is, so a dotted line stays dotted where it runs
+// along another line.
+func mergeJunctions(c1, c2 string) string {
+	arms1, ok1 := glyphArms(c1)
+	arms2, ok2 := glyphArms(c2)
+	if !ok1 || !ok2 {
+		return c1
 	}
-
-	// If no merge is defined, return c1 as a fallback
-	return c1
+	var merged boxArms
+	for i := range merged {
+		merged[i] = max(arms1[i], arms2[i])
+	}
+	switch merged {
+	case arms2:
+		return c2
+	case arms1:
+		return c1
+	}
+	log.Debugf("Merging %s and %s to %s", c1, c2, junctionGlyphs[merged])
+	return junctionGlyphs[merged]
 }
 
 func (g *graph) mergeDrawings(baseDrawing *drawing, mergeCoord drawingCoord, drawings ...*drawing) *drawing {
@@ -507,12 +814,8 @@ func (g *graph) mergeDrawings(baseDrawing *drawing, mergeCoord drawingCoord, dra
 }
 
 func isJunctionChar(c string) bool {
-	for _, junctionChar := range junctionChars {
-		if c == junctionChar {
-			return true
-		}
-	}
-	return false
+	_, ok := glyphArms(c)
+	return ok
 }
 
 func drawingToString(d *drawing) string {
@@ -0,0 +1,29 @@
+package graph
+
+import "testing"
+
+func TestMergeJunctions(t *testing.T) {
+	tests := []struct {
+		existing, drawn, want string
+	}{
+		{"─", "│", "┼"},
+		{"┌", "┘", "┼"},
+		{"─", "┬", "┬"},
+		{"┬", "─", "┬"},
+		{"━", "│", "┿"},
+		{"┃", "─", "╂"},
+		{"┃", "└", "┠"},
+		{"┏", "─", "┲"},
+		{"━", "┌", "┯"},
+		{"│", "╺", "┝"},
+		{"┃", "│", "┃"},
+		{"│", "┆", "┆"},
+		{"┄", "│", "┼"},
+		{"─", "►", "─"},
+	}
+	for _, tt := range tests {
+		if got := mergeJunctions(tt.existing, tt.drawn); got != tt.want {
+			t.Errorf("mergeJunctions(%q, %q) = %q, want %q", tt.existing, tt.drawn, got, tt.want)
+		}
+	}
+}
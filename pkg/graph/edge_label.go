@@ -0,0 +1,297 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Edge labels are placed once everything else is drawn, so they can be kept
+// clear of it. A label is written over a stretch of its own edge's line that
+// no other edge shares, with some of that line showing on either side, and
+// on blank cells everywhere else. determineLabelLine has already made room
+// along one of the edge's segments; if that room was taken after all, the
+// edge's other segments are tried. A label that fits nowhere becomes a
+// numbered marker on the edge, with the label itself in a legend below the
+// drawing.
+
+// edgeLabelPlacement is where an edge's label went on the drawing.
+type edgeLabelPlacement struct {
+	// lines are the lines written on the drawing, each starting at the
+	// coord of the same index in starts.
+	lines  []string
+	starts []drawingCoord
+	// footnote is the number of the legend entry that holds the label when
+	// it didn't fit along the edge; lines then holds its marker, unless
+	// there was no room for even that.
+	footnote int
+}
+
+// labelBlock is a candidate spot for a label: its lines, one below the
+// other and centred on the same column.
+type labelBlock struct {
+	lines  []string
+	starts []drawingCoord
+}
+
+// labelSpotter finds room for labels on a drawing.
+type labelSpotter struct {
+	g *graph
+	// drawnBy counts the edges drawn through each cell.
+	drawnBy map[drawingCoord]int
+	// taken holds the cells of the labels placed so far.
+	taken map[drawingCoord]bool
+}
+
+// placeEdgeLabels places the label of every edge on g's drawing, given the
+// lines and corners drawn for each edge, and returns a drawing of each
+// edge's label in the order of g.edges. Labels that didn't fit are listed
+// in g.labelLegend.
+func (g *graph) placeEdgeLabels(edgeLines map[*edge]*drawing) []*drawing {
+	s := &labelSpotter{g: g, drawnBy: map[drawingCoord]int{}, taken: map[drawingCoord]bool{}}
+	for _, d := range edgeLines {
+		for x := range *d {
+			for y, cell := range (*d)[x] {
+				if cell != " " {
+					s.drawnBy[drawingCoord{x, y}]++
+				}
+			}
+		}
+	}
+
+	g.labelLegend = nil
+	labels := make([]*drawing, len(g.edges))
+	for i, e := range g.edges {
+		labels[i] = copyCanvas(g.drawing)
+		e.labelPlacement = nil
+		if e.text == "" || len(e.path) < 2 {
+			continue
+		}
+		placement := &edgeLabelPlacement{}
+		block, ok := s.find(e, edgeLines[e], e.label.lines, false)
+		if !ok {
+			placement.footnote = len(g.labelLegend) + 1
+			g.labelLegend = append(g.labelLegend, g.legendEntry(e, placement.footnote))
+			// The legend tells which edge a marker belongs to, so it may go
+			// on a line the edge shares with others.
+			block, ok = s.find(e, edgeLines[e], []string{footnoteMarker(placement.footnote)}, true)
+		}
+		if ok {
+			placement.lines, placement.starts = block.lines, block.starts
+			for j, line := range block.lines {
+				labels[i].drawText(block.starts[j], line)
+				for x := 0; x < runewidth.StringWidth(line); x++ {
+					s.taken[drawingCoord{block.starts[j].x + x, block.starts[j].y}] = true
+				}
+			}
+		}
+		e.labelPlacement = placement
+	}
+	return labels
+}
+
+func footnoteMarker(n int) string {
+	return fmt.Sprintf("[%d]", n)
+}
+
+// legendEntry is the line of the legend that holds e's label.
+func (g *graph) legendEntry(e *edge, footnote int) string {
+	arrow := "→"
+	if g.useAscii {
+		arrow = "->"
+	}
+	from := strings.Join(e.from.label.lines, " ")
+	if e.fromSubgraph != nil {
+		from = strings.Join(e.fromSubgraph.label.lines, " ")
+	}
+	to := strings.Join(e.to.label.lines, " ")
+	if e.toSubgraph != nil {
+		to = strings.Join(e.toSubgraph.label.lines, " ")
+	}
+	return fmt.Sprintf("%s %s %s %s: %s", footnoteMarker(footnote), from, arrow, to, strings.Join(e.label.lines, " "))
+}
+
+// drawEdgeLabels writes every placed label on the drawing. Unlike the
+// drawings merged onto it, the spaces in a label are written too, so the
+// line it's on doesn't show through between its words.
+func (g *graph) drawEdgeLabels() {
+	for _, e := range g.edges {
+		if p := e.labelPlacement; p != nil {
+			for i, line := range p.lines {
+				g.drawing.drawText(p.starts[i], line)
+			}
+		}
+	}
+}
+
+// drawLabelLegend writes the labels that didn't fit below the drawing,
+// after a blank line.
+func (g *graph) drawLabelLegend() {
+	if len(g.labelLegend) == 0 {
+		return
+	}
+	_, maxY := getDrawingSize(g.drawing)
+	for i, entry := range g.labelLegend {
+		g.drawing.drawText(drawingCoord{x: 0, y: maxY + 2 + i}, entry)
+	}
+}
+
+// find looks for room for lines along e, whose lines and corners are drawn
+// in own: first on the segment determineLabelLine picked, then on the
+// others, longest first. Unless shared is set, the lines may only cover
+// stretches of e's line that no other edge is drawn over.
+func (s *labelSpotter) find(e *edge, own *drawing, lines []string, shared bool) (labelBlock, bool) {
+	line := s.g.pathToDrawing(e)
+	segments := make([]int, 0, len(line)-1)
+	preferred := -1
+	for i := 1; i < len(line); i++ {
+		if len(e.labelLine) == 2 && e.path[i-1] == e.labelLine[0] && e.path[i] == e.labelLine[1] {
+			preferred = i
+			continue
+		}
+		segments = append(segments, i)
+	}
+	sort.SliceStable(segments, func(a, b int) bool {
+		return segmentLength(line[segments[a]-1], line[segments[a]]) > segmentLength(line[segments[b]-1], line[segments[b]])
+	})
+	if preferred > 0 {
+		segments = append([]int{preferred}, segments...)
+	}
+
+	for _, i := range segments {
+		a, b := line[i-1], line[i]
+		// Start from the middle of the segment, leaving room for the line
+		// and arrowheads either side.
+		middle := lineMiddle(s.g.labelLineOn(e, a, b))
+		for _, block := range labelBlocksAlong(a, b, middle, lines) {
+			if s.fits(own, block, a, b, shared) {
+				return block, true
+			}
+		}
+	}
+	return labelBlock{}, false
+}
+
+// labelLineOn is the stretch of the segment from a to b a label is centred
+// on, leaving room for the edge's line and arrowheads either side.
+func (g *graph) labelLineOn(e *edge, a, b drawingCoord) []drawingCoord {
+	if e.isBidirectional {
+		return insetLine([]drawingCoord{a, b}, 2, 2)
+	}
+	return insetLine([]drawingCoord{a, b}, 1, 2)
+}
+
+func segmentLength(a, b drawingCoord) int {
+	return Abs(a.x-b.x) + Abs(a.y-b.y)
+}
+
+// labelBlocksAlong returns every spot for lines along the segment from a to
+// b, nearest to middle first. On a horizontal segment the first line is
+// written over the edge and the others below it; on a vertical one the
+// lines are stacked across it.
+func labelBlocksAlong(a, b, middle drawingCoord, lines []string) []labelBlock {
+	width := 0
+	for _, line := range lines {
+		width = Max(width, runewidth.StringWidth(line))
+	}
+	block := func(centreX, top int) labelBlock {
+		lb := labelBlock{lines: lines}
+		for i, line := range lines {
+			lb.starts = append(lb.starts, drawingCoord{x: centreX - runewidth.StringWidth(line)/2, y: top + i})
+		}
+		return lb
+	}
+
+	// Spots are tried by how far their first line's middle is from middle.
+	var spots []drawingCoord
+	if a.y == b.y {
+		for x := Min(a.x, b.x) + width/2; x <= Max(a.x, b.x)-(width-1)/2; x++ {
+			spots = append(spots, drawingCoord{x: x, y: a.y})
+		}
+	} else {
+		for y := Min(a.y, b.y); y+len(lines)-1 <= Max(a.y, b.y); y++ {
+			spots = append(spots, drawingCoord{x: a.x, y: y})
+		}
+		middle.y -= (len(lines) - 1) / 2
+	}
+	sort.SliceStable(spots, func(i, j int) bool {
+		return segmentLength(spots[i], middle) < segmentLength(spots[j], middle)
+	})
+	blocks := make([]labelBlock, len(spots))
+	for i, spot := range spots {
+		blocks[i] = block(spot.x, spot.y)
+	}
+	return blocks
+}
+
+// fits reports whether block can be written over the segment of its edge
+// from a to b, whose lines and corners are drawn in own, without covering
+// anything else, or other edges' lines unless shared is set. The edge's line
+// must carry on past both ends of the label.
+func (s *labelSpotter) fits(own *drawing, block labelBlock, a, b drawingCoord, shared bool) bool {
+	horizontal := a.y == b.y
+	d := s.g.drawing
+	maxX, maxY := getDrawingSize(d)
+	cell := func(c drawingCoord) string {
+		if c.x < 0 || c.y < 0 {
+			return "outside"
+		}
+		if c.x > maxX || c.y > maxY {
+			return " "
+		}
+		return (*d)[c.x][c.y]
+	}
+	free := func(c drawingCoord) bool {
+		return !s.taken[c] && cell(c) == " "
+	}
+	// onLine reports whether c carries e's line, and no other edge's unless
+	// shared is set. The gaps in a dotted line count as line too.
+	onLine := func(c drawingCoord) bool {
+		if s.taken[c] || c.x < 0 || c.y < 0 || c.x >= len(*own) || c.y >= len((*own)[0]) {
+			return false
+		}
+		mine := (*own)[c.x][c.y]
+		if mine == " " {
+			inSpan := c.x >= Min(a.x, b.x) && c.x <= Max(a.x, b.x) && c.y >= Min(a.y, b.y) && c.y <= Max(a.y, b.y)
+			return inSpan && s.drawnBy[c] == 0 && cell(c) == " "
+		}
+		return (s.drawnBy[c] == 1 || shared) && cell(c) == mine
+	}
+
+	if horizontal {
+		lineRow := block.starts[0].y
+		for i, line := range block.lines {
+			start := block.starts[i]
+			width := runewidth.StringWidth(line)
+			want := free
+			if start.y == lineRow {
+				want = onLine
+			}
+			for x := start.x - 1; x <= start.x+width; x++ {
+				if !want(drawingCoord{x, start.y}) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	lineX := block.starts[0].x + runewidth.StringWidth(block.lines[0])/2
+	for i, line := range block.lines {
+		start := block.starts[i]
+		for x := start.x - 1; x <= start.x+runewidth.StringWidth(line); x++ {
+			c := drawingCoord{x, start.y}
+			if x == lineX {
+				if !onLine(c) {
+					return false
+				}
+			} else if !free(c) {
+				return false
+			}
+		}
+	}
+	first, last := block.starts[0], block.starts[len(block.starts)-1]
+	return onLine(drawingCoord{lineX, first.y - 1}) && onLine(drawingCoord{lineX, last.y + 1})
+}
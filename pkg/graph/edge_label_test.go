@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+func TestEdgeLabelFallsBackToFootnote(t *testing.T) {
+	// The labelled edge runs along the unlabelled one the whole way.
+	gd, err := Parse("graph TD\nA --> B\nB --> C\nA --> C\nA -->|skip| C")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	config := diagram.DefaultConfig()
+	config.UseAscii = true
+	text, err := Render(gd, config)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if lineOf(text, "[1] A -> C: skip") != len(strings.Split(text, "\n"))-1 {
+		t.Errorf("want a legend entry for the label below the drawing:\n%s", text)
+	}
+
+	layout, err := Layout(gd, config)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	rows := splitDrawing(text)
+	for _, e := range layout.Edges {
+		if e.Label != "skip" {
+			continue
+		}
+		if e.Footnote != 1 {
+			t.Errorf("footnote = %d, want 1", e.Footnote)
+		}
+		if e.LabelAt == nil {
+			t.Fatalf("the footnote marker has no position")
+		}
+		if got := string(rows[e.LabelAt.Y][e.LabelAt.X : e.LabelAt.X+3]); got != "[1]" {
+			t.Errorf("marker reads %q, want [1]", got)
+		}
+	}
+}
+
+func TestMultiLineEdgeLabel(t *testing.T) {
+	for _, input := range []string{
+		"graph LR\nA -->|first line<br>second| B",
+		"graph TD\nA -->|first line<br>second| B",
+	} {
+		t.Run(strings.SplitN(input, "\n", 2)[0], func(t *testing.T) {
+			gd, err := Parse(input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			config := diagram.DefaultConfig()
+			config.UseAscii = true
+			text, err := Render(gd, config)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			first, second := lineOf(text, "first"), lineOf(text, "second")
+			if first < 0 || second != first+1 {
+				t.Errorf("want the label's lines one below the other:\n%s", text)
+			}
+			if lineOf(text, "[1]") >= 0 {
+				t.Errorf("the label should fit without a footnote:\n%s", text)
+			}
+		})
+	}
+}
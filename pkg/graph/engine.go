@@ -0,0 +1,359 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	log "github.com/sirupsen/logrus"
+)
+
+// GridCoord is a cell of the grid graphs are laid out on. A node takes up the
+// 3x3 cells from its coord to the right and down, and the cells around it
+// carry its edges, so nodes next to each other are usually 4 cells apart.
+// How wide a column or high a row ends up on the drawing depends on what's
+// in it. RL and BT graphs are laid out as LR and TD and mirrored afterwards.
+type GridCoord struct {
+	X, Y int
+}
+
+// LayoutGraph is what a layout engine gets to see of a graph.
+type LayoutGraph struct {
+	// Direction is the graph's direction: LR, RL, TD or BT.
+	Direction string
+	Nodes     []LayoutNode
+	// Edges holds the graph's edges in the order they were written, followed
+	// by its invisible (~~~) ones.
+	Edges     []LayoutEdge
+	Subgraphs []LayoutSubgraph
+
+	g *graph
+}
+
+type LayoutNode struct {
+	ID    string
+	Label string
+}
+
+// LayoutEdge is an edge between two nodes. An edge to or from a subgraph is
+// laid out against one of the subgraph's nodes, and drawn up to its frame.
+type LayoutEdge struct {
+	From, To string
+	// FromSubgraph and ToSubgraph are the IDs of the subgraphs the edge
+	// starts and ends at, if any.
+	FromSubgraph, ToSubgraph string
+	// MinLength is the number of ranks the edge spans at least: 2 for --->.
+	MinLength int
+	// Invisible edges (~~~) take part in the layout but aren't routed.
+	Invisible bool
+
+	edge *edge
+}
+
+type LayoutSubgraph struct {
+	ID string
+	// Parent is the ID of the subgraph this one is nested in, if any.
+	Parent string
+	// Direction is the subgraph's own direction statement, if it has one.
+	Direction string
+	// Nodes holds the IDs of the subgraph's nodes, including those of the
+	// subgraphs nested in it.
+	Nodes []string
+}
+
+// Placer decides where a graph's nodes go.
+type Placer interface {
+	// Place returns the grid coord of every node of g, keyed by ID. Nodes
+	// mustn't overlap; subgraphs are framed around the nodes they hold.
+	Place(g *LayoutGraph) (map[string]GridCoord, error)
+}
+
+// RoutingGrid is a laid out graph, for a Router to find paths across.
+type RoutingGrid struct {
+	*LayoutGraph
+	// Placed holds the grid coord every node was placed at, keyed by ID.
+	Placed map[string]GridCoord
+}
+
+// Free reports whether no node takes up c.
+func (r *RoutingGrid) Free(c GridCoord) bool {
+	return r.g.isFreeInGrid(gridCoord{x: c.X, y: c.Y})
+}
+
+// ShortestPath finds the path with the fewest steps and turns from one cell
+// to another around the nodes, as the cells it turns at. Running along or
+// across the edges routed so far counts as a few extra steps, so the path
+// keeps clear of them unless that's a long way round.
+func (r *RoutingGrid) ShortestPath(from, to GridCoord) ([]GridCoord, error) {
+	path, err := r.g.getPath(gridCoord{x: from.X, y: from.Y}, gridCoord{x: to.X, y: to.Y})
+	if err != nil {
+		return nil, err
+	}
+	return toGridCoords(mergePath(path)), nil
+}
+
+// Router decides the path every edge takes once the nodes are placed.
+type Router interface {
+	// Route returns the path of e as the cells it starts at, turns at and
+	// ends at. It starts on the middle cell of the side of e.From it leaves
+	// from and ends on that of the side of e.To it arrives at, or just
+	// outside the frame of a subgraph the edge starts or ends at.
+	Route(grid *RoutingGrid, e LayoutEdge) ([]GridCoord, error)
+}
+
+// Engine lays graphs out: its Placer places the nodes, after which its
+// Router finds the edges' paths between them. A nil Placer or Router does
+// what the default engine's does.
+type Engine struct {
+	Placer Placer
+	Router Router
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]Engine{
+		"grid":    {Placer: gridPlacer{}, Router: gridRouter{}},
+		"layered": {Placer: layeredPlacer{}, Router: gridRouter{}},
+	}
+)
+
+// RegisterEngine makes e available as the layout engine called name, which
+// diagram.Config.Layout picks. It replaces any engine registered under the
+// same name, including the built-in "grid" and "layered" ones.
+func RegisterEngine(name string, e Engine) {
+	if e.Placer == nil {
+		e.Placer = gridPlacer{}
+	}
+	if e.Router == nil {
+		e.Router = gridRouter{}
+	}
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	engines[name] = e
+}
+
+// Engines returns the names of the registered layout engines, sorted.
+func Engines() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	return engineNames()
+}
+
+// lookupEngine returns the engine called name, or the grid engine when name
+// is empty.
+func lookupEngine(name string) (Engine, error) {
+	if name == "" {
+		name = "grid"
+	}
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	e, ok := engines[name]
+	if !ok {
+		return Engine{}, &diagram.ConfigError{Field: "Layout", Value: name, Message: fmt.Sprintf("must be one of %s", strings.Join(engineNames(), ", "))}
+	}
+	return e, nil
+}
+
+// engineNames returns the sorted names of the registered engines; callers
+// hold enginesMu.
+func engineNames() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gridPlacer places nodes rank by rank in the order they're found.
+type gridPlacer struct{}
+
+func (gridPlacer) Place(lg *LayoutGraph) (map[string]GridCoord, error) {
+	lg.g.placeNodesOnGrid()
+	return lg.g.placedNodes(), nil
+}
+
+// layeredPlacer orders every rank to keep edges from crossing. It can't lay
+// a subgraph out along a direction of its own, and leaves graphs with such
+// subgraphs to the grid placer.
+type layeredPlacer struct{}
+
+func (layeredPlacer) Place(lg *LayoutGraph) (map[string]GridCoord, error) {
+	if lg.g.hasSubgraphDirection() {
+		log.Debugf("Placing nodes on the grid: the layered layout doesn't follow subgraph directions")
+		lg.g.placeNodesOnGrid()
+	} else {
+		lg.g.placeNodesLayered()
+	}
+	return lg.g.placedNodes(), nil
+}
+
+// gridRouter routes edges along the shortest path around the nodes.
+type gridRouter struct{}
+
+func (gridRouter) Route(grid *RoutingGrid, e LayoutEdge) ([]GridCoord, error) {
+	grid.g.determinePath(e.edge)
+	return toGridCoords(e.edge.path), nil
+}
+
+// layoutGraph describes g to a layout engine.
+func (g *graph) layoutGraph() *LayoutGraph {
+	lg := &LayoutGraph{Direction: g.graphDirection, g: g}
+	for _, n := range g.nodes {
+		lg.Nodes = append(lg.Nodes, LayoutNode{ID: n.name, Label: strings.Join(n.label.lines, "\n")})
+	}
+	for i, graphEdges := range [][]*edge{g.edges, g.invisibleEdges} {
+		for _, e := range graphEdges {
+			le := LayoutEdge{From: e.from.name, To: e.to.name, MinLength: max(e.length, 1), Invisible: i == 1, edge: e}
+			if e.fromSubgraph != nil {
+				le.FromSubgraph = e.fromSubgraph.id
+			}
+			if e.toSubgraph != nil {
+				le.ToSubgraph = e.toSubgraph.id
+			}
+			lg.Edges = append(lg.Edges, le)
+		}
+	}
+	for _, sg := range g.subgraphs {
+		ls := LayoutSubgraph{ID: sg.id, Direction: sg.direction}
+		if sg.parent != nil {
+			ls.Parent = sg.parent.id
+		}
+		for _, n := range sg.nodes {
+			ls.Nodes = append(ls.Nodes, n.name)
+		}
+		lg.Subgraphs = append(lg.Subgraphs, ls)
+	}
+	return lg
+}
+
+// placedNodes returns the grid coord of every node that has one, keyed by
+// name.
+func (g *graph) placedNodes() map[string]GridCoord {
+	placed := map[string]GridCoord{}
+	for _, n := range g.nodes {
+		if n.gridCoord != nil {
+			placed[n.name] = GridCoord{X: n.gridCoord.x, Y: n.gridCoord.y}
+		}
+	}
+	return placed
+}
+
+// placeNodes places g's nodes with placer, reserving the cells of each.
+func (g *graph) placeNodes(placer Placer, lg *LayoutGraph) error {
+	placed, err := placer.Place(lg)
+	if err != nil {
+		return err
+	}
+	g.grid = map[gridCoord]*node{}
+	for _, n := range g.nodes {
+		c, ok := placed[n.name]
+		if !ok {
+			return fmt.Errorf("layout engine didn't place node %s", n.name)
+		}
+		if c.X < 0 || c.Y < 0 {
+			return fmt.Errorf("layout engine placed node %s at %d,%d, outside the grid", n.name, c.X, c.Y)
+		}
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 3; y++ {
+				if other := g.grid[gridCoord{x: c.X + x, y: c.Y + y}]; other != nil {
+					return fmt.Errorf("layout engine placed nodes %s and %s on top of each other", other.name, n.name)
+				}
+			}
+		}
+		g.reserveSpotInGrid(n, &gridCoord{x: c.X, y: c.Y})
+	}
+	return nil
+}
+
+// routeEdges finds the path of every visible edge with router.
+func (g *graph) routeEdges(router Router, lg *LayoutGraph) error {
+	grid := &RoutingGrid{LayoutGraph: lg, Placed: g.placedNodes()}
+	g.resetRouting()
+	for _, le := range g.routingOrder(lg.Edges) {
+		if le.Invisible {
+			continue
+		}
+		e := le.edge
+		path, err := router.Route(grid, le)
+		if err != nil {
+			return fmt.Errorf("routing edge %s -> %s: %w", le.From, le.To, err)
+		}
+		// The built-in router sets the path up itself.
+		if !slices.Equal(path, toGridCoords(e.path)) {
+			if err := g.setRoute(e, path); err != nil {
+				return fmt.Errorf("routing edge %s -> %s: %w", le.From, le.To, err)
+			}
+		}
+		g.markRouted(e)
+	}
+	return nil
+}
+
+// routingOrder is the order edges are routed in: as written, except that
+// edges going back up the ranks swap places among themselves so the ones
+// between nearer nodes go first. The edges routed after them then go around
+// them, and back edges nest instead of crossing.
+func (g *graph) routingOrder(edges []LayoutEdge) []LayoutEdge {
+	var slots []int
+	var back []LayoutEdge
+	for i, le := range edges {
+		if !le.Invisible && g.isBackEdge(le.edge) {
+			slots = append(slots, i)
+			back = append(back, le)
+		}
+	}
+	sort.SliceStable(back, func(i, j int) bool {
+		return back[i].edge.span() < back[j].edge.span()
+	})
+	ordered := slices.Clone(edges)
+	for i, slot := range slots {
+		ordered[slot] = back[i]
+	}
+	return ordered
+}
+
+// isBackEdge reports whether e goes back to an earlier rank.
+func (g *graph) isBackEdge(e *edge) bool {
+	if g.isHorizontal() {
+		return e.to.gridCoord.x < e.from.gridCoord.x
+	}
+	return e.to.gridCoord.y < e.from.gridCoord.y
+}
+
+// setRoute makes path the path of e, leaving and arriving in the direction
+// of its first and last steps.
+func (g *graph) setRoute(e *edge, path []GridCoord) error {
+	if len(path) < 2 {
+		return fmt.Errorf("path %v has fewer than two cells", path)
+	}
+	gridPath := make([]gridCoord, len(path))
+	for i, c := range path {
+		gridPath[i] = gridCoord{x: c.X, y: c.Y}
+		if i > 0 && (c.X != path[i-1].X) == (c.Y != path[i-1].Y) {
+			return fmt.Errorf("path %v doesn't go straight from %v to %v", path, path[i-1], c)
+		}
+	}
+	last := len(gridPath) - 1
+	e.path = mergePath(gridPath)
+	e.startDir = determineDirection(genericCoord(gridPath[0]), genericCoord(gridPath[1]))
+	e.endDir = determineDirection(genericCoord(gridPath[last]), genericCoord(gridPath[last-1]))
+	if e.fromSubgraph != nil {
+		g.makeRoomForSubgraphFrame(e.fromSubgraph, gridPath[0], e.startDir)
+	}
+	if e.toSubgraph != nil {
+		g.makeRoomForSubgraphFrame(e.toSubgraph, gridPath[last], e.endDir)
+	}
+	return nil
+}
+
+func toGridCoords(path []gridCoord) []GridCoord {
+	coords := make([]GridCoord, len(path))
+	for i, c := range path {
+		coords[i] = GridCoord{X: c.x, Y: c.y}
+	}
+	return coords
+}
@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// diagonalPlacer places every node one rank and one position further than
+// the one before it.
+type diagonalPlacer struct{}
+
+func (diagonalPlacer) Place(g *LayoutGraph) (map[string]GridCoord, error) {
+	placed := map[string]GridCoord{}
+	for i, n := range g.Nodes {
+		placed[n.ID] = GridCoord{X: 4 * i, Y: 4 * i}
+	}
+	return placed, nil
+}
+
+// elbowRouter leaves every node at the bottom and arrives from the left.
+type elbowRouter struct{}
+
+func (elbowRouter) Route(grid *RoutingGrid, e LayoutEdge) ([]GridCoord, error) {
+	from, to := grid.Placed[e.From], grid.Placed[e.To]
+	return []GridCoord{{X: from.X + 1, Y: from.Y + 2}, {X: from.X + 1, Y: to.Y + 1}, {X: to.X, Y: to.Y + 1}}, nil
+}
+
+type diagonalRouter struct{}
+
+func (diagonalRouter) Route(grid *RoutingGrid, e LayoutEdge) ([]GridCoord, error) {
+	from, to := grid.Placed[e.From], grid.Placed[e.To]
+	return []GridCoord{{X: from.X + 2, Y: from.Y + 2}, {X: to.X, Y: to.Y}}, nil
+}
+
+type overlappingPlacer struct{}
+
+func (overlappingPlacer) Place(g *LayoutGraph) (map[string]GridCoord, error) {
+	placed := map[string]GridCoord{}
+	for i, n := range g.Nodes {
+		placed[n.ID] = GridCoord{X: i, Y: 0}
+	}
+	return placed, nil
+}
+
+func renderWithEngine(t *testing.T, mermaid, layout string) (string, error) {
+	t.Helper()
+	gd, err := Parse(mermaid)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	config := diagram.DefaultConfig()
+	config.UseAscii = true
+	config.Layout = layout
+	return Render(gd, config)
+}
+
+func TestRegisteredEngineLaysGraphOut(t *testing.T) {
+	RegisterEngine("test-diagonal", Engine{Placer: diagonalPlacer{}, Router: elbowRouter{}})
+
+	out, err := renderWithEngine(t, "graph LR\nA --> B", "test-diagonal")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"+---+          ",
+		"|   |          ",
+		"| A |          ",
+		"|   |          ",
+		"+---+          ",
+		"  |            ",
+		"  |            ",
+		"  |            ",
+		"  |            ",
+		"  |            ",
+		"  |       +---+",
+		"  |       |   |",
+		"  +------>| B |",
+		"          |   |",
+		"          +---+",
+	}, "\n")
+	if out != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", out, want)
+	}
+}
+
+// lineOf returns the index of the line of out that contains text.
+func lineOf(out, text string) int {
+	for i, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, text) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRegisteredEngineDefaultsToBuiltInParts(t *testing.T) {
+	RegisterEngine("test-diagonal-placer", Engine{Placer: diagonalPlacer{}})
+	RegisterEngine("test-elbow-router", Engine{Router: elbowRouter{}})
+
+	out, err := renderWithEngine(t, "graph LR\nA --> B", "test-diagonal-placer")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if lineOf(out, "| B |") <= lineOf(out, "| A |") || !strings.Contains(out, ">") {
+		t.Errorf("B should be below A, with the edge routed to it:\n%s", out)
+	}
+
+	out, err = renderWithEngine(t, "graph LR\nA --> B", "test-elbow-router")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(out, "+---+     +---+\n") {
+		t.Errorf("the grid placer should put B next to A:\n%s", out)
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	RegisterEngine("test-overlapping", Engine{Placer: overlappingPlacer{}})
+
+	_, err := renderWithEngine(t, "graph LR\nA --> B", "no-such-engine")
+	var configErr *diagram.ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "Layout" {
+		t.Errorf("unknown engine: error = %v, want a ConfigError for Layout", err)
+	}
+
+	if _, err := renderWithEngine(t, "graph LR\nA --> B", "test-overlapping"); err == nil || !strings.Contains(err.Error(), "on top of each other") {
+		t.Errorf("overlapping nodes: error = %v", err)
+	}
+
+	RegisterEngine("test-diagonal-router", Engine{Router: diagonalRouter{}})
+	if _, err := renderWithEngine(t, "graph LR\nA --> B", "test-diagonal-router"); err == nil || !strings.Contains(err.Error(), "doesn't go straight") {
+		t.Errorf("diagonal path: error = %v", err)
+	}
+}
@@ -1,7 +1,8 @@
-package cmd
+package graph
 
 import (
 	"errors"
+	"slices"
 
 	"github.com/elliotchance/orderedmap/v2"
 	log "github.com/sirupsen/logrus"
@@ -29,9 +30,43 @@ func (g graph) lineToDrawing(line []gridCoord) []drawingCoord {
 	return dc
 }
 
+// edgeDrawingLine is e's path on the drawing, with any end at a subgraph
+// moved onto the subgraph's frame.
+func (g graph) edgeDrawingLine(e *edge) []drawingCoord {
+	line := g.pathToDrawing(e)
+	if len(line) < 2 {
+		return line
+	}
+	last := len(line) - 1
+	if e.fromSubgraph != nil {
+		line[0] = frameCrossing(e.fromSubgraph, line[0], line[1])
+	}
+	if e.toSubgraph != nil {
+		line[last] = frameCrossing(e.toSubgraph, line[last], line[last-1])
+	}
+	return line
+}
+
+// frameCrossing moves c, the end of a line inside sg's frame, along the line
+// to where it crosses the frame on its way to next.
+func frameCrossing(sg *subgraph, c, next drawingCoord) drawingCoord {
+	switch {
+	case next.x > c.x:
+		c.x = sg.maxX
+	case next.x < c.x:
+		c.x = sg.minX
+	case next.y > c.y:
+		c.y = sg.maxY
+	case next.y < c.y:
+		c.y = sg.minY
+	}
+	return c
+}
+
 type graph struct {
 	nodes            []*node
 	edges            []*edge
+	invisibleEdges   []*edge
 	drawing          *drawing
 	grid             map[gridCoord]*node
 	edgeCounts       map[edgePair]int
@@ -47,6 +82,13 @@ type graph struct {
 	offsetX          int
 	offsetY          int
 	useAscii         bool
+	layout           string
+	compact          bool
+	// routedCells and subgraphLabelCells steer the router; see routing.go.
+	routedCells        map[gridCoord][]routedCell
+	subgraphLabelCells map[gridCoord]*subgraph
+	// labelLegend holds the edge labels that didn't fit on the drawing.
+	labelLegend []string
 }
 
 type edgePair struct {
@@ -62,11 +104,17 @@ func newEdgePair(from, to int) edgePair {
 }
 
 type subgraph struct {
-	name     string
-	label    graphLabel
-	nodes    []*node
-	parent   *subgraph
-	children []*subgraph
+	// id is how edges refer to the subgraph: the id before its [title], or
+	// the title itself when it has none.
+	id    string
+	name  string
+	label graphLabel
+	// direction is the subgraph's own layout direction (LR, RL, TD or BT),
+	// or empty to lay it out like the graph.
+	direction string
+	nodes     []*node
+	parent    *subgraph
+	children  []*subgraph
 	// Bounding box in drawing coordinates
 	minX int
 	minY int
@@ -89,7 +137,7 @@ func mkGraph(data *orderedmap.OrderedMap[string, []textEdge], nodeSpecs map[stri
 		// Get or create parent node
 		parentNode, err := g.getNode(nodeName)
 		if err != nil {
-			parentNode = &node{name: nodeName, label: spec.label, index: index, styleClassName: spec.styleClass}
+			parentNode = &node{name: nodeName, label: spec.label, shape: spec.shape, index: index, styleClassName: spec.styleClass}
 			g.appendNode(parentNode)
 			index += 1
 		}
@@ -97,7 +145,7 @@ func mkGraph(data *orderedmap.OrderedMap[string, []textEdge], nodeSpecs map[stri
 			childSpec := nodeSpecs[textEdge.child.name]
 			childNode, err := g.getNode(textEdge.child.name)
 			if err != nil {
-				childNode = &node{name: textEdge.child.name, label: childSpec.label, index: index, styleClassName: childSpec.styleClass}
+				childNode = &node{name: textEdge.child.name, label: childSpec.label, shape: childSpec.shape, index: index, styleClassName: childSpec.styleClass}
 				g.appendNode(childNode)
 				index += 1
 			}
@@ -105,11 +153,18 @@ func mkGraph(data *orderedmap.OrderedMap[string, []textEdge], nodeSpecs map[stri
 				from:            parentNode,
 				to:              childNode,
 				text:            textEdge.label,
+				label:           newGraphLabel(textEdge.label),
 				isBidirectional: textEdge.isBidirectional,
 				stroke:          textEdge.stroke,
 				head:            textEdge.head,
+				length:          textEdge.length,
+				index:           textEdge.index,
+			}
+			if e.stroke == strokeInvisible {
+				g.invisibleEdges = append(g.invisibleEdges, &e)
+			} else {
+				g.edges = append(g.edges, &e)
 			}
-			g.edges = append(g.edges, &e)
 		}
 	}
 	return g
@@ -128,6 +183,20 @@ func (g *graph) setStyleClasses(properties *graphProperties) {
 			log.Debugf("Setting style class for node %s to %s", n.name, n.styleClassName)
 			(*n).styleClass = g.styleClasses[n.styleClassName]
 		}
+		// A style statement overrides the node's class.
+		if styles, ok := properties.nodeStyles[n.name]; ok {
+			n.styleClass = styleClass{n.styleClassName, mergeStyles(n.styleClass.styles, styles)}
+		}
+	}
+	for _, e := range g.edges {
+		styles, ok := properties.linkStyles[e.index]
+		if !ok && properties.linkStyles[defaultLinkStyle] == nil {
+			continue
+		}
+		e.styles = mergeStyles(properties.linkStyles[defaultLinkStyle], styles)
+		if dash := e.styles["stroke-dasharray"]; dash != "" && dash != "0" && dash != "none" {
+			e.stroke = strokeDotted
+		}
 	}
 }
 
@@ -137,10 +206,12 @@ func (g *graph) setSubgraphs(textSubgraphs []*textSubgraph) {
 	// Convert textSubgraphs to subgraphs with node references
 	for _, tsg := range textSubgraphs {
 		sg := &subgraph{
-			name:     tsg.name,
-			label:    tsg.label,
-			nodes:    []*node{},
-			children: []*subgraph{},
+			id:        tsg.linkID(),
+			name:      tsg.name,
+			label:     tsg.label,
+			direction: tsg.direction,
+			nodes:     []*node{},
+			children:  []*subgraph{},
 		}
 
 		// Find and add node references
@@ -179,10 +250,137 @@ func (g *graph) setSubgraphs(textSubgraphs []*textSubgraph) {
 		}
 	}
 
+	g.connectSubgraphEdges()
 	log.Debugf("Set %d subgraphs", len(g.subgraphs))
 }
 
-func (g *graph) createMapping() {
+// connectSubgraphEdges points the edges that mkGraph attached to a node named
+// after a subgraph at that subgraph instead, and drops the node, which was
+// never meant to be drawn. For layout an edge into a subgraph still runs to
+// its first node and an edge out of it leaves from its last, so the subgraph
+// is ranked between the nodes it's linked to. An empty subgraph has nothing
+// to lay out against and stays a plain node.
+func (g *graph) connectSubgraphEdges() {
+	stand := map[*node]*subgraph{}
+	for _, sg := range g.subgraphs {
+		if n, err := g.getNode(sg.id); err == nil {
+			stand[n] = sg
+		}
+	}
+	isStandIn := func(n *node) bool {
+		_, ok := stand[n]
+		return ok
+	}
+	for n, sg := range stand {
+		if !slices.ContainsFunc(sg.nodes, func(m *node) bool { return !isStandIn(m) }) {
+			delete(stand, n)
+		}
+	}
+	if len(stand) == 0 {
+		return
+	}
+
+	for _, sg := range g.subgraphs {
+		sg.nodes = slices.DeleteFunc(sg.nodes, isStandIn)
+	}
+	for _, graphEdges := range [][]*edge{g.edges, g.invisibleEdges} {
+		for _, e := range graphEdges {
+			if sg, ok := stand[e.from]; ok {
+				e.fromSubgraph = sg
+				e.from = sg.nodes[len(sg.nodes)-1]
+			}
+			if sg, ok := stand[e.to]; ok {
+				e.toSubgraph = sg
+				e.to = sg.nodes[0]
+			}
+		}
+	}
+
+	// The subgraph's nodes take the place of its stand-in, so that nodes
+	// linked from the subgraph come after them when the graph is ranked.
+	nodes := []*node{}
+	seen := map[*node]bool{}
+	for _, n := range g.nodes {
+		members := []*node{n}
+		if sg, ok := stand[n]; ok {
+			members = sg.nodes
+		}
+		for _, m := range members {
+			if !seen[m] && !isStandIn(m) {
+				nodes = append(nodes, m)
+				seen[m] = true
+			}
+		}
+	}
+	g.nodes = nodes
+	for i, n := range g.nodes {
+		n.index = i
+	}
+}
+
+// createMapping lays g out with the layout engine it was configured with and
+// works out where everything goes on the drawing.
+func (g *graph) createMapping() error {
+	engine, err := lookupEngine(g.layout)
+	if err != nil {
+		return err
+	}
+	lg := g.layoutGraph()
+	if err := g.placeNodes(engine.Placer, lg); err != nil {
+		return err
+	}
+
+	for _, n := range g.nodes {
+		g.setColumnWidth(n)
+	}
+
+	if err := g.routeEdges(engine.Router, lg); err != nil {
+		return err
+	}
+	for _, e := range g.edges {
+		g.increaseGridSizeForPath(e.path)
+		g.makeRoomForSelfLoop(e)
+		g.makeRoomBesideFrames(e)
+	}
+	if g.compact {
+		g.compactGrid()
+	}
+	for _, e := range g.edges {
+		g.determineLabelLine(e)
+	}
+	g.assignLanes()
+
+	// ! Last point before we manipulate the drawing !
+	log.Debug("Mapping complete, starting to draw")
+
+	for _, n := range g.nodes {
+		// A node is drawn from its top-left corner, which in a mirrored
+		// layout is the opposite corner of its grid cell.
+		corner := *n.gridCoord
+		if g.graphDirection == "RL" {
+			corner.x += 2
+		}
+		if g.graphDirection == "BT" {
+			corner.y += 2
+		}
+		dc := g.gridToDrawingCoord(corner, nil)
+		g.nodes[n.index].setCoord(&dc)
+		g.nodes[n.index].setDrawing(*g)
+	}
+
+	// Calculate subgraph bounding boxes after nodes are positioned
+	g.calculateSubgraphBoundingBoxes()
+
+	// Offset everything if subgraphs have negative coordinates
+	g.offsetDrawingForSubgraphs()
+	g.setDrawingSizeToGridConstraints()
+	return nil
+}
+
+// placeNodesOnGrid sets the grid coord of every node rank by rank: roots
+// first, then each node's children on the next free spot of their rank, in
+// the order they're found.
+func (g *graph) placeNodesOnGrid() {
 	// Set mapping coord for every node in the graph.
 	// Keyed by level so it grows with the graph instead of assuming a fixed
 	// number of levels; a missing key reads as the zero value.
@@ -219,7 +417,7 @@ func (g *graph) createMapping() {
 
 	// Separate root nodes by whether they're in subgraphs, but only if we have both types
 	// AND there are edges in subgraphs (indicating intentional layout structure)
-	shouldSeparate := g.graphDirection == "LR" && hasExternalRoots && hasSubgraphRootsWithEdges
+	shouldSeparate := g.isHorizontal() && hasExternalRoots && hasSubgraphRootsWithEdges
 
 	externalRootNodes := []*node{}
 	subgraphRootNodes := []*node{}
@@ -239,7 +437,7 @@ func (g *graph) createMapping() {
 	// Place external root nodes first at level 0
 	for _, n := range externalRootNodes {
 		var mappingCoord *gridCoord
-		if g.graphDirection == "LR" {
+		if g.isHorizontal() {
 			mappingCoord = g.reserveSpotInGrid(g.nodes[n.index], &gridCoord{x: 0, y: highestPositionPerLevel[0]})
 		} else {
 			mappingCoord = g.reserveSpotInGrid(g.nodes[n.index], &gridCoord{x: highestPositionPerLevel[0], y: 0})
@@ -247,6 +445,7 @@ func (g *graph) createMapping() {
 		log.Debugf("Setting mapping coord for external rootnode %s to %v", n.name, mappingCoord)
 		g.nodes[n.index].gridCoord = mappingCoord
 		highestPositionPerLevel[0] = highestPositionPerLevel[0] + 4
+		g.placeAcrossRanks(n, highestPositionPerLevel)
 	}
 
 	// Place subgraph root nodes at level 4 (one level to the right/down of external nodes)
@@ -255,7 +454,7 @@ func (g *graph) createMapping() {
 		subgraphLevel := 4
 		for _, n := range subgraphRootNodes {
 			var mappingCoord *gridCoord
-			if g.graphDirection == "LR" {
+			if g.isHorizontal() {
 				mappingCoord = g.reserveSpotInGrid(g.nodes[n.index], &gridCoord{x: subgraphLevel, y: highestPositionPerLevel[subgraphLevel]})
 			} else {
 				mappingCoord = g.reserveSpotInGrid(g.nodes[n.index], &gridCoord{x: highestPositionPerLevel[subgraphLevel], y: subgraphLevel})
@@ -263,27 +462,39 @@ func (g *graph) createMapping() {
 			log.Debugf("Setting mapping coord for subgraph rootnode %s to %v", n.name, mappingCoord)
 			g.nodes[n.index].gridCoord = mappingCoord
 			highestPositionPerLevel[subgraphLevel] = highestPositionPerLevel[subgraphLevel] + 4
+			g.placeAcrossRanks(n, highestPositionPerLevel)
 		}
 	}
 
 	for _, n := range g.nodes {
 		log.Debugf("Creating mapping for node %s at %v", n.name, n.gridCoord)
-		var childLevel int
-		// Next column is 4 coords further. This is because every node is 3 coords wide + 1 coord inbetween.
-		if g.graphDirection == "LR" {
-			childLevel = n.gridCoord.x + 4
-		} else {
-			childLevel = n.gridCoord.y + 4
+		level := n.gridCoord.y
+		if g.isHorizontal() {
+			level = n.gridCoord.x
 		}
-		highestPosition := highestPositionPerLevel[childLevel]
-		for _, child := range g.getChildren(n) {
+		// The highest position of each level before this node's children
+		// were placed on it, so children on the same level line up.
+		startPositions := map[int]int{}
+		for _, e := range g.getEdgesFromNode(n) {
+			child := e.to
 			// Skip if the child already has a mapping coord
 			if child.gridCoord != nil {
 				continue
 			}
+			if g.isCrossRankEdge(n, child) {
+				continue
+			}
+			// Next column is 4 coords further. This is because every node is 3 coords wide + 1 coord inbetween.
+			// Longer links (--->) go another level further for every extra rank.
+			childLevel := level + 4*max(e.length, 1)
+			highestPosition, ok := startPositions[childLevel]
+			if !ok {
+				highestPosition = highestPositionPerLevel[childLevel]
+				startPositions[childLevel] = highestPosition
+			}
 
 			var mappingCoord *gridCoord
-			if g.graphDirection == "LR" {
+			if g.isHorizontal() {
 				mappingCoord = g.reserveSpotInGrid(g.nodes[child.index], &gridCoord{x: childLevel, y: highestPosition})
 			} else {
 				mappingCoord = g.reserveSpotInGrid(g.nodes[child.index], &gridCoord{x: highestPosition, y: childLevel})
@@ -291,34 +502,152 @@ func (g *graph) createMapping() {
 			log.Debugf("Setting mapping coord for child %s of parent %s to %v", child.name, n.name, mappingCoord)
 			g.nodes[child.index].gridCoord = mappingCoord
 			highestPositionPerLevel[childLevel] = highestPosition + 4
+			g.placeAcrossRanks(child, highestPositionPerLevel)
 		}
 	}
 
-	for _, n := range g.nodes {
-		g.setColumnWidth(n)
+	g.mirrorSubgraphLayouts()
+}
+
+// layoutSubgraph is the innermost subgraph holding both from and to that
+// sets a direction for them, itself or through one of its parents, or nil
+// when the graph's direction applies.
+func (g *graph) layoutSubgraph(from, to *node) *subgraph {
+	var innermost *subgraph
+	for _, sg := range g.subgraphs {
+		if slices.Contains(sg.nodes, from) && slices.Contains(sg.nodes, to) &&
+			(innermost == nil || g.getSubgraphDepth(sg) > g.getSubgraphDepth(innermost)) {
+			innermost = sg
+		}
+	}
+	for sg := innermost; sg != nil; sg = sg.parent {
+		if sg.direction != "" {
+			return sg
+		}
 	}
+	return nil
+}
 
-	for _, e := range g.edges {
-		g.determinePath(e)
-		g.increaseGridSizeForPath(e.path)
-		g.determineLabelLine(e)
+// isCrossRankEdge reports whether the edge from from to to is inside a
+// subgraph whose direction runs across the graph's, like an LR subgraph in a
+// TD graph.
+func (g *graph) isCrossRankEdge(from, to *node) bool {
+	sg := g.layoutSubgraph(from, to)
+	if sg == nil {
+		return false
 	}
+	horizontal := sg.direction == "LR" || sg.direction == "RL"
+	return horizontal != g.isHorizontal()
+}
 
-	// ! Last point before we manipulate the drawing !
-	log.Debug("Mapping complete, starting to draw")
+// placeAcrossRanks places the children of n that it links to inside a
+// subgraph laid out across the graph's ranks, and theirs in turn. They stay
+// on n's rank and step along it instead, moving on to the next rank when a
+// spot is taken. Children are placed as soon as their parent is, so the
+// spots next to it are still free.
+func (g *graph) placeAcrossRanks(n *node, highestPositionPerLevel map[int]int) {
+	for _, e := range g.getEdgesFromNode(n) {
+		child := e.to
+		if child.gridCoord != nil || !g.isCrossRankEdge(n, child) {
+			continue
+		}
+		coord := gridCoord{x: n.gridCoord.x + 4*max(e.length, 1), y: n.gridCoord.y}
+		if g.isHorizontal() {
+			coord = gridCoord{x: n.gridCoord.x, y: n.gridCoord.y + 4*max(e.length, 1)}
+		}
+		mappingCoord := g.reserveSpotAlong(g.nodes[child.index], &coord, !g.isHorizontal())
+		log.Debugf("Setting mapping coord for child %s of parent %s across ranks to %v", child.name, n.name, mappingCoord)
+		g.nodes[child.index].gridCoord = mappingCoord
+
+		// Nodes placed on this rank later go after the child.
+		level, position := mappingCoord.y, mappingCoord.x
+		if g.isHorizontal() {
+			level, position = mappingCoord.x, mappingCoord.y
+		}
+		highestPositionPerLevel[level] = Max(highestPositionPerLevel[level], position+4)
+		g.placeAcrossRanks(child, highestPositionPerLevel)
+	}
+}
 
-	for _, n := range g.nodes {
-		dc := g.gridToDrawingCoord(*n.gridCoord, nil)
-		g.nodes[n.index].setCoord(&dc)
-		g.nodes[n.index].setDrawing(*g)
+// mirrorSubgraphLayouts turns subgraphs around that are laid out against
+// their direction. Subgraphs are laid out left to right or top to bottom on
+// the grid, and the whole graph is mirrored when it's drawn RL or BT; a
+// subgraph that should run the other way than it ends up is mirrored within
+// the area its nodes take up.
+func (g *graph) mirrorSubgraphLayouts() {
+	for _, sg := range g.subgraphs {
+		if sg.parent == nil {
+			g.mirrorSubgraphLayout(sg, g.graphDirection == "RL", g.graphDirection == "BT")
+		}
 	}
-	g.setDrawingSizeToGridConstraints()
+}
 
-	// Calculate subgraph bounding boxes after nodes are positioned
-	g.calculateSubgraphBoundingBoxes()
+// mirrorSubgraphLayout mirrors sg if needed and goes on with its children.
+// mirroredX and mirroredY say whether sg's nodes are drawn mirrored already,
+// by the graph's direction or by a parent subgraph.
+func (g *graph) mirrorSubgraphLayout(sg *subgraph, mirroredX, mirroredY bool) {
+	switch sg.direction {
+	case "LR", "RL":
+		if (sg.direction == "RL") != mirroredX && g.mirrorNodes(sg.nodes, true) {
+			mirroredX = !mirroredX
+		}
+	case "TD", "BT":
+		if (sg.direction == "BT") != mirroredY && g.mirrorNodes(sg.nodes, false) {
+			mirroredY = !mirroredY
+		}
+	}
+	for _, child := range sg.children {
+		g.mirrorSubgraphLayout(child, mirroredX, mirroredY)
+	}
+}
 
-	// Offset everything if subgraphs have negative coordinates
-	g.offsetDrawingForSubgraphs()
+// mirrorNodes mirrors the positions of nodes on the grid, horizontally or
+// vertically, within the area they take up. Nodes are only moved if none
+// lands on a node that isn't being mirrored with them; mirrorNodes reports
+// whether they were.
+func (g *graph) mirrorNodes(nodes []*node, horizontal bool) bool {
+	if len(nodes) < 2 {
+		return false
+	}
+	low, high := 1000000, -1000000
+	for _, n := range nodes {
+		c := n.gridCoord.y
+		if horizontal {
+			c = n.gridCoord.x
+		}
+		low, high = Min(low, c), Max(high, c)
+	}
+	mirrored := map[*node]gridCoord{}
+	for _, n := range nodes {
+		c := *n.gridCoord
+		if horizontal {
+			c.x = low + high - c.x
+		} else {
+			c.y = low + high - c.y
+		}
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 3; y++ {
+				if other := g.grid[gridCoord{x: c.x + x, y: c.y + y}]; other != nil && !slices.Contains(nodes, other) {
+					log.Debugf("Not mirroring subgraph nodes: %s would land on %s", n.name, other.name)
+					return false
+				}
+			}
+		}
+		mirrored[n] = c
+	}
+
+	for _, n := range nodes {
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 3; y++ {
+				delete(g.grid, gridCoord{x: n.gridCoord.x + x, y: n.gridCoord.y + y})
+			}
+		}
+	}
+	for _, n := range nodes {
+		c := mirrored[n]
+		g.reserveSpotInGrid(n, &c)
+	}
+	return true
 }
 
 func (g *graph) calculateSubgraphBoundingBoxes() {
@@ -456,6 +785,9 @@ func (g *graph) ensureSubgraphSpacing() {
 	}
 }
 
+// subgraphPadding is the space between a subgraph's frame and the nodes in it.
+const subgraphPadding = 2
+
 func (g *graph) calculateSubgraphBoundingBox(sg *subgraph) {
 	if len(sg.nodes) == 0 {
 		return
@@ -496,6 +828,20 @@ func (g *graph) calculateSubgraphBoundingBox(sg *subgraph) {
 		maxY = Max(maxY, nodeMaxY)
 	}
 
+	// A self-loop goes around the corner of its node, through the space
+	// next to it, which the frame has to take in as well.
+	for _, e := range g.edges {
+		if e.from != e.to || !slices.Contains(sg.nodes, e.from) {
+			continue
+		}
+		for _, c := range g.pathToDrawing(e) {
+			minX = Min(minX, c.x)
+			minY = Min(minY, c.y)
+			maxX = Max(maxX, c.x)
+			maxY = Max(maxY, c.y)
+		}
+	}
+
 	// Ensure the title fits inside the frame after padding is applied.
 	currentWidth := maxX - minX
 	currentInnerWidth := currentWidth + 3
@@ -506,7 +852,6 @@ func (g *graph) calculateSubgraphBoundingBox(sg *subgraph) {
 	}
 
 	// Add padding (allow negative coordinates, we'll offset later)
-	const subgraphPadding = 2
 	subgraphLabelSpace := sg.label.contentHeight() + 1
 	sg.minX = minX - subgraphPadding
 	sg.minY = minY - subgraphPadding - subgraphLabelSpace
@@ -565,23 +910,27 @@ func (g *graph) draw() *drawing {
 	g.drawSubgraphs()
 
 	// Draw all nodes.
+	colors := map[drawingCoord]cellColor{}
 	for _, node := range g.nodes {
 		if !node.drawn {
 			g.drawNode(node)
 		}
+		g.setNodeColors(node, colors)
 	}
 	lineDrawings := []*drawing{}
 	cornerDrawings := []*drawing{}
 	arrowHeadDrawings := []*drawing{}
 	boxStartDrawings := []*drawing{}
-	labelDrawings := []*drawing{}
+	edgeLines := map[*edge]*drawing{}
 	for _, edge := range g.edges {
-		line, boxStart, arrowHead, corners, label := g.drawEdge(edge)
+		line, boxStart, arrowHead, corners := g.drawEdge(edge)
 		lineDrawings = append(lineDrawings, line)
 		cornerDrawings = append(cornerDrawings, corners)
 		arrowHeadDrawings = append(arrowHeadDrawings, arrowHead)
 		boxStartDrawings = append(boxStartDrawings, boxStart)
-		labelDrawings = append(labelDrawings, label)
+		if line != nil {
+			edgeLines[edge] = g.mergeDrawings(line, drawingCoord{0, 0}, corners)
+		}
 	}
 
 	// Draw in order
@@ -589,11 +938,21 @@ func (g *graph) draw() *drawing {
 	g.drawing = g.mergeDrawings(g.drawing, drawingCoord{0, 0}, cornerDrawings...)
 	g.drawing = g.mergeDrawings(g.drawing, drawingCoord{0, 0}, arrowHeadDrawings...)
 	g.drawing = g.mergeDrawings(g.drawing, drawingCoord{0, 0}, boxStartDrawings...)
-	g.drawing = g.mergeDrawings(g.drawing, drawingCoord{0, 0}, labelDrawings...)
 
-	// Draw subgraph labels LAST so they don't get overwritten by arrows
+	// Draw subgraph labels after the arrows so they don't get overwritten
 	g.drawSubgraphLabels()
 
+	// Edge labels go last, on whatever room is left
+	labelDrawings := g.placeEdgeLabels(edgeLines)
+	for i, edge := range g.edges {
+		if edge.styles != nil {
+			setEdgeColors(edge, colors, labelDrawings[i], lineDrawings[i], cornerDrawings[i], arrowHeadDrawings[i])
+		}
+	}
+	g.drawEdgeLabels()
+	g.drawLabelLegend()
+
+	g.colorDrawing(g.drawing, colors)
 	return g.drawing
 }
 
@@ -665,11 +1024,15 @@ func (g *graph) appendNode(n *node) {
 	g.nodes = append(g.nodes, n)
 }
 
+// getEdgesFromNode returns the edges leaving n, including invisible ones:
+// they're laid out like any other edge.
 func (g graph) getEdgesFromNode(n *node) []edge {
 	edges := []edge{}
-	for _, edge := range g.edges {
-		if (edge.from.name) == (n.name) {
-			edges = append(edges, *edge)
+	for _, graphEdges := range [][]*edge{g.edges, g.invisibleEdges} {
+		for _, edge := range graphEdges {
+			if (edge.from.name) == (n.name) {
+				edges = append(edges, *edge)
+			}
 		}
 	}
 	return edges
@@ -701,7 +1064,25 @@ func (g *graph) gridToDrawingCoord(c gridCoord, dir *direction) drawingCoord {
 	for row := 0; row < target.y; row++ {
 		y += g.rowHeight[row]
 	}
-	dc := drawingCoord{x: x + g.columnWidth[target.x]/2 + g.offsetX, y: y + g.rowHeight[target.y]/2 + g.offsetY}
+	x += g.columnWidth[target.x] / 2
+	y += g.rowHeight[target.y] / 2
+	// RL and BT graphs are laid out on the grid as LR and TD; mirror them
+	// here so the first rank ends up on the right or at the bottom.
+	if g.graphDirection == "RL" {
+		x = sumValues(g.columnWidth) - 1 - x
+	}
+	if g.graphDirection == "BT" {
+		y = sumValues(g.rowHeight) - 1 - y
+	}
+	dc := drawingCoord{x: x + g.offsetX, y: y + g.offsetY}
 
 	return dc
 }
+
+func sumValues(m map[int]int) int {
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,282 @@
+package graph
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// graphTestDataPath returns the absolute path to a cmd/testdata subdirectory,
+// resolved from this file's location so tests work from any working directory.
+func graphTestDataPath(subdir string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "..", "..", "cmd", "testdata", subdir)
+}
+
+func verifyMap(t *testing.T, testCaseFile string, useAscii bool, layout string, compact bool) {
+	tc, err := testutil.ReadTestCase(testCaseFile)
+	if err != nil {
+		t.Fatalf("Failed to read test case file: %v", err)
+	}
+
+	properties, err := mermaidFileToMap(tc.Mermaid, "cli")
+	if err != nil {
+		log.Fatal("Failed to parse mermaid: ", err)
+	}
+	properties.paddingX = tc.PaddingX
+	properties.paddingY = tc.PaddingY
+	properties.useAscii = useAscii
+	properties.layout = layout
+	properties.compact = compact
+	actualMap, err := drawMap(properties)
+	if err != nil {
+		t.Fatalf("drawMap() error = %v", err)
+	}
+	if tc.Expected != actualMap {
+		expectedWithSpaces := testutil.VisualizeWhitespace(tc.Expected)
+		actualWithSpaces := testutil.VisualizeWhitespace(actualMap)
+		t.Errorf("Map didn't match actual map\nExpected:\n%v\nActual:\n%v", expectedWithSpaces, actualWithSpaces)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	dir := graphTestDataPath("ascii")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
+			t.Run(file.Name(), func(t *testing.T) {
+				verifyMap(t, filepath.Join(dir, file.Name()), true, "grid", false)
+			})
+		}
+	}
+}
+
+func TestExtendedChars(t *testing.T) {
+	dir := graphTestDataPath("extended-chars")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
+			t.Run(file.Name(), func(t *testing.T) {
+				verifyMap(t, filepath.Join(dir, file.Name()), false, "grid", false)
+			})
+		}
+	}
+}
+
+// TestLayered renders the cases in cmd/testdata/layered with the layered
+// layout instead of the grid layout.
+func TestLayered(t *testing.T) {
+	dir := graphTestDataPath("layered")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
+			t.Run(file.Name(), func(t *testing.T) {
+				verifyMap(t, filepath.Join(dir, file.Name()), true, "layered", false)
+			})
+		}
+	}
+}
+
+// TestCompact renders the cases in cmd/testdata/compact with the grid
+// compacted.
+func TestCompact(t *testing.T) {
+	dir := graphTestDataPath("compact")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
+			t.Run(file.Name(), func(t *testing.T) {
+				verifyMap(t, filepath.Join(dir, file.Name()), true, "grid", true)
+			})
+		}
+	}
+}
+
+// TestMultibyte verifies that multibyte UTF-8 labels (Cyrillic, Greek, accented
+// Latin, etc.) render correctly without splitting runes into invalid byte
+// fragments. Test cases are loaded from cmd/testdata/multibyte/*.txt.
+func TestMultibyte(t *testing.T) {
+	dir := graphTestDataPath("multibyte")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory %s: %v", dir, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".txt") {
+			t.Run(file.Name(), func(t *testing.T) {
+				verifyMap(t, filepath.Join(dir, file.Name()), true, "grid", false)
+			})
+		}
+	}
+}
+
+// TestGraphUseAsciiConfig tests that Render respects config.UseAscii
+func TestGraphUseAsciiConfig(t *testing.T) {
+	gd, err := Parse(`graph LR
+A --> B`)
+	if err != nil {
+		t.Fatalf("Failed to parse graph: %v", err)
+	}
+
+	// Test with UseAscii = true (should produce ASCII output)
+	asciiConfig := &diagram.Config{
+		UseAscii:         true,
+		BoxBorderPadding: 1,
+		PaddingBetweenX:  5,
+		PaddingBetweenY:  5,
+		GraphDirection:   "LR",
+		StyleType:        "cli",
+	}
+	asciiOutput, err := Render(gd, asciiConfig)
+	if err != nil {
+		t.Fatalf("Failed to render with ASCII config: %v", err)
+	}
+
+	// Test with UseAscii = false (should produce Unicode output)
+	unicodeConfig := &diagram.Config{
+		UseAscii:         false,
+		BoxBorderPadding: 1,
+		PaddingBetweenX:  5,
+		PaddingBetweenY:  5,
+		GraphDirection:   "LR",
+		StyleType:        "cli",
+	}
+	unicodeOutput, err := Render(gd, unicodeConfig)
+	if err != nil {
+		t.Fatalf("Failed to render with Unicode config: %v", err)
+	}
+
+	// Verify outputs are different
+	if asciiOutput == unicodeOutput {
+		t.Errorf("ASCII and Unicode outputs should be different, but they were identical:\n%s", asciiOutput)
+	}
+
+	// Verify ASCII output contains ASCII characters (not Unicode box-drawing)
+	if strings.Contains(asciiOutput, "┌") || strings.Contains(asciiOutput, "─") || strings.Contains(asciiOutput, "│") {
+		t.Errorf("ASCII output should not contain Unicode box-drawing characters, but found:\n%s", asciiOutput)
+	}
+
+	// Verify Unicode output contains Unicode characters
+	if !strings.Contains(unicodeOutput, "┌") && !strings.Contains(unicodeOutput, "─") && !strings.Contains(unicodeOutput, "│") {
+		t.Errorf("Unicode output should contain Unicode box-drawing characters, but found:\n%s", unicodeOutput)
+	}
+}
+
+// TestRenderSharesParsedDiagram renders one parsed diagram with different
+// configs from several goroutines; run with -race to catch writes to it.
+func TestRenderSharesParsedDiagram(t *testing.T) {
+	gd, err := Parse("graph LR\nsubgraph one\nA -->|label| B\nend\nB --> C{Choice}")
+	if err != nil {
+		t.Fatalf("Failed to parse graph: %v", err)
+	}
+	configs := []*diagram.Config{diagram.DefaultConfig(), diagram.NewTestConfig(true, "html")}
+	want := make([]string, len(configs))
+	for i, config := range configs {
+		if want[i], err = Render(gd, config); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		for i, config := range configs {
+			wg.Add(1)
+			go func(i int, config *diagram.Config) {
+				defer wg.Done()
+				if got, err := Render(gd, config); err != nil || got != want[i] {
+					t.Errorf("concurrent Render() = %q, %v; want %q", got, err, want[i])
+				}
+			}(i, config)
+		}
+	}
+	wg.Wait()
+}
+
+// TestEdgesToSubgraphs verifies that an edge endpoint named after a subgraph
+// refers to the subgraph instead of creating a node of its own.
+func TestEdgesToSubgraphs(t *testing.T) {
+	properties, err := mermaidFileToMap("graph LR\nA --> one\nsubgraph one\nB --> C\nend\nsubgraph two [Second]\nD\nend\none --> two", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+	g, err := mapGraph(properties)
+	if err != nil {
+		t.Fatalf("mapGraph() error = %v", err)
+	}
+
+	names := []string{}
+	for i, n := range g.nodes {
+		names = append(names, n.name)
+		if n.index != i {
+			t.Errorf("node %s has index %d, want %d", n.name, n.index, i)
+		}
+	}
+	if strings.Join(names, ",") != "A,B,C,D" {
+		t.Fatalf("nodes = %v, want A,B,C,D", names)
+	}
+
+	ends := []string{}
+	for _, e := range g.edges {
+		from, to := e.from.name, e.to.name
+		if e.fromSubgraph != nil {
+			from = e.fromSubgraph.id
+		}
+		if e.toSubgraph != nil {
+			to = e.toSubgraph.id
+		}
+		ends = append(ends, from+"->"+to)
+	}
+	if strings.Join(ends, " ") != "A->one one->two B->C" {
+		t.Errorf("edges = %v, want A->one one->two B->C", ends)
+	}
+	// For layout, edges run between the subgraphs' first and last nodes.
+	if e := g.edges[1]; e.from.name != "C" || e.to.name != "D" {
+		t.Errorf("one->two is laid out from %s to %s, want C to D", e.from.name, e.to.name)
+	}
+}
+
+func TestLinksIntoOwnSubgraphAreRejected(t *testing.T) {
+	for _, tc := range []struct {
+		name, input string
+		line        int
+	}{
+		{"subgraph to its node", "graph TD\nsubgraph S\nA --> B\nend\nS --> A", 5},
+		{"node to its subgraph", "graph TD\nsubgraph S\nA --> B\nend\nB --> S", 5},
+		{"subgraph to nested subgraph", "graph TD\nsubgraph T\nsubgraph S\nA\nend\nB\nend\nT --> S", 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := mermaidFileToMap(tc.input, "cli")
+			var lineErr *diagram.LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("mermaidFileToMap() error = %v, want a *diagram.LineError", err)
+			}
+			if lineErr.Line != tc.line {
+				t.Errorf("error is at line %d, want %d: %v", lineErr.Line, tc.line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,528 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The layered layout places nodes the way Sugiyama-style layouts do: it
+// breaks cycles, puts every node on a rank, orders each rank to keep edges
+// from crossing and then picks where along its rank every node goes. Unlike
+// the grid layout, the result doesn't depend on the order nodes are written
+// in, which pays off for larger graphs. Subgraph directions aren't followed,
+// so graphs with a subgraph that sets one of its own are placed on the grid
+// instead; see layeredPlacer.
+
+// crossingSweeps is how many times the ranks are reordered, alternating
+// between downward and upward sweeps, before the best order found is kept.
+const crossingSweeps = 24
+
+// hasSubgraphDirection reports whether a subgraph of g is laid out along
+// another direction than the graph.
+func (g *graph) hasSubgraphDirection() bool {
+	for _, sg := range g.subgraphs {
+		if sg.direction != "" && sg.direction != g.graphDirection {
+			return true
+		}
+	}
+	return false
+}
+
+// layeredNode is a node of the layered layout: one of the graph's nodes, or a
+// dummy standing in for an edge on a rank it passes without stopping.
+type layeredNode struct {
+	n *node // nil for dummies
+	// chain holds the subgraphs the node is in, outermost first. A dummy is
+	// in the subgraphs both ends of its edge are in.
+	chain []*subgraph
+	rank  int
+	order int // index within its rank
+	slot  int // position along its rank, 4 grid coords apart
+	in    []*layeredNode
+	out   []*layeredNode
+}
+
+// layeredEdge is an edge of the layered layout, pointing down the ranks.
+type layeredEdge struct {
+	from   *layeredNode
+	to     *layeredNode
+	minLen int
+}
+
+// placeNodesLayered sets the grid coord of every node with the layered
+// layout.
+func (g *graph) placeNodesLayered() {
+	nodes := make([]*layeredNode, len(g.nodes))
+	for i, n := range g.nodes {
+		nodes[i] = &layeredNode{n: n, chain: g.subgraphChain(n)}
+	}
+
+	edges := g.acyclicEdges(nodes)
+	discovered := rankNodes(nodes, edges)
+	layers := addDummies(discovered, edges)
+	orderLayers(layers)
+	assignSlots(layers)
+	separateSubgraphs(layers, g.subgraphs)
+
+	// Dummies only hold a place for their edge, so the first node starts
+	// the grid.
+	minSlot := math.MaxInt
+	for _, layer := range layers {
+		for _, ln := range layer {
+			if ln.n != nil {
+				minSlot = min(minSlot, ln.slot)
+			}
+		}
+	}
+	for _, layer := range layers {
+		for _, ln := range layer {
+			if ln.n == nil {
+				continue
+			}
+			level, position := 4*ln.rank, 4*(ln.slot-minSlot)
+			coord := &gridCoord{x: position, y: level}
+			if g.isHorizontal() {
+				coord = &gridCoord{x: level, y: position}
+			}
+			ln.n.gridCoord = g.reserveSpotInGrid(ln.n, coord)
+			log.Debugf("Setting layered mapping coord for %s to %v", ln.n.name, ln.n.gridCoord)
+		}
+	}
+}
+
+// subgraphChain returns the subgraphs n is in, outermost first.
+func (g *graph) subgraphChain(n *node) []*subgraph {
+	var innermost *subgraph
+	for _, sg := range g.subgraphs {
+		for _, member := range sg.nodes {
+			if member == n && (innermost == nil || g.getSubgraphDepth(sg) > g.getSubgraphDepth(innermost)) {
+				innermost = sg
+			}
+		}
+	}
+	chain := []*subgraph{}
+	for sg := innermost; sg != nil; sg = sg.parent {
+		chain = append([]*subgraph{sg}, chain...)
+	}
+	return chain
+}
+
+// acyclicEdges turns the graph's edges into edges between nodes, turning
+// around the ones that close a cycle so that every edge points down. Cycles
+// are found depth first, starting from the nodes in the order they were
+// written. Self-loops don't take part in the layout.
+func (g *graph) acyclicEdges(nodes []*layeredNode) []*layeredEdge {
+	outgoing := map[*layeredNode][]*edge{}
+	for _, graphEdges := range [][]*edge{g.edges, g.invisibleEdges} {
+		for _, e := range graphEdges {
+			if e.from != e.to {
+				outgoing[nodes[e.from.index]] = append(outgoing[nodes[e.from.index]], e)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[*layeredNode]int{}
+	edges := []*layeredEdge{}
+	var visit func(ln *layeredNode)
+	visit = func(ln *layeredNode) {
+		state[ln] = visiting
+		for _, e := range outgoing[ln] {
+			to := nodes[e.to.index]
+			le := &layeredEdge{from: ln, to: to, minLen: max(e.length, 1)}
+			if state[to] == visiting {
+				le.from, le.to = to, ln
+			}
+			edges = append(edges, le)
+			if state[to] == unvisited {
+				visit(to)
+			}
+		}
+		state[ln] = visited
+	}
+	for _, ln := range nodes {
+		if state[ln] == unvisited {
+			visit(ln)
+		}
+	}
+	return edges
+}
+
+// rankNodes puts every node on a rank. Each node starts out on the lowest rank
+// its incoming edges allow (the longest path to it), after which nodes with
+// more edges going out than coming in move down towards their children, which
+// shortens edges the way network simplex would. It returns the nodes in the
+// order they're first reached from the top, which is where ordering the ranks
+// starts from.
+func rankNodes(nodes []*layeredNode, edges []*layeredEdge) []*layeredNode {
+	in := map[*layeredNode][]*layeredEdge{}
+	out := map[*layeredNode][]*layeredEdge{}
+	for _, e := range edges {
+		in[e.to] = append(in[e.to], e)
+		out[e.from] = append(out[e.from], e)
+	}
+
+	// Reverse postorder of a depth-first walk is a topological order.
+	seen := map[*layeredNode]bool{}
+	postorder := []*layeredNode{}
+	discovered := []*layeredNode{}
+	var visit func(ln *layeredNode)
+	visit = func(ln *layeredNode) {
+		seen[ln] = true
+		discovered = append(discovered, ln)
+		for _, e := range out[ln] {
+			if !seen[e.to] {
+				visit(e.to)
+			}
+		}
+		postorder = append(postorder, ln)
+	}
+	for _, ln := range nodes {
+		if len(in[ln]) == 0 && !seen[ln] {
+			visit(ln)
+		}
+	}
+
+	for i := len(postorder) - 1; i >= 0; i-- {
+		ln := postorder[i]
+		for _, e := range in[ln] {
+			ln.rank = max(ln.rank, e.from.rank+e.minLen)
+		}
+	}
+	for _, ln := range postorder {
+		if len(out[ln]) <= len(in[ln]) {
+			continue
+		}
+		lowest := -1
+		for _, e := range out[ln] {
+			if lowest == -1 || e.to.rank-e.minLen < lowest {
+				lowest = e.to.rank - e.minLen
+			}
+		}
+		ln.rank = max(ln.rank, lowest)
+	}
+	return discovered
+}
+
+// addDummies breaks every edge spanning more than one rank into a chain of
+// edges through dummy nodes, one on every rank it passes, and returns the
+// nodes by rank.
+func addDummies(nodes []*layeredNode, edges []*layeredEdge) [][]*layeredNode {
+	layers := [][]*layeredNode{}
+	addToLayer := func(ln *layeredNode) {
+		for len(layers) <= ln.rank {
+			layers = append(layers, []*layeredNode{})
+		}
+		ln.order = len(layers[ln.rank])
+		layers[ln.rank] = append(layers[ln.rank], ln)
+	}
+	for _, ln := range nodes {
+		addToLayer(ln)
+	}
+
+	for _, e := range edges {
+		from := e.from
+		for rank := e.from.rank + 1; rank < e.to.rank; rank++ {
+			dummy := &layeredNode{rank: rank, chain: commonChain(e.from.chain, e.to.chain)}
+			addToLayer(dummy)
+			from.out = append(from.out, dummy)
+			dummy.in = append(dummy.in, from)
+			from = dummy
+		}
+		from.out = append(from.out, e.to)
+		e.to.in = append(e.to.in, from)
+	}
+	return layers
+}
+
+// commonChain returns the subgraphs that both a and b are in, outermost
+// first.
+func commonChain(a, b []*subgraph) []*subgraph {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// orderLayers orders every rank to keep edges from crossing, sorting each
+// rank by where its nodes' neighbours on the rank before (sweeping down) or
+// after (sweeping up) it are. The order with the fewest crossings is kept.
+func orderLayers(layers [][]*layeredNode) {
+	best := cloneLayers(layers)
+	bestCrossings := countCrossings(layers)
+	for sweep := 0; sweep < crossingSweeps && bestCrossings > 0; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r < len(layers); r++ {
+				layers[r] = sortLayer(layers[r], func(ln *layeredNode) []*layeredNode { return ln.in })
+			}
+		} else {
+			for r := len(layers) - 2; r >= 0; r-- {
+				layers[r] = sortLayer(layers[r], func(ln *layeredNode) []*layeredNode { return ln.out })
+			}
+		}
+		if crossings := countCrossings(layers); crossings < bestCrossings {
+			best, bestCrossings = cloneLayers(layers), crossings
+		}
+	}
+	for r := range layers {
+		layers[r] = best[r]
+		for i, ln := range layers[r] {
+			ln.order = i
+		}
+	}
+}
+
+func cloneLayers(layers [][]*layeredNode) [][]*layeredNode {
+	clone := make([][]*layeredNode, len(layers))
+	for r, layer := range layers {
+		clone[r] = append([]*layeredNode{}, layer...)
+	}
+	return clone
+}
+
+// sortLayer sorts a rank by the barycenter of each node's neighbours, which
+// are the nodes neighbours returns. Nodes without any keep their place. The
+// nodes of a subgraph are kept together, sorted by their average barycenter,
+// so its frame doesn't enclose nodes from outside it.
+func sortLayer(layer []*layeredNode, neighbours func(*layeredNode) []*layeredNode) []*layeredNode {
+	key := map[*layeredNode]float64{}
+	for _, ln := range layer {
+		key[ln] = float64(ln.order)
+		if ns := neighbours(ln); len(ns) > 0 {
+			sum := 0
+			for _, n := range ns {
+				sum += n.order
+			}
+			key[ln] = float64(sum) / float64(len(ns))
+		}
+	}
+	sorted := sortGroups(layer, key, 0)
+	for i, ln := range sorted {
+		ln.order = i
+	}
+	return sorted
+}
+
+// sortGroups sorts nodes by key, keeping the nodes in each subgraph at
+// nesting depth depth together.
+func sortGroups(nodes []*layeredNode, key map[*layeredNode]float64, depth int) []*layeredNode {
+	type unit struct {
+		key   float64
+		nodes []*layeredNode
+	}
+	units := []*unit{}
+	groups := map[*subgraph]*unit{}
+	for _, ln := range nodes {
+		if len(ln.chain) <= depth {
+			units = append(units, &unit{key: key[ln], nodes: []*layeredNode{ln}})
+			continue
+		}
+		u, ok := groups[ln.chain[depth]]
+		if !ok {
+			u = &unit{}
+			groups[ln.chain[depth]] = u
+			units = append(units, u)
+		}
+		u.nodes = append(u.nodes, ln)
+	}
+	for _, u := range groups {
+		for _, ln := range u.nodes {
+			u.key += key[ln]
+		}
+		u.key /= float64(len(u.nodes))
+		u.nodes = sortGroups(u.nodes, key, depth+1)
+	}
+
+	sort.SliceStable(units, func(i, j int) bool { return units[i].key < units[j].key })
+	sorted := make([]*layeredNode, 0, len(nodes))
+	for _, u := range units {
+		sorted = append(sorted, u.nodes...)
+	}
+	return sorted
+}
+
+// countCrossings counts the pairs of edges between neighbouring ranks that
+// cross.
+func countCrossings(layers [][]*layeredNode) int {
+	crossings := 0
+	for _, layer := range layers {
+		type pair struct{ from, to int }
+		pairs := []pair{}
+		for _, ln := range layer {
+			for _, to := range ln.out {
+				pairs = append(pairs, pair{ln.order, to.order})
+			}
+		}
+		for i := range pairs {
+			for j := i + 1; j < len(pairs); j++ {
+				if (pairs[i].from-pairs[j].from)*(pairs[i].to-pairs[j].to) < 0 {
+					crossings++
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+// assignSlots picks where along its rank every node goes. Nodes move towards
+// the (lower) median of their neighbours on the rank before, then the rank after and
+// then before again, which straightens edges out. Nodes that want the same
+// slot share out the slots around it, so a rank spreads out evenly instead
+// of only one way.
+func assignSlots(layers [][]*layeredNode) {
+	for _, layer := range layers {
+		for i, ln := range layer {
+			ln.slot = i
+		}
+	}
+	place := func(layer []*layeredNode, neighbours func(*layeredNode) []*layeredNode) {
+		wants := make([]float64, len(layer))
+		for i, ln := range layer {
+			want := float64(ln.slot)
+			if ns := neighbours(ln); len(ns) > 0 {
+				slots := make([]int, len(ns))
+				for j, n := range ns {
+					slots[j] = n.slot
+				}
+				sort.Ints(slots)
+				want = float64(slots[(len(slots)-1)/2])
+			}
+			// Slots have to go up by at least one along the rank, so taking
+			// the node's index off turns that into staying level.
+			wants[i] = want - float64(i)
+		}
+		for i, slot := range closestAscending(wants) {
+			layer[i].slot = slot + i
+		}
+	}
+	in := func(ln *layeredNode) []*layeredNode { return ln.in }
+	out := func(ln *layeredNode) []*layeredNode { return ln.out }
+	for r := 1; r < len(layers); r++ {
+		place(layers[r], in)
+	}
+	for r := len(layers) - 2; r >= 0; r-- {
+		place(layers[r], out)
+	}
+	for r := 1; r < len(layers); r++ {
+		place(layers[r], in)
+	}
+}
+
+// closestAscending returns the ascending, not necessarily increasing,
+// sequence of whole numbers closest to values: runs of values that go down
+// are replaced by their average.
+func closestAscending(values []float64) []int {
+	type block struct {
+		sum   float64
+		count int
+	}
+	blocks := []block{}
+	for _, v := range values {
+		blocks = append(blocks, block{sum: v, count: 1})
+		for len(blocks) > 1 {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if prev.sum/float64(prev.count) <= last.sum/float64(last.count) {
+				break
+			}
+			blocks = append(blocks[:len(blocks)-2], block{sum: prev.sum + last.sum, count: prev.count + last.count})
+		}
+	}
+	result := make([]int, 0, len(values))
+	for _, b := range blocks {
+		for i := 0; i < b.count; i++ {
+			result = append(result, int(math.Floor(b.sum/float64(b.count)+0.5)))
+		}
+	}
+	return result
+}
+
+// separateSubgraphs moves nodes that ended up within a subgraph's span, on
+// ranks it covers, without being in it. Subgraphs are drawn as a frame
+// around all their nodes, which would take those nodes in as well. Nodes
+// only ever move further along their rank, along with everything after them.
+func separateSubgraphs(layers [][]*layeredNode, subgraphs []*subgraph) {
+	inSubgraph := func(ln *layeredNode, sg *subgraph) bool {
+		for _, s := range ln.chain {
+			if s == sg {
+				return true
+			}
+		}
+		return false
+	}
+	shiftFrom := func(layer []*layeredNode, i, by int) {
+		for ; i < len(layer); i++ {
+			layer[i].slot += by
+		}
+	}
+
+	// Every move resolves an overlap, but may cause another further along,
+	// so this runs until nothing moves or it's clear it won't settle.
+	for round := 0; round < 4*len(layers)*len(subgraphs); round++ {
+		moved := false
+		for _, sg := range subgraphs {
+			first, last, top, bottom := 0, 0, -1, -1
+			for _, layer := range layers {
+				for _, ln := range layer {
+					if !inSubgraph(ln, sg) {
+						continue
+					}
+					if top == -1 || ln.slot < first {
+						first = ln.slot
+					}
+					if top == -1 || ln.slot > last {
+						last = ln.slot
+					}
+					if top == -1 {
+						top = ln.rank
+					}
+					bottom = ln.rank
+				}
+			}
+			if top == -1 {
+				continue
+			}
+			for r := top; r <= bottom && !moved; r++ {
+				layer := layers[r]
+				firstMember := -1
+				for i, ln := range layer {
+					if inSubgraph(ln, sg) {
+						firstMember = i
+						break
+					}
+				}
+				for i, ln := range layer {
+					if inSubgraph(ln, sg) || ln.slot < first || ln.slot > last {
+						continue
+					}
+					if firstMember != -1 && i < firstMember || firstMember == -1 && 2*ln.slot < first+last {
+						// The node comes before the subgraph, so the
+						// subgraph moves past it on every rank it's on.
+						by := ln.slot + 1 - first
+						for _, l := range layers[top : bottom+1] {
+							for j, m := range l {
+								if inSubgraph(m, sg) {
+									shiftFrom(l, j, by)
+									break
+								}
+							}
+						}
+					} else {
+						shiftFrom(layer, i, last+1-ln.slot)
+					}
+					moved = true
+					break
+				}
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package graph
+
+import "testing"
+
+func mapLayeredGraph(t *testing.T, mermaid, layout string) *graph {
+	t.Helper()
+	properties, err := mermaidFileToMap(mermaid, "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+	properties.layout = layout
+	g, err := mapGraph(properties)
+	if err != nil {
+		t.Fatalf("mapGraph() error = %v", err)
+	}
+	return g
+}
+
+// rankAndPosition splits n's grid coord into its rank and its position along
+// that rank.
+func rankAndPosition(g *graph, n *node) (int, int) {
+	if g.isHorizontal() {
+		return n.gridCoord.x, n.gridCoord.y
+	}
+	return n.gridCoord.y, n.gridCoord.x
+}
+
+// edgeCrossings counts the pairs of edges between neighbouring ranks whose
+// ends are in opposite order.
+func edgeCrossings(g *graph) int {
+	crossings := 0
+	for i, a := range g.edges {
+		for _, b := range g.edges[i+1:] {
+			aFromRank, aFrom := rankAndPosition(g, a.from)
+			aToRank, aTo := rankAndPosition(g, a.to)
+			bFromRank, bFrom := rankAndPosition(g, b.from)
+			bToRank, bTo := rankAndPosition(g, b.to)
+			if aFromRank == bFromRank && aToRank == bToRank && (aFrom-bFrom)*(aTo-bTo) < 0 {
+				crossings++
+			}
+		}
+	}
+	return crossings
+}
+
+func TestLayeredLayoutRanksFollowEdges(t *testing.T) {
+	g := mapLayeredGraph(t, "graph TD\nA --> B\nB ---> C\nA --> C\nD --> C\nC --> E", "layered")
+
+	taken := map[gridCoord]string{}
+	for _, n := range g.nodes {
+		if n.gridCoord == nil {
+			t.Fatalf("node %s wasn't placed", n.name)
+		}
+		if other, ok := taken[*n.gridCoord]; ok {
+			t.Errorf("nodes %s and %s are both at %v", other, n.name, *n.gridCoord)
+		}
+		taken[*n.gridCoord] = n.name
+	}
+	for _, e := range g.edges {
+		fromRank, _ := rankAndPosition(g, e.from)
+		toRank, _ := rankAndPosition(g, e.to)
+		if toRank-fromRank < 4*max(e.length, 1) {
+			t.Errorf("edge %s -> %s goes from rank %d to %d, want at least %d ranks down", e.from.name, e.to.name, fromRank, toRank, max(e.length, 1))
+		}
+	}
+	// D only links to C, so it moves down next to it.
+	d, _ := g.getNode("D")
+	c, _ := g.getNode("C")
+	dRank, _ := rankAndPosition(g, d)
+	if cRank, _ := rankAndPosition(g, c); dRank != cRank-4 {
+		t.Errorf("D is on rank %d, want %d", dRank, cRank-4)
+	}
+}
+
+func TestLayeredLayoutBreaksCycles(t *testing.T) {
+	g := mapLayeredGraph(t, "graph LR\nA --> B\nB --> C\nC --> A\nC --> C\nC --> D", "layered")
+
+	ranks := map[string]int{}
+	for _, n := range g.nodes {
+		if n.gridCoord == nil {
+			t.Fatalf("node %s wasn't placed", n.name)
+		}
+		ranks[n.name], _ = rankAndPosition(g, n)
+	}
+	if !(ranks["A"] < ranks["B"] && ranks["B"] < ranks["C"] && ranks["C"] < ranks["D"]) {
+		t.Errorf("ranks = %v, want A, B, C and D one after the other", ranks)
+	}
+}
+
+func TestLayeredLayoutReducesCrossings(t *testing.T) {
+	mermaid := "graph TD\nA --> C\nA --> D\nB --> C\nB --> X"
+	grid := edgeCrossings(mapLayeredGraph(t, mermaid, "grid"))
+	layered := edgeCrossings(mapLayeredGraph(t, mermaid, "layered"))
+	if grid == 0 {
+		t.Fatalf("the grid layout has no crossings to reduce")
+	}
+	if layered != 0 {
+		t.Errorf("layered layout has %d crossings, want 0 (grid has %d)", layered, grid)
+	}
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// graphLayout lays out and draws the graph described by properties, and
+// reports where its nodes, edges and subgraphs ended up on the drawing.
+func graphLayout(properties *graphProperties) (*diagram.Layout, error) {
+	g, err := mapGraph(properties)
+	if err != nil {
+		return nil, err
+	}
+	d := g.draw()
+	maxX, maxY := getDrawingSize(d)
+	layout := &diagram.Layout{Type: "graph", Width: maxX + 1, Height: maxY + 1}
+
+	for _, n := range g.nodes {
+		w, h := getDrawingSize(n.drawing)
+		layout.Nodes = append(layout.Nodes, diagram.Node{
+			ID:    n.name,
+			Label: strings.Join(n.label.lines, "\n"),
+			Box:   diagram.Rect{X: n.drawingCoord.x, Y: n.drawingCoord.y, Width: w + 1, Height: h + 1},
+		})
+	}
+
+	for _, e := range g.edges {
+		le := diagram.Edge{From: e.from.name, To: e.to.name, Points: []diagram.Point{}}
+		if e.text != "" {
+			le.Label = strings.Join(e.label.lines, "\n")
+		}
+		if e.fromSubgraph != nil {
+			le.From = e.fromSubgraph.id
+		}
+		if e.toSubgraph != nil {
+			le.To = e.toSubgraph.id
+		}
+		for _, c := range g.edgeDrawingLine(e) {
+			le.Points = append(le.Points, diagram.Point{X: c.x, Y: c.y})
+		}
+		if p := e.labelPlacement; p != nil {
+			le.Footnote = p.footnote
+			if len(p.starts) > 0 {
+				le.LabelAt = &diagram.Point{X: p.starts[0].x, Y: p.starts[0].y}
+			}
+		}
+		layout.Edges = append(layout.Edges, le)
+	}
+
+	for _, sg := range g.subgraphs {
+		if len(sg.nodes) == 0 {
+			continue
+		}
+		layout.Subgraphs = append(layout.Subgraphs, diagram.Subgraph{
+			ID:    sg.id,
+			Label: strings.Join(sg.label.lines, "\n"),
+			Box:   diagram.Rect{X: sg.minX, Y: sg.minY, Width: sg.maxX - sg.minX + 1, Height: sg.maxY - sg.minY + 1},
+		})
+	}
+	return layout, nil
+}
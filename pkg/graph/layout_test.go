@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// cellAt returns the glyph drawn at (x, y) in a rendered diagram.
+func cellAt(t *testing.T, rows [][]rune, x, y int) string {
+	t.Helper()
+	if y < 0 || y >= len(rows) || x < 0 || x >= len(rows[y]) {
+		t.Fatalf("cell (%d,%d) is outside the drawing", x, y)
+	}
+	return string(rows[y][x])
+}
+
+func splitDrawing(s string) [][]rune {
+	var rows [][]rune
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		rows = append(rows, []rune(line))
+	}
+	return rows
+}
+
+func TestGraphLayoutMatchesDrawing(t *testing.T) {
+	for _, input := range []string{
+		"graph LR\nsubgraph one\nA -->|label| B\nend\nB --> C\nA --> C",
+		"graph TD\nA -->|label| B\nA --> C\nB --> D\nC --> D",
+		"graph BT\nA -->|label| B\nB --> C",
+		"graph LR\nA --> grp\nsubgraph grp [Group]\nB --> C\nend\ngrp --> D",
+	} {
+		t.Run(strings.SplitN(input, "\n", 2)[0], func(t *testing.T) {
+			gd, err := Parse(input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			config := diagram.DefaultConfig()
+			text, err := Render(gd, config)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			layout, err := Layout(gd, config)
+			if err != nil {
+				t.Fatalf("Layout() error = %v", err)
+			}
+			rows := splitDrawing(text)
+			if layout.Height != len(rows) {
+				t.Errorf("height = %d, drawing has %d rows", layout.Height, len(rows))
+			}
+
+			boxes := map[string]diagram.Rect{}
+			for _, n := range layout.Nodes {
+				b := n.Box
+				boxes[n.ID] = b
+				if got := cellAt(t, rows, b.X, b.Y); got != "┌" {
+					t.Errorf("node %s top-left = %q, want ┌", n.ID, got)
+				}
+				if got := cellAt(t, rows, b.X+b.Width-1, b.Y+b.Height-1); got != "┘" {
+					t.Errorf("node %s bottom-right = %q, want ┘", n.ID, got)
+				}
+			}
+
+			// Edges may also start or end at a subgraph's frame.
+			for _, sg := range layout.Subgraphs {
+				boxes[sg.ID] = sg.Box
+			}
+
+			onBorder := func(p diagram.Point, b diagram.Rect) bool {
+				inside := p.X >= b.X && p.X < b.X+b.Width && p.Y >= b.Y && p.Y < b.Y+b.Height
+				edge := p.X == b.X || p.X == b.X+b.Width-1 || p.Y == b.Y || p.Y == b.Y+b.Height-1
+				return inside && edge
+			}
+			for _, e := range layout.Edges {
+				if len(e.Points) < 2 {
+					t.Fatalf("edge %s->%s has %d points", e.From, e.To, len(e.Points))
+				}
+				if first := e.Points[0]; !onBorder(first, boxes[e.From]) {
+					t.Errorf("edge %s->%s starts at %v, off the border of %s", e.From, e.To, first, e.From)
+				}
+				if last := e.Points[len(e.Points)-1]; !onBorder(last, boxes[e.To]) {
+					t.Errorf("edge %s->%s ends at %v, off the border of %s", e.From, e.To, last, e.To)
+				}
+				if e.Label == "" {
+					continue
+				}
+				if e.LabelAt == nil {
+					t.Fatalf("edge %s->%s has no label position", e.From, e.To)
+				}
+				got := string(rows[e.LabelAt.Y][e.LabelAt.X : e.LabelAt.X+len(e.Label)])
+				if got != e.Label {
+					t.Errorf("label of %s->%s reads %q at %v, want %q", e.From, e.To, got, *e.LabelAt, e.Label)
+				}
+			}
+
+			for _, sg := range layout.Subgraphs {
+				b := sg.Box
+				if got := cellAt(t, rows, b.X, b.Y); got != "┌" {
+					t.Errorf("subgraph %s top-left = %q, want ┌", sg.ID, got)
+				}
+				if got := cellAt(t, rows, b.X+b.Width-1, b.Y+b.Height-1); got != "┘" {
+					t.Errorf("subgraph %s bottom-right = %q, want ┘", sg.ID, got)
+				}
+			}
+		})
+	}
+}
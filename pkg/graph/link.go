@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"regexp"
+	"strings"
+)
+
+// flowLink is one link operator of a flowchart line, like -->, -- text -->
+// or <==>, broken down into how its edge is drawn and laid out.
+type flowLink struct {
+	label           string
+	isBidirectional bool
+	stroke          edgeStroke
+	head            edgeHead
+	// length is the number of ranks the edge spans at least: 1 for --> and
+	// -.->, and one more for every extra dash, equals sign or dot (--->).
+	length int
+}
+
+var (
+	// linkPattern matches a whole link operator: an optional start head, a
+	// solid, thick or dotted line of any length and an optional end head, or
+	// ~~~ for an invisible link.
+	linkPattern = regexp.MustCompile(`^(?:~~~+|[<ox]?(?:--+[->ox]|==+[=>ox]|-\.+-[>ox]?))`)
+	// linkTextStartPattern matches the first half of a link with its text
+	// inline (A -- text --> B); the second half is found with the end pattern
+	// for the same stroke.
+	linkTextStartPattern = regexp.MustCompile(`^[<ox]?(?:--|==|-\.)\s`)
+	linkTextEndPatterns  = map[byte]*regexp.Regexp{
+		'-': regexp.MustCompile(`--+[->ox]`),
+		'=': regexp.MustCompile(`==+[=>ox]`),
+		'.': regexp.MustCompile(`-?\.+-[>ox]?`),
+	}
+)
+
+// destructLink works out the link that start and end make up. For a link
+// without inline text both are the whole operator; otherwise start is its
+// first half (-- or <==) and end the second (--> or ==>). Like mermaid, a
+// start head only counts when it matches the end head.
+func destructLink(start, end string) flowLink {
+	link := flowLink{stroke: strokeSolid, head: headNone}
+	switch end[len(end)-1] {
+	case '>':
+		link.head = headArrow
+	case 'o':
+		link.head = headCircle
+	case 'x':
+		link.head = headCross
+	}
+	switch start[0] {
+	case '<':
+		link.isBidirectional = link.head == headArrow
+	case 'o':
+		link.isBidirectional = link.head == headCircle
+	case 'x':
+		link.isBidirectional = link.head == headCross
+	}
+
+	// The last character is either the end head or the one a line needs at
+	// least, so what's left counts the ranks the link spans.
+	line := strings.TrimLeft(end[:len(end)-1], "<ox")
+	link.length = len(line) - 1
+	switch {
+	case strings.Contains(line, "."):
+		link.stroke = strokeDotted
+		link.length = strings.Count(line, ".")
+	case strings.HasPrefix(line, "="):
+		link.stroke = strokeThick
+	case strings.HasPrefix(line, "~"):
+		link.stroke = strokeInvisible
+	}
+	return link
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// matchLink reports whether a link starts at line[i], and if so returns it
+// along with the index just past it and its |label|, if any.
+func matchLink(line string, i int) (flowLink, int, bool) {
+	// An o or x right after a node name is part of that name (box--x B).
+	if (line[i] == 'o' || line[i] == 'x') && i > 0 && isWordByte(line[i-1]) {
+		return flowLink{}, 0, false
+	}
+	rest := line[i:]
+
+	if op := linkPattern.FindString(rest); op != "" {
+		link := destructLink(op, op)
+		end := i + len(op)
+		label := strings.TrimLeft(line[end:], " \t")
+		if strings.HasPrefix(label, "|") {
+			if closing := strings.IndexByte(label[1:], '|'); closing != -1 {
+				link.label = label[1 : closing+1]
+				end = len(line) - len(label) + closing + 2
+			}
+		}
+		return link, end, true
+	}
+
+	if start := linkTextStartPattern.FindString(rest); start != "" {
+		start = strings.TrimRight(start, " \t")
+		text := rest[len(start):]
+		if loc := linkTextEndPatterns[start[len(start)-1]].FindStringIndex(text); loc != nil {
+			link := destructLink(start, text[loc[0]:loc[1]])
+			link.label = strings.TrimSpace(text[:loc[0]])
+			return link, i + len(start) + loc[1], true
+		}
+	}
+	return flowLink{}, 0, false
+}
+
+// splitLinks splits a flowchart line into the node groups between its links
+// and the links themselves, so there is always one group more than there
+// are links. Anything inside quotes, brackets, parentheses or braces is node
+// text, so a node's label can contain what looks like a link.
+func splitLinks(line string) ([]string, []flowLink) {
+	groups := []string{}
+	links := []flowLink{}
+	depth := 0
+	inQuotes := false
+	groupStart := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0:
+			if link, end, ok := matchLink(line, i); ok {
+				groups = append(groups, line[groupStart:i])
+				links = append(links, link)
+				groupStart = end
+				i = end - 1
+			}
+		}
+	}
+	return append(groups, line[groupStart:]), links
+}
+
+// splitNodeGroup splits the A & B & C between two links into its nodes'
+// texts, leaving any & inside a node's label alone.
+func splitNodeGroup(group string) []string {
+	parts := []string{}
+	depth := 0
+	inQuotes := false
+	partStart := 0
+	for i := 0; i < len(group); i++ {
+		switch c := group[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			if depth > 0 {
+				depth--
+			}
+		case c == '&' && depth == 0:
+			parts = append(parts, strings.TrimSpace(group[partStart:i]))
+			partStart = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(group[partStart:]))
+}
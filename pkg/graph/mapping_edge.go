@@ -0,0 +1,549 @@
+package graph
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// edgeStroke is the line style drawn along an edge's path: "-", ":" and "="
+// in ASCII, and light, dashed and heavy box-drawing lines in Unicode.
+type edgeStroke int
+
+const (
+	strokeSolid edgeStroke = iota
+	strokeDotted
+	strokeThick
+	// strokeInvisible links (~~~) only place nodes; they're kept in
+	// graph.invisibleEdges and never routed or drawn.
+	strokeInvisible
+)
+
+// edgeHead is the glyph drawn at an edge's arrowhead end. headNone means no
+// glyph is drawn at all (an open link like ---).
+type edgeHead int
+
+const (
+	headArrow edgeHead = iota
+	headCircle
+	headCross
+	headNone
+)
+
+type edge struct {
+	from            *node
+	to              *node
+	text            string
+	label           graphLabel
+	isBidirectional bool
+	stroke          edgeStroke
+	head            edgeHead
+	length          int
+	index           int
+	styles          map[string]string // from linkStyle
+	path            []gridCoord
+	labelLine       []gridCoord
+	startDir        direction
+	endDir          direction
+	// fromSubgraph and toSubgraph are set when an end of the edge is a
+	// subgraph rather than a node. from and to are then the subgraph's node
+	// the edge is laid out against, and the edge is drawn to its frame.
+	fromSubgraph *subgraph
+	toSubgraph   *subgraph
+	// laneOffsets moves each point of path sideways on the drawing, into
+	// the lanes assignLanes put its segments in. It's nil when none moved.
+	laneOffsets []drawingCoord
+	// labelPlacement is where the label ended up, once it's drawn.
+	labelPlacement *edgeLabelPlacement
+}
+
+func (g *graph) determinePath(e *edge) {
+	if e.fromSubgraph != nil || e.toSubgraph != nil {
+		fromBox, toBox := edgeEndBox(e.from, e.fromSubgraph), edgeEndBox(e.to, e.toSubgraph)
+		if !fromBox.overlaps(toBox) {
+			g.determineSubgraphPath(e, fromBox, toBox)
+			return
+		}
+		// A subgraph linked to itself has no side that faces the other end;
+		// draw the edge between the nodes it's laid out against instead.
+		// Links to what's inside a subgraph are rejected when parsing.
+		e.fromSubgraph, e.toSubgraph = nil, nil
+	}
+	key := newEdgePair(e.from.index, e.to.index)
+	duplicateIndex := g.edgeCounts[key]
+
+	if startDir, endDir, ok := g.parallelDirections(e, duplicateIndex); ok {
+		if path := g.pathBetweenSides(e, startDir, endDir); path != nil {
+			e.startDir = startDir
+			e.endDir = endDir
+			e.path = path
+			g.edgeCounts[key]++
+			return
+		}
+	}
+
+	// Get both paths and use least amount of steps
+	preferredDir, preferredOppositeDir, alternativeDir, alternativeOppositeDir := g.determineStartAndEndDir(e)
+	preferredPath := g.pathBetweenSides(e, preferredDir, preferredOppositeDir)
+	alternativePath := g.pathBetweenSides(e, alternativeDir, alternativeOppositeDir)
+	if preferredPath == nil && alternativePath == nil {
+		preferredDir, preferredOppositeDir, preferredPath = g.pathAroundLabels(e, preferredDir, preferredOppositeDir)
+	}
+
+	// Running along or across edges routed before counts as extra steps.
+	nrStepsPreferred := len(preferredPath) + g.pathPenalty(preferredPath)
+	nrStepsAlternative := len(alternativePath) + g.pathPenalty(alternativePath)
+	if alternativePath == nil || (preferredPath != nil && nrStepsPreferred <= nrStepsAlternative) {
+		log.Debugf("Using preferred path with %v steps instead of alternative path with %v steps", nrStepsPreferred, nrStepsAlternative)
+		e.startDir = preferredDir
+		e.endDir = preferredOppositeDir
+		e.path = preferredPath
+	} else {
+		log.Debugf("Using alternative path with %v steps instead of preferred path with %v steps", nrStepsAlternative, nrStepsPreferred)
+		e.startDir = alternativeDir
+		e.endDir = alternativeOppositeDir
+		e.path = alternativePath
+	}
+	g.edgeCounts[key]++
+}
+
+// pathBetweenSides finds the merged path of e from the startDir side of its
+// start node to the endDir side of its end node, running over the labels of
+// frames only. It returns nil when there is none.
+func (g *graph) pathBetweenSides(e *edge, startDir, endDir direction, frames ...*subgraph) []gridCoord {
+	from := e.from.gridCoord.Direction(startDir)
+	to := e.to.gridCoord.Direction(endDir)
+	// An edge between two nodes of a subgraph stays inside it, and its
+	// frame is drawn around the edge.
+	frames = append(frames, commonChain(g.subgraphChain(e.from), g.subgraphChain(e.to))...)
+	log.Debugf("Determining path from %v (direction %v) to %v (direction %v)", *e.from, startDir, *e.to, endDir)
+	path, err := g.getPath(from, to, frames...)
+	if err != nil {
+		log.Debugf("Error getting path from %v to %v: %v", from, to, err)
+		return nil
+	}
+	return mergePath(path)
+}
+
+// pathAroundLabels finds the cheapest path of e between any two sides of its
+// nodes, for when a subgraph label shuts in the sides it would rather use.
+// When every side is shut in, the path runs from startDir to endDir over the
+// labels, which beats leaving the edge out.
+func (g *graph) pathAroundLabels(e *edge, startDir, endDir direction) (direction, direction, []gridCoord) {
+	sides := []direction{Up, Down, Left, Right}
+	var best []gridCoord
+	bestSteps := 0
+	for _, from := range sides {
+		for _, to := range sides {
+			if e.from == e.to && from == to {
+				continue
+			}
+			path := g.pathBetweenSides(e, from, to)
+			if path == nil {
+				continue
+			}
+			if steps := len(path) + g.pathPenalty(path); best == nil || steps < bestSteps {
+				startDir, endDir, best, bestSteps = from, to, path, steps
+			}
+		}
+	}
+	if best == nil {
+		best = g.pathBetweenSides(e, startDir, endDir, g.subgraphs...)
+	}
+	return startDir, endDir, best
+}
+
+// gridBox is a rectangle of grid cells, bounds included.
+type gridBox struct {
+	minX, minY, maxX, maxY int
+}
+
+func (b gridBox) overlaps(other gridBox) bool {
+	return b.minX <= other.maxX && other.minX <= b.maxX && b.minY <= other.maxY && other.minY <= b.maxY
+}
+
+func (b gridBox) center() gridCoord {
+	return gridCoord{x: b.minX + (b.maxX-b.minX)/2, y: b.minY + (b.maxY-b.minY)/2}
+}
+
+// edgeEndBox is the grid area an end of an edge takes up: n's 3x3 cell, or
+// every cell of sg's nodes when the end is a subgraph.
+func edgeEndBox(n *node, sg *subgraph) gridBox {
+	b := gridBox{n.gridCoord.x, n.gridCoord.y, n.gridCoord.x + 2, n.gridCoord.y + 2}
+	if sg == nil {
+		return b
+	}
+	for _, m := range sg.nodes {
+		b.minX = Min(b.minX, m.gridCoord.x)
+		b.minY = Min(b.minY, m.gridCoord.y)
+		b.maxX = Max(b.maxX, m.gridCoord.x+2)
+		b.maxY = Max(b.maxY, m.gridCoord.y+2)
+	}
+	return b
+}
+
+// sideFacing is the side of from that faces to, looking along the graph's
+// ranks first. The boxes must not overlap.
+func (g *graph) sideFacing(from, to gridBox) direction {
+	sides := []direction{Right, Left, Down, Up}
+	if !g.isHorizontal() {
+		sides = []direction{Down, Up, Right, Left}
+	}
+	for _, side := range sides {
+		switch {
+		case side == Right && to.minX > from.maxX,
+			side == Left && to.maxX < from.minX,
+			side == Down && to.minY > from.maxY,
+			side == Up && to.maxY < from.minY:
+			return side
+		}
+	}
+	return sides[0]
+}
+
+// subgraphEndCoord is the cell on side of box, the grid area of sg, that an
+// edge towards other attaches to: in line with other's center where the
+// side is long enough.
+func subgraphEndCoord(box gridBox, side direction, other gridBox) gridCoord {
+	c := other.center()
+	c.x = Min(Max(c.x, box.minX), box.maxX)
+	c.y = Min(Max(c.y, box.minY), box.maxY)
+	switch side {
+	case Up:
+		c.y = box.minY
+	case Down:
+		c.y = box.maxY
+	case Left:
+		c.x = box.minX
+	case Right:
+		c.x = box.maxX
+	}
+	return c
+}
+
+// determineSubgraphPath routes an edge with a subgraph at either end. The
+// path runs between the sides of the two ends that face each other; a
+// subgraph's side is the edge of the area its nodes take up, and the edge is
+// cut back to the subgraph's frame when it is drawn. The gap outside a
+// subgraph's side is widened so its frame and the arrow to it both fit.
+func (g *graph) determineSubgraphPath(e *edge, fromBox, toBox gridBox) {
+	e.startDir = g.sideFacing(fromBox, toBox)
+	e.endDir = g.sideFacing(toBox, fromBox)
+
+	from := e.from.gridCoord.Direction(e.startDir)
+	if e.fromSubgraph != nil {
+		from = subgraphEndCoord(fromBox, e.startDir, toBox)
+		g.makeRoomForSubgraphFrame(e.fromSubgraph, from, e.startDir)
+	}
+	to := e.to.gridCoord.Direction(e.endDir)
+	if e.toSubgraph != nil {
+		to = subgraphEndCoord(toBox, e.endDir, fromBox)
+		g.makeRoomForSubgraphFrame(e.toSubgraph, to, e.endDir)
+	}
+	log.Debugf("Determining subgraph path from %v (direction %v) to %v (direction %v)", from, e.startDir, to, e.endDir)
+
+	path, err := g.getPath(from, to, e.fromSubgraph, e.toSubgraph)
+	if err != nil {
+		path, err = g.getPath(from, to, g.subgraphs...)
+	}
+	if err != nil {
+		log.Debugf("Error getting path from %v to %v: %v", from, to, err)
+		return
+	}
+	e.path = mergePath(path)
+}
+
+// makeRoomForSubgraphFrame widens the gap next to c, on side of sg, so that
+// besides the usual padding it holds sg's frame and the space inside it.
+func (g *graph) makeRoomForSubgraphFrame(sg *subgraph, c gridCoord, side direction) {
+	gap := gridCoord{x: c.x + side.x - 1, y: c.y + side.y - 1}
+	switch side {
+	case Left, Right:
+		g.columnWidth[gap.x] = Max(g.columnWidth[gap.x], g.paddingX+2*subgraphPadding)
+	case Up, Down:
+		room := g.paddingY + 2*subgraphPadding
+		if g.onDrawing(side) == Up {
+			room += sg.label.contentHeight() + 1
+		}
+		g.rowHeight[gap.y] = Max(g.rowHeight[gap.y], room)
+	}
+}
+
+// makeRoomForSelfLoop widens the gaps e runs through outside the subgraph
+// its node is in, when e is a self-loop, so that the loop fits inside the
+// subgraph's frame with the frame's padding around it.
+func (g *graph) makeRoomForSelfLoop(e *edge) {
+	if e.from != e.to {
+		return
+	}
+	var sg *subgraph
+	for _, candidate := range g.subgraphs {
+		if slices.Contains(candidate.nodes, e.from) && (sg == nil || g.getSubgraphDepth(candidate) > g.getSubgraphDepth(sg)) {
+			sg = candidate
+		}
+	}
+	if sg == nil {
+		return
+	}
+	box := edgeEndBox(e.from, sg)
+	for _, c := range e.path {
+		switch {
+		case c.x > box.maxX:
+			g.makeRoomForSubgraphFrame(sg, gridCoord{x: c.x - 1, y: c.y}, Right)
+		case c.x < box.minX:
+			g.makeRoomForSubgraphFrame(sg, gridCoord{x: c.x + 1, y: c.y}, Left)
+		}
+		switch {
+		case c.y > box.maxY:
+			g.makeRoomForSubgraphFrame(sg, gridCoord{x: c.x, y: c.y - 1}, Down)
+		case c.y < box.minY:
+			g.makeRoomForSubgraphFrame(sg, gridCoord{x: c.x, y: c.y + 1}, Up)
+		}
+	}
+}
+
+// makeRoomBesideFrames widens the gaps in which e runs right alongside the
+// frame of a subgraph it doesn't run inside of, so that the frame is drawn
+// between the subgraph's nodes and the edge rather than on top of the edge.
+func (g *graph) makeRoomBesideFrames(e *edge) {
+	inside := commonChain(g.subgraphChain(e.from), g.subgraphChain(e.to))
+	for _, sg := range g.subgraphs {
+		if len(sg.nodes) == 0 || slices.Contains(inside, sg) || sg == e.fromSubgraph || sg == e.toSubgraph {
+			continue
+		}
+		box := edgeEndBox(sg.nodes[0], sg)
+		for i := 1; i < len(e.path); i++ {
+			a, b := e.path[i-1], e.path[i]
+			switch {
+			case a.x == b.x && Min(a.y, b.y) <= box.maxY+1 && Max(a.y, b.y) >= box.minY-1:
+				if a.x > box.maxX && g.noColumnsBetween(box.maxX, a.x) {
+					g.makeRoomForSubgraphFrame(sg, gridCoord{x: a.x - 1, y: a.y}, Right)
+				}
+				if a.x < box.minX && g.noColumnsBetween(a.x, box.minX) {
+					g.makeRoomForSubgraphFrame(sg, gridCoord{x: a.x + 1, y: a.y}, Left)
+				}
+			case a.y == b.y && Min(a.x, b.x) <= box.maxX+1 && Max(a.x, b.x) >= box.minX-1:
+				if a.y > box.maxY && g.noRowsBetween(box.maxY, a.y) {
+					g.makeRoomForSubgraphFrame(sg, gridCoord{x: a.x, y: a.y - 1}, Down)
+				}
+				if a.y < box.minY && g.noRowsBetween(a.y, box.minY) {
+					g.makeRoomForSubgraphFrame(sg, gridCoord{x: a.x, y: a.y + 1}, Up)
+				}
+			}
+		}
+	}
+}
+
+// noColumnsBetween reports whether the grid columns strictly between a and b
+// take up no room on the drawing.
+func (g *graph) noColumnsBetween(a, b int) bool {
+	for x := a + 1; x < b; x++ {
+		if g.columnWidth[x] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// noRowsBetween reports whether the grid rows strictly between a and b take
+// up no room on the drawing.
+func (g *graph) noRowsBetween(a, b int) bool {
+	for y := a + 1; y < b; y++ {
+		if g.rowHeight[y] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *graph) parallelDirections(e *edge, duplicateIndex int) (direction, direction, bool) {
+	// A self-loop has no direction to run alongside.
+	if duplicateIndex == 0 || e.from == e.to {
+		return Middle, Middle, false
+	}
+
+	dir := determineDirection(genericCoord(*e.from.gridCoord), genericCoord(*e.to.gridCoord))
+	switch {
+	case g.isHorizontal() && (dir == Right || dir == Left):
+		options := [][2]direction{{Down, Down}, {Up, Up}}
+		if duplicateIndex-1 < len(options) {
+			return options[duplicateIndex-1][0], options[duplicateIndex-1][1], true
+		}
+	case !g.isHorizontal() && (dir == Down || dir == Up):
+		options := [][2]direction{{Right, Right}, {Left, Left}}
+		if duplicateIndex-1 < len(options) {
+			return options[duplicateIndex-1][0], options[duplicateIndex-1][1], true
+		}
+	}
+
+	return Middle, Middle, false
+}
+
+func (g *graph) determineLabelLine(e *edge) {
+	// What line on the path should the label be placed?
+	if e.text == "" {
+		return
+	}
+	lenLabel := e.label.width
+	// Widening a column that is occupied by a node would push that node's
+	// border out, leaving a visible gap between the box and any incoming
+	// arrowhead. Prefer label-line candidates whose target column is a free
+	// edge corridor; only fall back to a node column if no corridor segment
+	// is available. A label on a line other edges run along too would be
+	// unclear, so segments of the edge's own come first.
+	var largestLine []gridCoord
+	var fallbackLine []gridCoord
+	for _, ownOnly := range []bool{true, false} {
+		prevStep := e.path[0]
+		var largestLineSize int
+		var fallbackLineSize int
+		for _, step := range e.path[1:] {
+			line := []gridCoord{gridCoord(prevStep), gridCoord(step)}
+			prevStep = step
+			if ownOnly && g.segmentShared(e, line[0], line[1]) {
+				continue
+			}
+			lineWidth := g.calculateLineWidth(line)
+			if _, ok := g.labelColumn(line); !ok {
+				if lineWidth > fallbackLineSize {
+					fallbackLineSize = lineWidth
+					fallbackLine = line
+				}
+				continue
+			}
+			if lineWidth >= lenLabel {
+				largestLine = line
+				break
+			}
+			if lineWidth > largestLineSize {
+				largestLineSize = lineWidth
+				largestLine = line
+			}
+		}
+		if largestLine != nil || fallbackLine != nil {
+			break
+		}
+	}
+	if largestLine == nil {
+		largestLine = fallbackLine
+	}
+	if largestLine == nil {
+		// Path only had a single segment that lives on a node column; use it
+		// rather than dropping the label entirely.
+		largestLine = []gridCoord{e.path[0], e.path[1]}
+	}
+
+	labelPadding := 3 // Wrap with -{label}-> (dashes + end arrowhead, 3 char)
+	if e.isBidirectional {
+		labelPadding = 4 // Wrap with <-{label}-> (start arrowhead+ dashes + end arrowhead, 4 char)
+	}
+	middleX, ok := g.labelColumn(largestLine)
+	if !ok {
+		middleX = labelMiddleX(largestLine)
+	}
+	if g.isNodeBorderColumn(middleX) {
+		// A border is drawn at the start of its column, so widening it
+		// would leave every edge attached to that side of the node in the
+		// middle of nowhere. The label has to make do with the room there
+		// is, or go in the legend.
+		log.Debugf("Not widening column %v, it holds a node's border", middleX)
+	} else {
+		log.Debugf("Increasing column width for column %v from size %v to %v", middleX, g.columnWidth[middleX], lenLabel+labelPadding)
+		g.columnWidth[middleX] = Max(g.columnWidth[middleX], lenLabel+labelPadding)
+		log.Debugf("New column sizes: %v", g.columnWidth)
+	}
+	g.makeRoomForLabelRows(e, largestLine)
+	e.labelLine = largestLine
+}
+
+// makeRoomForLabelRows heightens the row a label is written in. Across a
+// horizontal line the label's lines go below the one on the line; across a
+// vertical line they're stacked around its middle, with the line showing
+// above and below them and room for arrowheads.
+func (g *graph) makeRoomForLabelRows(e *edge, line []gridCoord) {
+	lines := e.label.height()
+	if line[0].x == line[1].x {
+		row := Min(line[0].y, line[1].y) + Abs(line[0].y-line[1].y)/2
+		if g.isNodeRow(row) {
+			// Heightening the row would stretch the nodes on it.
+			return
+		}
+		arrowheads := 1
+		if e.isBidirectional {
+			arrowheads = 2
+		}
+		for g.rowHeight[row] < lines+2+arrowheads {
+			g.rowHeight[row]++
+		}
+		return
+	}
+
+	row := line[0].y
+	for _, n := range g.nodes {
+		if row == n.gridCoord.y || row == n.gridCoord.y+2 {
+			// A node's border is a single row.
+			return
+		}
+	}
+	fits := func(height int) bool { return height/2+lines-1 < height }
+	for !fits(g.rowHeight[row]) {
+		g.rowHeight[row]++
+	}
+}
+
+func labelMiddleX(line []gridCoord) int {
+	minX, maxX := line[0].x, line[1].x
+	if minX > maxX {
+		minX, maxX = maxX, minX
+	}
+	return minX + (maxX-minX)/2
+}
+
+// labelColumn is the column of line to widen for its label: the middle one,
+// or else the one nearest to it that no node is in. It reports false when
+// there are nodes in every column line spans.
+func (g *graph) labelColumn(line []gridCoord) (int, bool) {
+	middle := labelMiddleX(line)
+	minX, maxX := Min(line[0].x, line[1].x), Max(line[0].x, line[1].x)
+	for d := 0; middle-d >= minX || middle+d <= maxX; d++ {
+		for _, x := range []int{middle - d, middle + d} {
+			if x >= minX && x <= maxX && !g.isNodeColumn(x) {
+				return x, true
+			}
+		}
+	}
+	return middle, false
+}
+
+// isNodeBorderColumn reports whether grid column x holds the left or right
+// border of any node.
+func (g *graph) isNodeBorderColumn(x int) bool {
+	for _, n := range g.nodes {
+		if n.gridCoord != nil && (x == n.gridCoord.x || x == n.gridCoord.x+2) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNodeColumn reports whether grid column x is occupied by any node.
+// Widening such a column distorts the box that owns it.
+func (g *graph) isNodeColumn(x int) bool {
+	for _, n := range g.nodes {
+		if n.gridCoord == nil {
+			continue
+		}
+		if x >= n.gridCoord.x && x <= n.gridCoord.x+2 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g graph) calculateLineWidth(line []gridCoord) int {
+	totalSize := 0
+	for _, c := range line {
+		totalSize += g.columnWidth[c.x]
+	}
+	return totalSize
+}
@@ -1,4 +1,4 @@
-package cmd
+package graph
 
 import (
 	log "github.com/sirupsen/logrus"
@@ -7,6 +7,7 @@ import (
 type node struct {
 	name           string
 	label          graphLabel
+	shape          nodeShape
 	drawing        *drawing
 	drawingCoord   *drawingCoord
 	gridCoord      *gridCoord
@@ -25,7 +26,12 @@ func (n *node) setCoord(c *drawingCoord) {
 }
 
 func (n *node) setDrawing(g graph) *drawing {
-	d := drawBox(n, g)
+	var d *drawing
+	if n.shape == shapeRect {
+		d = drawBox(n, g)
+	} else {
+		d = drawShape(n, g)
+	}
 	n.drawing = d
 	return d
 }
@@ -37,10 +43,10 @@ func (g *graph) setColumnWidth(n *node) {
 	// - 2x padding
 	// - 2x margin
 	col1 := 1
-	col2 := 2*g.boxBorderPadding + n.label.width
+	col2 := 2*g.boxBorderPadding + n.label.width + n.shape.extraWidth(n.label, g.boxBorderPadding)
 	col3 := 1
 	colsToBePlaced := []int{col1, col2, col3}
-	rowsToBePlaced := []int{1, n.label.contentHeight() + 2*g.boxBorderPadding, 1} // Border, padding + content, border
+	rowsToBePlaced := []int{1, n.label.contentHeight() + 2*g.boxBorderPadding + n.shape.extraHeight(), 1} // Border, padding + content, border
 
 	for idx, col := range colsToBePlaced {
 		// Set new width for column if the size increased
@@ -88,13 +94,19 @@ func (g *graph) increaseGridSizeForPath(path []gridCoord) {
 }
 
 func (g *graph) reserveSpotInGrid(n *node, requestedCoord *gridCoord) *gridCoord {
+	return g.reserveSpotAlong(n, requestedCoord, g.isHorizontal())
+}
+
+// reserveSpotAlong reserves requestedCoord for n, or the first free spot
+// after it down the grid when ranks are horizontal, or to the right otherwise.
+func (g *graph) reserveSpotAlong(n *node, requestedCoord *gridCoord, horizontalRanks bool) *gridCoord {
 	if g.grid[*requestedCoord] != nil {
 		log.Debugf("Coord %d,%d is already taken", requestedCoord.x, requestedCoord.y)
 		// Next column is 4 coords further. This is because every node is 3 coords wide + 1 coord inbetween.
-		if g.graphDirection == "LR" {
-			return g.reserveSpotInGrid(n, &gridCoord{x: requestedCoord.x, y: requestedCoord.y + 4})
+		if horizontalRanks {
+			return g.reserveSpotAlong(n, &gridCoord{x: requestedCoord.x, y: requestedCoord.y + 4}, horizontalRanks)
 		} else {
-			return g.reserveSpotInGrid(n, &gridCoord{x: requestedCoord.x + 4, y: requestedCoord.y})
+			return g.reserveSpotAlong(n, &gridCoord{x: requestedCoord.x + 4, y: requestedCoord.y}, horizontalRanks)
 		}
 	}
 	// Reserve border + middle + border for node
@@ -0,0 +1,619 @@
+// Package graph parses and renders mermaid graph and flowchart diagrams as
+// ASCII or Unicode box drawings, and as SVG images laid out the same way.
+package graph
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/elliotchance/orderedmap/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+type graphProperties struct {
+	data             *orderedmap.OrderedMap[string, []textEdge]
+	nodeSpecs        map[string]graphNodeSpec
+	styleClasses     *map[string]styleClass
+	boxBorderPadding int
+	graphDirection   string
+	styleType        string
+	paddingX         int
+	paddingY         int
+	subgraphs        []*textSubgraph
+	useAscii         bool
+	showCoords       bool
+	layout           string
+	compact          bool
+	// nodeStyles and linkStyles hold the properties of style and linkStyle
+	// statements, by node name and by link index. linkStyle default is
+	// kept under defaultLinkStyle.
+	nodeStyles map[string]map[string]string
+	linkStyles map[int]map[string]string
+	linkCount  int
+	// line is the line of mermaid being parsed.
+	line int
+}
+
+// defaultLinkStyle is the linkStyles key of linkStyle default, which
+// applies to every link.
+const defaultLinkStyle = -1
+
+type textNode struct {
+	name       string
+	label      graphLabel
+	hasLabel   bool
+	shape      nodeShape
+	hasShape   bool
+	styleClass string
+}
+
+type graphNodeSpec struct {
+	label           graphLabel
+	labelIsExplicit bool
+	shape           nodeShape
+	styleClass      string
+}
+
+type textEdge struct {
+	parent textNode
+	child  textNode
+	flowLink
+	// index counts the links in the order they were defined, which is how
+	// linkStyle refers to them.
+	index int
+	// line is the line of mermaid the link is on.
+	line int
+}
+
+type textSubgraph struct {
+	id    string
+	name  string
+	label graphLabel
+	// direction is set by a direction statement inside the subgraph; empty
+	// means its nodes are laid out like the enclosing graph.
+	direction string
+	nodes     []string
+	parent    *textSubgraph
+	children  []*textSubgraph
+}
+
+func parseSubgraphHeader(header string) textSubgraph {
+	trimmed := strings.TrimSpace(header)
+	labelText := trimmed
+	id := ""
+
+	if match := regexp.MustCompile(`^(\S+)\s*\[(.+)\]$`).FindStringSubmatch(trimmed); match != nil {
+		id = strings.TrimSpace(match[1])
+		labelText = strings.TrimSpace(match[2])
+		labelText = strings.Trim(labelText, `"`)
+	}
+
+	return textSubgraph{
+		id:    id,
+		name:  labelText,
+		label: newGraphLabel(labelText),
+		nodes: []string{},
+	}
+}
+
+func splitGraphLines(mermaid string) []string {
+	lines := []string{}
+	var current strings.Builder
+	bracketDepth := 0
+	inQuotes := false
+
+	for i := 0; i < len(mermaid); i++ {
+		switch mermaid[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '[':
+			if !inQuotes {
+				bracketDepth++
+			}
+		case ']':
+			if !inQuotes && bracketDepth > 0 {
+				bracketDepth--
+			}
+		case '\n':
+			if bracketDepth == 0 {
+				lines = append(lines, current.String())
+				current.Reset()
+				continue
+			}
+		case '\\':
+			if i+1 < len(mermaid) && mermaid[i+1] == 'n' && bracketDepth == 0 {
+				lines = append(lines, current.String())
+				current.Reset()
+				i++
+				continue
+			}
+		}
+
+		current.WriteByte(mermaid[i])
+	}
+
+	return append(lines, current.String())
+}
+
+// shapeAttributesRegex matches one `key: value` pair of a `A@{ ... }` node
+// declaration; the value may be double-quoted to hold commas.
+var shapeAttributesRegex = regexp.MustCompile(`(\w+)\s*:\s*("[^"]*"|[^,]*)`)
+
+func parseNode(line string) textNode {
+	// Trim any whitespace from the line that might be left after comment removal
+	trimmedLine := strings.TrimSpace(line)
+	styleClass := ""
+	if idx := strings.LastIndex(trimmedLine, ":::"); idx != -1 {
+		styleClass = strings.TrimSpace(trimmedLine[idx+3:])
+		trimmedLine = strings.TrimSpace(trimmedLine[:idx])
+	}
+
+	// A@{ shape: circle, label: "Text" }
+	if open := strings.Index(trimmedLine, "@{"); open > 0 && strings.HasSuffix(trimmedLine, "}") {
+		node := textNode{name: strings.TrimSpace(trimmedLine[:open]), hasShape: true, styleClass: styleClass}
+		node.label = newGraphLabel(node.name)
+		for _, match := range shapeAttributesRegex.FindAllStringSubmatch(trimmedLine[open+2:len(trimmedLine)-1], -1) {
+			value := strings.Trim(strings.TrimSpace(match[2]), `"`)
+			switch match[1] {
+			case "shape":
+				shape, ok := lookupNodeShape(value)
+				if !ok {
+					log.Warnf("Unknown node shape %q, drawing a rectangle", value)
+				}
+				node.shape = shape
+			case "label":
+				node.label = newGraphLabel(value)
+				node.hasLabel = true
+			}
+		}
+		return node
+	}
+
+	// A[text], A(text), A{text}, A>text] and the other bracket pairs.
+	if open := strings.IndexAny(trimmedLine, "[({>"); open > 0 {
+		name := strings.TrimSpace(trimmedLine[:open])
+		body := trimmedLine[open:]
+		for _, d := range nodeShapeDelimiters {
+			if len(body) < len(d.open)+len(d.close) || !strings.HasPrefix(body, d.open) || !strings.HasSuffix(body, d.close) {
+				continue
+			}
+			labelText := strings.TrimSpace(body[len(d.open) : len(body)-len(d.close)])
+			labelText = strings.Trim(labelText, `"`)
+			return textNode{name: name, label: newGraphLabel(labelText), hasLabel: true, shape: d.shape, hasShape: true, styleClass: styleClass}
+		}
+	}
+
+	return textNode{name: trimmedLine, label: newGraphLabel(trimmedLine), styleClass: styleClass}
+}
+
+func parseStyleClass(matchedLine []string) styleClass {
+	return styleClass{matchedLine[0], parseStyles(matchedLine[1])}
+}
+
+// parseStyles parses the CSS-like properties of a classDef, style or
+// linkStyle statement.
+func parseStyles(styles string) map[string]string {
+	// Styles are comma separated and key-values are separated by colon
+	// Example: fill:#f9f,stroke:#333,stroke-width:4px
+	styleMap := make(map[string]string)
+	for _, style := range strings.Split(strings.TrimRight(styles, "; "), ",") {
+		key, value, ok := strings.Cut(style, ":")
+		if !ok {
+			continue
+		}
+		styleMap[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return styleMap
+}
+
+// mergeStyles copies the properties of styles over those of base, into a
+// new map.
+func mergeStyles(base map[string]string, styles ...map[string]string) map[string]string {
+	merged := make(map[string]string, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for _, s := range styles {
+		for k, v := range s {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// setLink adds an edge for link from every node in lhs to every node in rhs.
+func setLink(lhs, rhs []textNode, link flowLink, gp *graphProperties) {
+	log.Debug("Setting link from ", lhs, " to ", rhs, " with label ", link.label)
+	for _, l := range lhs {
+		for _, r := range rhs {
+			setData(l, textEdge{parent: l, child: r, flowLink: link, index: gp.linkCount, line: gp.line}, gp.data, gp.nodeSpecs)
+			gp.linkCount++
+		}
+	}
+}
+
+func rememberNode(node textNode, nodeSpecs map[string]graphNodeSpec) {
+	spec := nodeSpecs[node.name]
+	if node.hasLabel || len(spec.label.lines) == 0 {
+		spec.label = node.label
+		spec.labelIsExplicit = node.hasLabel
+	}
+	if node.hasShape {
+		spec.shape = node.shape
+	}
+	if node.styleClass != "" {
+		spec.styleClass = node.styleClass
+	}
+	nodeSpecs[node.name] = spec
+}
+
+func addNode(node textNode, data *orderedmap.OrderedMap[string, []textEdge], nodeSpecs map[string]graphNodeSpec) {
+	rememberNode(node, nodeSpecs)
+	if _, ok := data.Get(node.name); !ok {
+		data.Set(node.name, []textEdge{})
+	}
+}
+
+func setData(parent textNode, edge textEdge, data *orderedmap.OrderedMap[string, []textEdge], nodeSpecs map[string]graphNodeSpec) {
+	rememberNode(parent, nodeSpecs)
+	rememberNode(edge.child, nodeSpecs)
+	// Check if the parent is in the map
+	if children, ok := data.Get(parent.name); ok {
+		// If it is, append the child to the list of children
+		data.Set(parent.name, append(children, edge))
+	} else {
+		// If it isn't, add it to the map
+		data.Set(parent.name, []textEdge{edge})
+	}
+	// Check if the child is in the map
+	if _, ok := data.Get(edge.child.name); ok {
+		// If it is, do nothing
+	} else {
+		// If it isn't, add it to the map
+		data.Set(edge.child.name, []textEdge{})
+	}
+}
+
+var (
+	classDefRegex  = regexp.MustCompile(`^classDef\s+(\S+)\s+(.+)$`)
+	classRegex     = regexp.MustCompile(`^class\s+([\w.-]+(?:\s*,\s*[\w.-]+)*)\s+([\w-]+)\s*;?$`)
+	styleRegex     = regexp.MustCompile(`^style\s+([\w.-]+)\s+(.+)$`)
+	linkStyleRegex = regexp.MustCompile(`^linkStyle\s+(default|\d+(?:\s*,\s*\d+)*)\s+(.+)$`)
+)
+
+// parseStyleStatement applies a class, style or linkStyle statement, and
+// reports whether line was one.
+func (gp *graphProperties) parseStyleStatement(line string) bool {
+	line = strings.TrimSpace(line)
+	if match := classRegex.FindStringSubmatch(line); match != nil {
+		for _, name := range strings.Split(match[1], ",") {
+			name = strings.TrimSpace(name)
+			spec := gp.nodeSpecs[name]
+			spec.styleClass = match[2]
+			gp.nodeSpecs[name] = spec
+		}
+		return true
+	}
+	if match := styleRegex.FindStringSubmatch(line); match != nil {
+		gp.nodeStyles[match[1]] = mergeStyles(gp.nodeStyles[match[1]], parseStyles(match[2]))
+		return true
+	}
+	if match := linkStyleRegex.FindStringSubmatch(line); match != nil {
+		styles := parseStyles(match[2])
+		if match[1] == "default" {
+			gp.linkStyles[defaultLinkStyle] = mergeStyles(gp.linkStyles[defaultLinkStyle], styles)
+			return true
+		}
+		for _, index := range strings.Split(match[1], ",") {
+			i, _ := strconv.Atoi(strings.TrimSpace(index))
+			gp.linkStyles[i] = mergeStyles(gp.linkStyles[i], styles)
+		}
+		return true
+	}
+	return false
+}
+
+// parseString parses a line of nodes and the links between them, like
+// A --> B & C -- text --> D, and returns the nodes on it. It fails for lines
+// without a link or &, which are a single node.
+func (gp *graphProperties) parseString(line string) ([]textNode, error) {
+	log.Debugf("Parsing line: %v", line)
+	if strings.TrimSpace(line) == "" {
+		return []textNode{}, nil
+	}
+	if match := classDefRegex.FindStringSubmatch(line); match != nil {
+		s := parseStyleClass(match[1:])
+		(*gp.styleClasses)[s.name] = s
+		return []textNode{}, nil
+	}
+	if gp.parseStyleStatement(line) {
+		return []textNode{}, nil
+	}
+
+	groups, links := splitLinks(line)
+	if len(links) == 0 && !strings.Contains(line, "&") {
+		return []textNode{}, errors.New("Could not parse line: " + line)
+	}
+	nodeGroups := make([][]textNode, len(groups))
+	for i, group := range groups {
+		for _, text := range splitNodeGroup(group) {
+			if text == "" {
+				return []textNode{}, errors.New("Could not parse line: " + line)
+			}
+			nodeGroups[i] = append(nodeGroups[i], parseNode(text))
+		}
+	}
+	if len(links) == 0 {
+		log.Debugf("Found & pattern nodes %v", nodeGroups[0])
+		return nodeGroups[0], nil
+	}
+	for i, link := range links {
+		setLink(nodeGroups[i], nodeGroups[i+1], link, gp)
+	}
+	return nodeGroups[len(nodeGroups)-1], nil
+}
+
+func mermaidFileToMap(mermaid, styleType string) (*graphProperties, error) {
+	rawLines := splitGraphLines(mermaid)
+
+	// Process lines to remove comments. lineNumbers holds the line of mermaid
+	// each kept line starts on.
+	lines := []string{}
+	lineNumbers := []int{}
+	lineNumber := 1
+	for _, line := range rawLines {
+		start := lineNumber
+		lineNumber += 1 + strings.Count(line, "\n")
+		// Stop processing at "---" separator (used in test files)
+		if line == "---" {
+			break
+		}
+
+		// Skip lines that start with %% (comment lines)
+		if strings.HasPrefix(strings.TrimSpace(line), "%%") {
+			continue
+		}
+
+		// Remove inline comments (anything after %%) and trim resulting whitespace
+		if idx := strings.Index(line, "%%"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+
+		// Skip empty lines after comment removal
+		if len(strings.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+			lineNumbers = append(lineNumbers, start)
+		}
+	}
+
+	data := orderedmap.NewOrderedMap[string, []textEdge]()
+	styleClasses := make(map[string]styleClass)
+	defaults := diagram.DefaultConfig()
+	properties := graphProperties{
+		data:             data,
+		nodeSpecs:        make(map[string]graphNodeSpec),
+		styleClasses:     &styleClasses,
+		boxBorderPadding: defaults.BoxBorderPadding,
+		graphDirection:   "",
+		styleType:        styleType,
+		paddingX:         defaults.PaddingBetweenX,
+		paddingY:         defaults.PaddingBetweenY,
+		subgraphs:        []*textSubgraph{},
+		nodeStyles:       make(map[string]map[string]string),
+		linkStyles:       make(map[int]map[string]string),
+	}
+
+	// Pick up optional padding directives before the graph definition
+	paddingRegex := regexp.MustCompile(`^(?i)padding([xy])\s*=\s*(\d+)$`)
+	for len(lines) > 0 {
+		trimmed := strings.TrimSpace(lines[0])
+		if trimmed == "" {
+			lines, lineNumbers = lines[1:], lineNumbers[1:]
+			continue
+		}
+		if match := paddingRegex.FindStringSubmatch(trimmed); match != nil {
+			paddingValue, err := strconv.Atoi(match[2])
+			if err != nil {
+				return &properties, err
+			}
+			if strings.EqualFold(match[1], "x") {
+				properties.paddingX = paddingValue
+			} else {
+				properties.paddingY = paddingValue
+			}
+			lines, lineNumbers = lines[1:], lineNumbers[1:]
+			continue
+		}
+		break
+	}
+
+	if len(lines) == 0 {
+		return &properties, errors.New("missing graph definition")
+	}
+
+	// The first line declares the diagram: "graph" or "flowchart" followed by an
+	// optional direction (e.g. "flowchart LR", "graph TD", or a bare "graph").
+	// strings.Fields collapses any surrounding or repeated whitespace, so
+	// indented or trailing-padded declarations parse correctly; TrimRight drops a
+	// trailing separator (mermaid allows "graph TD;").
+	fields := strings.Fields(strings.TrimRight(lines[0], "; \t\r"))
+	if len(fields) == 0 || (fields[0] != "graph" && fields[0] != "flowchart") {
+		return &properties, diagram.LineErrorf(lineNumbers[0], "unsupported graph type '%s'. Supported types: 'graph' or 'flowchart' with an optional direction (TD, TB, BT, LR, RL)", strings.TrimSpace(lines[0]))
+	}
+	if len(fields) > 2 {
+		return &properties, diagram.LineErrorf(lineNumbers[0], "unexpected tokens after graph direction: %q", strings.Join(fields[2:], " "))
+	}
+
+	// Mermaid defaults to top-down when no direction is given. TB is another
+	// name for TD; RL and BT are laid out like LR and TD and then mirrored.
+	properties.graphDirection = "TD"
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "LR", "RL", "TD", "BT":
+			properties.graphDirection = fields[1]
+		case "TB":
+			properties.graphDirection = "TD"
+		default:
+			return &properties, diagram.LineErrorf(lineNumbers[0], "unsupported graph direction '%s'. Supported directions: TD, TB, BT, LR, RL", fields[1])
+		}
+	}
+	lines, lineNumbers = lines[1:], lineNumbers[1:]
+
+	// Track subgraph context using a stack
+	subgraphStack := []*textSubgraph{}
+	subgraphRegex := regexp.MustCompile(`^\s*subgraph\s+(.+)$`)
+	endRegex := regexp.MustCompile(`^\s*end\s*$`)
+	directionRegex := regexp.MustCompile(`^direction\s+(TB|TD|BT|LR|RL)\s*;?$`)
+
+	// Iterate over the lines
+	for i, line := range lines {
+		properties.line = lineNumbers[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check for subgraph start
+		if match := subgraphRegex.FindStringSubmatch(trimmedLine); match != nil {
+			header := parseSubgraphHeader(match[1])
+			newSubgraph := &textSubgraph{
+				id:       header.id,
+				name:     header.name,
+				label:    header.label,
+				nodes:    []string{},
+				children: []*textSubgraph{},
+			}
+
+			// Set parent relationship if we're nested
+			if len(subgraphStack) > 0 {
+				parent := subgraphStack[len(subgraphStack)-1]
+				newSubgraph.parent = parent
+				parent.children = append(parent.children, newSubgraph)
+			}
+
+			subgraphStack = append(subgraphStack, newSubgraph)
+			properties.subgraphs = append(properties.subgraphs, newSubgraph)
+			log.Debugf("Started subgraph %s", newSubgraph.name)
+			continue
+		}
+
+		// Check for subgraph end
+		if endRegex.MatchString(trimmedLine) {
+			if len(subgraphStack) > 0 {
+				closedSubgraph := subgraphStack[len(subgraphStack)-1]
+				subgraphStack = subgraphStack[:len(subgraphStack)-1]
+				log.Debugf("Ended subgraph %s", closedSubgraph.name)
+			}
+			continue
+		}
+
+		// A direction statement lays out the subgraph it's in along its own
+		// axis. Outside of a subgraph the header sets the direction, and it's
+		// ignored.
+		if match := directionRegex.FindStringSubmatch(trimmedLine); match != nil {
+			if len(subgraphStack) == 0 {
+				log.Debugf("Ignoring direction %s outside of a subgraph", match[1])
+				continue
+			}
+			direction := match[1]
+			if direction == "TB" {
+				direction = "TD"
+			}
+			subgraphStack[len(subgraphStack)-1].direction = direction
+			continue
+		}
+
+		// Remember nodes before parsing this line
+		existingNodes := make(map[string]bool)
+		for el := data.Front(); el != nil; el = el.Next() {
+			existingNodes[el.Key] = true
+		}
+
+		// Parse nodes and edges normally
+		nodes, err := properties.parseString(line)
+		if err != nil {
+			log.Debugf("Parsing remaining text to node %v", line)
+			node := parseNode(line)
+			addNode(node, properties.data, properties.nodeSpecs)
+		} else {
+			// Ensure all returned nodes are in the map
+			for _, node := range nodes {
+				addNode(node, properties.data, properties.nodeSpecs)
+			}
+		}
+
+		// Add all new nodes to current subgraph(s)
+		if len(subgraphStack) > 0 {
+			for el := data.Front(); el != nil; el = el.Next() {
+				nodeName := el.Key
+				// If this is a new node (wasn't in existingNodes), add it to subgraph
+				if !existingNodes[nodeName] {
+					for _, sg := range subgraphStack {
+						// Check if node is not already in the subgraph
+						found := false
+						for _, n := range sg.nodes {
+							if n == nodeName {
+								found = true
+								break
+							}
+						}
+						if !found {
+							sg.nodes = append(sg.nodes, nodeName)
+							log.Debugf("Added node %s to subgraph %s", nodeName, sg.name)
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := properties.checkSubgraphLinks(); err != nil {
+		return &properties, err
+	}
+	return &properties, nil
+}
+
+// checkSubgraphLinks rejects links between a subgraph and a node or subgraph
+// inside it: the frame has no side that faces the other end to draw the
+// link from.
+func (gp *graphProperties) checkSubgraphLinks() error {
+	for _, sg := range gp.subgraphs {
+		id := sg.linkID()
+		inside := func(name string) bool {
+			if name == id {
+				return false
+			}
+			if slices.Contains(sg.nodes, name) {
+				return true
+			}
+			for _, other := range gp.subgraphs {
+				for p := other.parent; p != nil; p = p.parent {
+					if p == sg && other.linkID() == name {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		for el := gp.data.Front(); el != nil; el = el.Next() {
+			for _, e := range el.Value {
+				switch {
+				case e.parent.name == id && inside(e.child.name):
+					return diagram.LineErrorf(e.line, "subgraph %s can't link to %s, which is inside it", id, e.child.name)
+				case e.child.name == id && inside(e.parent.name):
+					return diagram.LineErrorf(e.line, "%s can't link to subgraph %s, which it is inside", e.parent.name, id)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// linkID is the name links refer to sg by: its ID, or its title when it has
+// none.
+func (sg *textSubgraph) linkID() string {
+	if sg.id == "" {
+		return sg.name
+	}
+	return sg.id
+}
@@ -0,0 +1,450 @@
+package graph
+
+import "testing"
+
+func TestSplitGraphLines(t *testing.T) {
+	input := "graph LR\\nA[\"line1\\nline2\"] --> B\\nC --> D"
+
+	got := splitGraphLines(input)
+	want := []string{"graph LR", `A["line1\nline2"] --> B`, "C --> D"}
+
+	if len(got) != len(want) {
+		t.Fatalf("line count = %d, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitGraphLinesKeepsLiteralNewlineInsideNodeLabel(t *testing.T) {
+	input := "graph LR\nA[\"line1\nline2\"] --> B\nC --> D"
+
+	got := splitGraphLines(input)
+	want := []string{"graph LR", "A[\"line1\nline2\"] --> B", "C --> D"}
+
+	if len(got) != len(want) {
+		t.Fatalf("line count = %d, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseNodeWithExplicitLabel(t *testing.T) {
+	node := parseNode(`A["line1<br/>line2"]:::primary`)
+
+	if node.name != "A" {
+		t.Fatalf("name = %q, want %q", node.name, "A")
+	}
+	if node.styleClass != "primary" {
+		t.Fatalf("styleClass = %q, want %q", node.styleClass, "primary")
+	}
+	if len(node.label.lines) != 2 {
+		t.Fatalf("label lines = %d, want 2", len(node.label.lines))
+	}
+	if node.label.lines[0] != "line1" || node.label.lines[1] != "line2" {
+		t.Fatalf("label lines = %#v, want [line1 line2]", node.label.lines)
+	}
+}
+
+func TestParseNodeShapes(t *testing.T) {
+	tests := []struct {
+		input string
+		shape nodeShape
+		label string
+	}{
+		{"A[text]", shapeRect, "text"},
+		{"A(text)", shapeRound, "text"},
+		{"A([text])", shapeStadium, "text"},
+		{"A[[text]]", shapeSubroutine, "text"},
+		{"A[(text)]", shapeCylinder, "text"},
+		{"A((text))", shapeCircle, "text"},
+		{"A(((text)))", shapeDoubleCircle, "text"},
+		{"A{text}", shapeRhombus, "text"},
+		{"A{{text}}", shapeHexagon, "text"},
+		{"A[/text/]", shapeLeanRight, "text"},
+		{`A[\text\]`, shapeLeanLeft, "text"},
+		{`A[/text\]`, shapeTrapezoid, "text"},
+		{`A[\text/]`, shapeInvTrapezoid, "text"},
+		{"A>text]", shapeAsymmetric, "text"},
+		{`A(("quoted (text)"))`, shapeCircle, "quoted (text)"},
+		{"A@{ shape: circle }", shapeCircle, "A"},
+		{`A@{ shape: lean-r, label: "Input, output" }`, shapeLeanRight, "Input, output"},
+		{"A@{ shape: db }", shapeCylinder, "A"},
+		{"A@{ shape: no-such-shape }", shapeRect, "A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node := parseNode(tt.input)
+			if node.name != "A" {
+				t.Fatalf("name = %q, want %q", node.name, "A")
+			}
+			if !node.hasShape || node.shape != tt.shape {
+				t.Fatalf("shape = %v (hasShape %v), want %v", node.shape, node.hasShape, tt.shape)
+			}
+			if len(node.label.lines) != 1 || node.label.lines[0] != tt.label {
+				t.Fatalf("label lines = %#v, want [%s]", node.label.lines, tt.label)
+			}
+		})
+	}
+}
+
+func TestMermaidFileToMapKeepsShapeAcrossBareReferences(t *testing.T) {
+	properties, err := mermaidFileToMap("graph TD\nA{Decide} --> B\nB --> A", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	if shape := properties.nodeSpecs["A"].shape; shape != shapeRhombus {
+		t.Fatalf("shape = %v, want %v", shape, shapeRhombus)
+	}
+	if shape := properties.nodeSpecs["B"].shape; shape != shapeRect {
+		t.Fatalf("shape = %v, want %v", shape, shapeRect)
+	}
+}
+
+func TestMermaidFileToMapPreservesEscapedLabelNewlines(t *testing.T) {
+	properties, err := mermaidFileToMap("graph LR\\nA[\"line1\\nline2\"] --> B", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	spec := properties.nodeSpecs["A"]
+	if len(spec.label.lines) != 2 {
+		t.Fatalf("label lines = %d, want 2", len(spec.label.lines))
+	}
+	if spec.label.lines[0] != "line1" || spec.label.lines[1] != "line2" {
+		t.Fatalf("label lines = %#v, want [line1 line2]", spec.label.lines)
+	}
+}
+
+func TestMermaidFileToMapPreservesLiteralLabelNewlines(t *testing.T) {
+	properties, err := mermaidFileToMap("graph LR\nA[\"line1\nline2\"] --> B", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	spec := properties.nodeSpecs["A"]
+	if len(spec.label.lines) != 2 {
+		t.Fatalf("label lines = %d, want 2", len(spec.label.lines))
+	}
+	if spec.label.lines[0] != "line1" || spec.label.lines[1] != "line2" {
+		t.Fatalf("label lines = %#v, want [line1 line2]", spec.label.lines)
+	}
+}
+
+func TestParseSubgraphHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantID    string
+		wantLabel string
+	}{
+		{
+			name:      "plain title",
+			header:    "Frontend",
+			wantID:    "",
+			wantLabel: "Frontend",
+		},
+		{
+			name:      "explicit id and title",
+			header:    "frontend [Frontend Services]",
+			wantID:    "frontend",
+			wantLabel: "Frontend Services",
+		},
+		{
+			name:      "quoted title",
+			header:    `frontend["Frontend Services"]`,
+			wantID:    "frontend",
+			wantLabel: "Frontend Services",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sg := parseSubgraphHeader(tt.header)
+			if sg.id != tt.wantID {
+				t.Fatalf("id = %q, want %q", sg.id, tt.wantID)
+			}
+			if sg.name != tt.wantLabel {
+				t.Fatalf("name = %q, want %q", sg.name, tt.wantLabel)
+			}
+			if len(sg.label.lines) != 1 || sg.label.lines[0] != tt.wantLabel {
+				t.Fatalf("label lines = %#v, want [%s]", sg.label.lines, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestMermaidFileToMapParsesSubgraphIDAndTitle(t *testing.T) {
+	properties, err := mermaidFileToMap("graph LR\nsubgraph frontend [Frontend Services]\nA --> B\nend", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	if len(properties.subgraphs) != 1 {
+		t.Fatalf("subgraphs = %d, want 1", len(properties.subgraphs))
+	}
+
+	sg := properties.subgraphs[0]
+	if sg.id != "frontend" {
+		t.Fatalf("id = %q, want %q", sg.id, "frontend")
+	}
+	if sg.name != "Frontend Services" {
+		t.Fatalf("name = %q, want %q", sg.name, "Frontend Services")
+	}
+}
+
+func TestMermaidFileToMapKeepsExplicitNodeLabelAcrossBareReferences(t *testing.T) {
+	properties, err := mermaidFileToMap("graph TD\nA[\"Foo\"] --> B[\"Bar\"]\nB --> C[\"Baz\"]", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	spec := properties.nodeSpecs["B"]
+	if len(spec.label.lines) != 1 || spec.label.lines[0] != "Bar" {
+		t.Fatalf("label lines = %#v, want [Bar]", spec.label.lines)
+	}
+	if !spec.labelIsExplicit {
+		t.Fatal("expected B label to remain explicit")
+	}
+}
+
+func TestMermaidFileToMapUsesLatestExplicitLabel(t *testing.T) {
+	properties, err := mermaidFileToMap("graph TD\nA[\"Old\"] --> B\nA[\"New\"] --> C", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	spec := properties.nodeSpecs["A"]
+	if len(spec.label.lines) != 1 || spec.label.lines[0] != "New" {
+		t.Fatalf("label lines = %#v, want [New]", spec.label.lines)
+	}
+	if !spec.labelIsExplicit {
+		t.Fatal("expected A label to remain explicit")
+	}
+}
+
+// Tests non-standard arrow operators.
+func TestMermaidFileToMapParsesNonStandardEdgeOperators(t *testing.T) {
+	for _, op := range []string{"-->", "-.->", "==>", "---", "--o", "--x"} {
+		t.Run(op, func(t *testing.T) {
+			properties, err := mermaidFileToMap("graph LR\nA "+op+" B", "cli")
+			if err != nil {
+				t.Fatalf("mermaidFileToMap() error = %v", err)
+			}
+			edges, ok := properties.data.Get("A")
+			if !ok || len(edges) != 1 || edges[0].child.name != "B" {
+				t.Fatalf("edges from A = %#v, want one edge to B", edges)
+			}
+		})
+	}
+}
+
+func TestMermaidFileToMapParsesChainedNonStandardEdges(t *testing.T) {
+	input := "graph LR\nA -.-> B\nB ==> C\nC --- D\nD --o E\nE --x F"
+	properties, err := mermaidFileToMap(input, "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	for _, link := range [][2]string{{"A", "B"}, {"B", "C"}, {"C", "D"}, {"D", "E"}, {"E", "F"}} {
+		edges, ok := properties.data.Get(link[0])
+		if !ok || len(edges) != 1 || edges[0].child.name != link[1] {
+			t.Fatalf("edges from %q = %#v, want one edge to %q", link[0], edges, link[1])
+		}
+	}
+}
+
+func TestSplitLinks(t *testing.T) {
+	tests := []struct {
+		line   string
+		groups []string
+		link   flowLink
+	}{
+		{"A --> B", []string{"A ", " B"}, flowLink{stroke: strokeSolid, head: headArrow, length: 1}},
+		{"A---->B", []string{"A", "B"}, flowLink{stroke: strokeSolid, head: headArrow, length: 3}},
+		{"A --- B", []string{"A ", " B"}, flowLink{stroke: strokeSolid, head: headNone, length: 1}},
+		{"A ==== B", []string{"A ", " B"}, flowLink{stroke: strokeThick, head: headNone, length: 2}},
+		{"A -.- B", []string{"A ", " B"}, flowLink{stroke: strokeDotted, head: headNone, length: 1}},
+		{"A -...-> B", []string{"A ", " B"}, flowLink{stroke: strokeDotted, head: headArrow, length: 3}},
+		{"A ~~~ B", []string{"A ", " B"}, flowLink{stroke: strokeInvisible, head: headNone, length: 1}},
+		{"A <==> B", []string{"A ", " B"}, flowLink{stroke: strokeThick, head: headArrow, isBidirectional: true, length: 1}},
+		{"A o--o B", []string{"A ", " B"}, flowLink{stroke: strokeSolid, head: headCircle, isBidirectional: true, length: 1}},
+		{"A x--x B", []string{"A ", " B"}, flowLink{stroke: strokeSolid, head: headCross, isBidirectional: true, length: 1}},
+		{"A o--x B", []string{"A ", " B"}, flowLink{stroke: strokeSolid, head: headCross, length: 1}},
+		{"box--x B", []string{"box", " B"}, flowLink{stroke: strokeSolid, head: headCross, length: 1}},
+		{"A -->|yes| B", []string{"A ", " B"}, flowLink{label: "yes", stroke: strokeSolid, head: headArrow, length: 1}},
+		{"A -- yes --> B", []string{"A ", " B"}, flowLink{label: "yes", stroke: strokeSolid, head: headArrow, length: 1}},
+		{"A -- two words ---> B", []string{"A ", " B"}, flowLink{label: "two words", stroke: strokeSolid, head: headArrow, length: 2}},
+		{"A == hot ==> B", []string{"A ", " B"}, flowLink{label: "hot", stroke: strokeThick, head: headArrow, length: 1}},
+		{"A -. maybe .-> B", []string{"A ", " B"}, flowLink{label: "maybe", stroke: strokeDotted, head: headArrow, length: 1}},
+		{"A <-- both --> B", []string{"A ", " B"}, flowLink{label: "both", stroke: strokeSolid, head: headArrow, isBidirectional: true, length: 1}},
+		{`A["a --> b"] --> B(c -- d)`, []string{`A["a --> b"] `, " B(c -- d)"}, flowLink{stroke: strokeSolid, head: headArrow, length: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			groups, links := splitLinks(tt.line)
+			if len(links) != 1 || links[0] != tt.link {
+				t.Fatalf("links = %+v, want [%+v]", links, tt.link)
+			}
+			if len(groups) != 2 || groups[0] != tt.groups[0] || groups[1] != tt.groups[1] {
+				t.Fatalf("groups = %q, want %q", groups, tt.groups)
+			}
+		})
+	}
+}
+
+func TestMermaidFileToMapParsesLinkChainsWithGroups(t *testing.T) {
+	properties, err := mermaidFileToMap("graph LR\nA & B -- go --> C[x & y] ~~~ D", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	for _, from := range []string{"A", "B"} {
+		edges, _ := properties.data.Get(from)
+		if len(edges) != 1 || edges[0].child.name != "C" || edges[0].label != "go" {
+			t.Fatalf("edges from %q = %+v, want one edge to C labelled go", from, edges)
+		}
+	}
+	edges, _ := properties.data.Get("C")
+	if len(edges) != 1 || edges[0].child.name != "D" || edges[0].stroke != strokeInvisible {
+		t.Fatalf("edges from C = %+v, want one invisible edge to D", edges)
+	}
+	if label := properties.nodeSpecs["C"].label.lines; len(label) != 1 || label[0] != "x & y" {
+		t.Fatalf("C label = %q, want x & y", label)
+	}
+}
+
+func TestMermaidFileToMapParsesStyleStatements(t *testing.T) {
+	input := "graph LR\nA --> B & C\nclass A,C hot\nstyle B fill:#f9f, stroke-dasharray: 5 5\nlinkStyle 0,1 stroke:red\nlinkStyle default color:blue"
+	properties, err := mermaidFileToMap(input, "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	if properties.data.Len() != 3 {
+		t.Fatalf("style statements should not add nodes, got %v", properties.data.Keys())
+	}
+	if properties.nodeSpecs["A"].styleClass != "hot" || properties.nodeSpecs["C"].styleClass != "hot" {
+		t.Errorf("class statement not applied: %+v", properties.nodeSpecs)
+	}
+	if styles := properties.nodeStyles["B"]; styles["fill"] != "#f9f" || styles["stroke-dasharray"] != "5 5" {
+		t.Errorf("style B = %v", styles)
+	}
+	edges, _ := properties.data.Get("A")
+	if len(edges) != 2 || edges[0].index != 0 || edges[1].index != 1 {
+		t.Fatalf("edges from A = %+v, want links 0 and 1", edges)
+	}
+	if properties.linkStyles[1]["stroke"] != "red" || properties.linkStyles[defaultLinkStyle]["color"] != "blue" {
+		t.Errorf("linkStyles = %v", properties.linkStyles)
+	}
+}
+
+func TestMermaidFileToMapParsesClassDefWithSpacesInStyles(t *testing.T) {
+	input := "graph LR\nA --> B\nclassDef hot stroke:red,stroke-dasharray: 5 5\nclass A hot"
+	properties, err := mermaidFileToMap(input, "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+
+	class, ok := (*properties.styleClasses)["hot"]
+	if !ok {
+		t.Fatalf("classDef hot was dropped: %v", *properties.styleClasses)
+	}
+	if class.styles["stroke"] != "red" || class.styles["stroke-dasharray"] != "5 5" {
+		t.Errorf("classDef hot styles = %v", class.styles)
+	}
+}
+
+func TestMermaidFileToMapParsesSubgraphDirection(t *testing.T) {
+	input := "graph TD\nsubgraph outer\ndirection LR\nA --> B\nsubgraph inner\n  direction TB;\n  C\nend\nend\nsubgraph plain\nD\nend"
+	properties, err := mermaidFileToMap(input, "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+	want := map[string]string{"outer": "LR", "inner": "TD", "plain": ""}
+	for _, sg := range properties.subgraphs {
+		if sg.direction != want[sg.name] {
+			t.Errorf("subgraph %s direction = %q, want %q", sg.name, sg.direction, want[sg.name])
+		}
+	}
+	if _, ok := properties.data.Get("direction LR"); ok {
+		t.Errorf("direction statement was parsed as a node")
+	}
+	if properties.graphDirection != "TD" {
+		t.Errorf("graph direction = %q, want TD", properties.graphDirection)
+	}
+}
+
+func TestMermaidFileToMapIgnoresTopLevelDirection(t *testing.T) {
+	properties, err := mermaidFileToMap("graph TD\ndirection LR\nA --> B", "cli")
+	if err != nil {
+		t.Fatalf("mermaidFileToMap() error = %v", err)
+	}
+	if properties.data.Len() != 2 {
+		t.Errorf("nodes = %v, want A and B", properties.data.Keys())
+	}
+	if properties.graphDirection != "TD" {
+		t.Errorf("graph direction = %q, want TD", properties.graphDirection)
+	}
+}
+
+// TestGraphTypeDetection verifies that the diagram declaration line is parsed
+// tolerantly: surrounding whitespace, a missing direction (defaults to
+// top-down), and the reverse directions RL/BT are all accepted.
+func TestGraphTypeDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantDir string
+		wantErr bool
+	}{
+		{"plain graph TD", "graph TD\nA --> B", "TD", false},
+		{"flowchart LR", "flowchart LR\nA --> B", "LR", false},
+		{"leading whitespace", "    flowchart LR\n    A --> B", "LR", false},
+		{"trailing whitespace", "graph LR    \nA --> B", "LR", false},
+		{"indented graph TD", "        graph TD\n        A --> B", "TD", false},
+		{"bare graph defaults to TD", "graph\nA --> B", "TD", false},
+		{"bare flowchart defaults to TD", "flowchart\nA --> B", "TD", false},
+		{"TB maps to TD", "flowchart TB\nA --> B", "TD", false},
+		{"RL stays right-to-left", "graph RL\nA --> B", "RL", false},
+		{"BT stays bottom-to-top", "flowchart BT\nA --> B", "BT", false},
+		{"trailing semicolon bare", "graph;\nA --> B", "TD", false},
+		{"trailing semicolon with direction", "graph TD;\nA --> B", "TD", false},
+		{"flowchart LR semicolon", "flowchart LR;\nA --> B", "LR", false},
+		{"CRLF line ending", "graph TD\r\nA --> B", "TD", false},
+		{"tab separator", "graph\tLR\nA --> B", "LR", false},
+		{"lowercase direction errors", "graph td\nA --> B", "", true},
+		{"uppercase graph keyword errors", "GRAPH TD\nA --> B", "", true}, // flowchart stays case-sensitive (mermaid parity)
+		{"extra tokens error", "graph TD foo\nA --> B", "", true},
+		{"unknown type errors", "sequenceDiagram\nA->>B: x", "", true},
+		{"unknown direction errors", "graph SIDEWAYS\nA --> B", "", true},
+		{"empty input errors", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props, err := mermaidFileToMap(tt.input, "cli")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got direction %q", props.graphDirection)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if props.graphDirection != tt.wantDir {
+				t.Errorf("direction = %q, want %q", props.graphDirection, tt.wantDir)
+			}
+		})
+	}
+}
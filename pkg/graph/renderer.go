@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// GraphDiagram is a parsed graph or flowchart diagram. It is never modified
+// by rendering, so one parsed diagram can be rendered concurrently with
+// different configs.
+type GraphDiagram struct {
+	properties *graphProperties
+}
+
+// Parse parses a mermaid graph or flowchart definition.
+func Parse(input string) (*GraphDiagram, error) {
+	properties, err := mermaidFileToMap(input, "cli")
+	if err != nil {
+		return nil, err
+	}
+	return &GraphDiagram{properties: properties}, nil
+}
+
+// Render draws the diagram as text.
+func Render(gd *GraphDiagram, config *diagram.Config) (string, error) {
+	properties, err := gd.configured(config)
+	if err != nil {
+		return "", err
+	}
+	return drawMap(properties)
+}
+
+// Layout lays the diagram out like Render and reports where its nodes, edges
+// and subgraphs ended up on the drawing.
+func Layout(gd *GraphDiagram, config *diagram.Config) (*diagram.Layout, error) {
+	properties, err := gd.configured(config)
+	if err != nil {
+		return nil, err
+	}
+	return graphLayout(properties)
+}
+
+// RenderSVG draws the diagram as an SVG image laid out like its text drawing,
+// with title above it when it is not empty.
+func RenderSVG(gd *GraphDiagram, config *diagram.Config, title string) (string, error) {
+	properties, err := gd.configured(config)
+	if err != nil {
+		return "", err
+	}
+	// Colours become SVG paint rather than escape codes in the labels.
+	properties.styleType = "svg"
+	g, err := mapGraph(properties)
+	if err != nil {
+		return "", err
+	}
+	maxX, maxY := getDrawingSize(g.draw())
+	return graphSVG(g, maxX+1, maxY+1, title), nil
+}
+
+// configured returns a copy of the parsed properties with the rendering
+// options from config applied.
+func (gd *GraphDiagram) configured(config *diagram.Config) (*graphProperties, error) {
+	if gd == nil || gd.properties == nil {
+		return nil, fmt.Errorf("graph diagram not parsed: call Parse() before rendering")
+	}
+	if config == nil {
+		config = diagram.DefaultConfig()
+	}
+
+	styleType := config.StyleType
+	if styleType == "" {
+		styleType = "cli"
+	}
+	properties := *gd.properties
+	properties.boxBorderPadding = config.BoxBorderPadding
+	properties.paddingX = config.PaddingBetweenX
+	properties.paddingY = config.PaddingBetweenY
+	properties.styleType = styleType
+	properties.useAscii = config.UseAscii
+	properties.showCoords = config.ShowCoords
+	properties.layout = config.Layout
+	properties.compact = config.Compact
+	return &properties, nil
+}
@@ -0,0 +1,405 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// Edges are routed one after the other. Every routed edge leaves its cells
+// behind in graph.routedCells, and the router makes later edges pay for
+// running along or across them, so they go around where that isn't much of a
+// detour. Edges that leave from or arrive at the same spot are free to share
+// a line, which branches off like a tree, unless the one routed first has a
+// label that would then be unclear to tell apart. Where other edges still end up
+// running alongside each other through the gaps between nodes, assignLanes
+// spreads them over parallel lanes, unless that would only make them cross.
+// The cells subgraph labels are drawn over are off limits, other than to
+// edges that start or end at that subgraph's frame.
+
+// cellUse records which way an edge routed through a cell runs.
+type cellUse uint8
+
+// routedCell is an edge running through a cell: which way it runs there,
+// where its path starts and ends and whether it has a label.
+type routedCell struct {
+	use        cellUse
+	start, end gridCoord
+	labelled   bool
+}
+
+const (
+	usedHorizontally cellUse = 1 << iota
+	usedVertically
+)
+
+const (
+	// overlapPenalty is what stepping onto a cell that an edge already runs
+	// through in the same direction costs on top of the step itself.
+	overlapPenalty = 2
+	// crossingPenalty is what crossing an edge costs.
+	crossingPenalty = 3
+	// laneSpacing is how far apart parallel lanes are on the drawing.
+	laneSpacing = 2
+)
+
+// resetRouting forgets all routed edges and marks the cells subgraph labels
+// are drawn over, ready to route the edges of the graph.
+func (g *graph) resetRouting() {
+	g.routedCells = map[gridCoord][]routedCell{}
+	g.subgraphLabelCells = map[gridCoord]*subgraph{}
+	for _, sg := range g.subgraphs {
+		if len(sg.nodes) == 0 || len(sg.label.lines) == 0 {
+			continue
+		}
+		box := edgeEndBox(sg.nodes[0], sg)
+		// The label sits at the top of the frame, which is the bottom of
+		// the grid in a mirrored BT graph.
+		row := box.minY - 1
+		if g.graphDirection == "BT" {
+			row = box.maxY + 1
+		}
+		// It's centred over the columns of the frame. An edge runs down the
+		// middle of a column; keep it a space clear of the label.
+		left, right := g.columnStart(box.minX), g.columnStart(box.maxX+1)
+		from := (left+right)/2 - sg.label.width/2
+		to := from + sg.label.width
+		for x := box.minX; x <= box.maxX; x++ {
+			if middle := g.columnStart(x) + g.columnWidth[x]/2; middle >= from-1 && middle <= to {
+				g.subgraphLabelCells[gridCoord{x: x, y: row}] = sg
+			}
+		}
+	}
+}
+
+// columnStart is the x on the drawing, before mirroring, where grid column x
+// starts.
+func (g *graph) columnStart(x int) int {
+	start := 0
+	for column := 0; column < x; column++ {
+		start += g.columnWidth[column]
+	}
+	return start
+}
+
+// markRouted records the cells e's path runs through.
+func (g *graph) markRouted(e *edge) {
+	path := e.path
+	if len(path) == 0 {
+		return
+	}
+	start, end := path[0], path[len(path)-1]
+	for i := 1; i < len(path); i++ {
+		use := usedVertically
+		if path[i-1].y == path[i].y {
+			use = usedHorizontally
+		}
+		for _, c := range segmentCells(path[i-1], path[i]) {
+			g.routedCells[c] = append(g.routedCells[c], routedCell{use: use, start: start, end: end, labelled: e.text != ""})
+		}
+	}
+}
+
+// segmentCells returns the cells from a to b, both included, which are on
+// the same row or column.
+func segmentCells(a, b gridCoord) []gridCoord {
+	step := gridCoord{}
+	switch {
+	case b.x > a.x:
+		step.x = 1
+	case b.x < a.x:
+		step.x = -1
+	case b.y > a.y:
+		step.y = 1
+	case b.y < a.y:
+		step.y = -1
+	}
+	cells := []gridCoord{a}
+	for c := a; c != b; {
+		c = gridCoord{x: c.x + step.x, y: c.y + step.y}
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+// stepPenalty is what stepping from current onto next costs on top of the
+// step itself, for a path from start to end. The cells next to either end
+// are free: edges that leave or arrive at the same side of a node share
+// them, unless the edge already there has a label.
+func (g *graph) stepPenalty(current, next, start, end gridCoord) int {
+	nearEnd := manhattan(next, start) <= 1 || manhattan(next, end) <= 1
+	penalty := 0
+	along := usedVertically
+	if current.y == next.y {
+		along = usedHorizontally
+	}
+	var use cellUse
+	for _, r := range g.routedCells[next] {
+		if r.labelled || (!nearEnd && r.start != start && r.end != end) {
+			use |= r.use
+		}
+	}
+	if use&along != 0 {
+		penalty += overlapPenalty
+	}
+	if use&^along != 0 {
+		penalty += crossingPenalty
+	}
+	return penalty
+}
+
+// pathPenalty adds up the penalties of every step along path, which may be
+// merged.
+func (g *graph) pathPenalty(path []gridCoord) int {
+	if len(path) < 2 {
+		return 0
+	}
+	start, end := path[0], path[len(path)-1]
+	penalty := 0
+	for i := 1; i < len(path); i++ {
+		cells := segmentCells(path[i-1], path[i])
+		for j := 1; j < len(cells); j++ {
+			penalty += g.stepPenalty(cells[j-1], cells[j], start, end)
+		}
+	}
+	return penalty
+}
+
+// span is how far apart the nodes e connects are on the grid. A self-loop
+// goes around its node's corner, further out than the edges beside it, so
+// its span is as long as there is.
+func (e *edge) span() int {
+	if e.from == e.to {
+		return math.MaxInt
+	}
+	return manhattan(*e.from.gridCoord, *e.to.gridCoord)
+}
+
+func manhattan(a, b gridCoord) int {
+	return Abs(a.x-b.x) + Abs(a.y-b.y)
+}
+
+// laneSegment is a stretch of an edge's path that can be moved sideways
+// into a lane of its own.
+type laneSegment struct {
+	e *edge
+	// index is that of the segment's first point in e.path.
+	index    int
+	vertical bool
+	// at is the column a vertical segment runs along, or the row of a
+	// horizontal one; from and to are the rows or columns it spans.
+	at, from, to int
+	lane         int
+}
+
+// assignLanes moves the stretches of edges that would run on top of each
+// other between the nodes into parallel lanes, widening the column or row
+// they run along to fit them. Stretches that leave or arrive at a node stay
+// where they are, so edges still meet nodes in the middle of their sides.
+func (g *graph) assignLanes() {
+	type line struct {
+		vertical bool
+		at       int
+	}
+	lines := map[line][]*laneSegment{}
+	var order []line
+	for _, e := range g.edges {
+		e.laneOffsets = nil
+		for i := 1; i+2 < len(e.path); i++ {
+			a, b := e.path[i], e.path[i+1]
+			if !g.segmentIsFree(a, b) {
+				continue
+			}
+			s := &laneSegment{e: e, index: i, vertical: a.x == b.x, at: a.y, from: Min(a.x, b.x), to: Max(a.x, b.x)}
+			if s.vertical {
+				s.at, s.from, s.to = a.x, Min(a.y, b.y), Max(a.y, b.y)
+			}
+			l := line{s.vertical, s.at}
+			if _, ok := lines[l]; !ok {
+				order = append(order, l)
+			}
+			lines[l] = append(lines[l], s)
+		}
+	}
+
+	for _, l := range order {
+		// Segments of edges between nearer nodes take the inner lanes, so
+		// parallel edges nest instead of crossing.
+		segments := lines[l]
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].e.span() < segments[j].e.span()
+		})
+		sizes, isNodeLine := g.rowHeight, g.isNodeRow
+		if l.vertical {
+			sizes, isNodeLine = g.columnWidth, g.isNodeColumn
+		}
+		for i, s := range segments {
+			taken := map[int]bool{}
+			for _, other := range segments[:i] {
+				// Stretches that only touch at an end still share a
+				// cell, which would leave it unclear where each goes on.
+				if s.from <= other.to && other.from <= s.to && !s.sharesEnd(other) && !s.mustCross(other) {
+					taken[other.lane] = true
+				}
+			}
+			for taken[s.lane] {
+				s.lane++
+			}
+			offset := laneOffset(s.lane)
+			if offset == 0 {
+				continue
+			}
+			if !laneFits(sizes[l.at], offset) {
+				if isNodeLine(l.at) {
+					// Widening the line would stretch the nodes on it.
+					s.lane = 0
+					continue
+				}
+				for !laneFits(sizes[l.at], offset) {
+					sizes[l.at]++
+				}
+			}
+			s.e.offsetSegment(s.index, s.vertical, offset)
+		}
+	}
+}
+
+// sharesEnd reports whether the edges of s and other leave from or arrive at
+// the same spot, in which case they may share a lane.
+func (s *laneSegment) sharesEnd(other *laneSegment) bool {
+	a, b := s.e.path, other.e.path
+	return a[0] == b[0] || a[len(a)-1] == b[len(b)-1]
+}
+
+// mustCross reports whether s and other come in from opposite sides at one
+// end and leave to opposite sides at the other, the other way round. Lanes
+// would only trade sharing the line for crossing each other, so they don't
+// get any.
+func (s *laneSegment) mustCross(other *laneSegment) bool {
+	a0, a1 := s.sides()
+	b0, b1 := other.sides()
+	return a0 != 0 && a1 != 0 && a0 == -b0 && a1 == -b1 && a0 == -a1
+}
+
+// sides is the side s's edge comes from at the lower end of s, and the side
+// it goes on to at the upper one: -1 for above or left of its line, 1 for
+// below or right of it, 0 where the edge ends there.
+func (s *laneSegment) sides() (int, int) {
+	path := s.e.path
+	side := func(end, beyond int) int {
+		if beyond < 0 || beyond >= len(path) {
+			return 0
+		}
+		if s.vertical {
+			return sign(path[beyond].x - path[end].x)
+		}
+		return sign(path[beyond].y - path[end].y)
+	}
+	before, after := side(s.index, s.index-1), side(s.index+1, s.index+2)
+	a, b := path[s.index], path[s.index+1]
+	if a.x+a.y > b.x+b.y {
+		return after, before
+	}
+	return before, after
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// laneOffset is how far lane is from the middle of its column or row: the
+// first lane runs through the middle, the next ones alternate either side.
+func laneOffset(lane int) int {
+	offset := (lane + 1) / 2 * laneSpacing
+	if lane%2 == 0 {
+		return -offset
+	}
+	return offset
+}
+
+// laneFits reports whether a lane offset from the middle of a column or row
+// of the given size is inside it, clear of the ones next to it.
+func laneFits(size, offset int) bool {
+	middle := size / 2
+	return middle+offset >= 1 && middle+offset < size-1
+}
+
+// offsetSegment moves the segment of e's path from point index to the next
+// one sideways by offset.
+func (e *edge) offsetSegment(index int, vertical bool, offset int) {
+	if e.laneOffsets == nil {
+		e.laneOffsets = make([]drawingCoord, len(e.path))
+	}
+	for _, i := range []int{index, index + 1} {
+		if vertical {
+			e.laneOffsets[i].x = offset
+		} else {
+			e.laneOffsets[i].y = offset
+		}
+	}
+}
+
+// segmentShared reports whether an edge other than e has been routed along
+// the segment of e's path from a to b, besides where it starts and ends.
+// Edges that only cross it don't count.
+func (g *graph) segmentShared(e *edge, a, b gridCoord) bool {
+	start, end := e.path[0], e.path[len(e.path)-1]
+	along := usedVertically
+	if a.y == b.y {
+		along = usedHorizontally
+	}
+	cells := segmentCells(a, b)
+	for _, c := range cells[1 : len(cells)-1] {
+		for _, r := range g.routedCells[c] {
+			if r.use&along != 0 && (r.start != start || r.end != end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentIsFree reports whether no node takes up any cell from a to b.
+func (g *graph) segmentIsFree(a, b gridCoord) bool {
+	for _, c := range segmentCells(a, b) {
+		if !g.isFreeInGrid(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNodeRow reports whether grid row y is occupied by any node.
+func (g *graph) isNodeRow(y int) bool {
+	for _, n := range g.nodes {
+		if n.gridCoord != nil && y >= n.gridCoord.y && y <= n.gridCoord.y+2 {
+			return true
+		}
+	}
+	return false
+}
+
+// pathToDrawing returns the points of e's path on the drawing, each moved
+// into the lanes of the segments it joins.
+func (g graph) pathToDrawing(e *edge) []drawingCoord {
+	line := g.lineToDrawing(e.path)
+	if e.laneOffsets == nil {
+		return line
+	}
+	for i, offset := range e.laneOffsets {
+		// The lanes are laid out before RL and BT graphs are mirrored.
+		if g.graphDirection == "RL" {
+			offset.x = -offset.x
+		}
+		if g.graphDirection == "BT" {
+			offset.y = -offset.y
+		}
+		line[i] = drawingCoord{x: line[i].x + offset.x, y: line[i].y + offset.y}
+	}
+	return line
+}
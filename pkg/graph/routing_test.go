@@ -0,0 +1,55 @@
+package graph
+
+import "testing"
+
+// drawnCells returns the cells of the drawing e's line runs through, keyed
+// by whether it runs through them vertically.
+func drawnCells(g *graph, e *edge) map[drawingCoord]bool {
+	cells := map[drawingCoord]bool{}
+	line := g.pathToDrawing(e)
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		for x := Min(a.x, b.x); x <= Max(a.x, b.x); x++ {
+			for y := Min(a.y, b.y); y <= Max(a.y, b.y); y++ {
+				cells[drawingCoord{x: x, y: y}] = a.x == b.x
+			}
+		}
+	}
+	return cells
+}
+
+func TestRoutedEdgesKeepApart(t *testing.T) {
+	for _, mermaid := range []string{
+		"graph TD\nA --> B --> C --> D\nD --> A\nC --> A\nD --> B",
+		"graph TD\nA --> B\nB --> C\nC -->|back1| A\nC -->|back2| B",
+	} {
+		g := mapLayeredGraph(t, mermaid, "grid")
+		for i, a := range g.edges {
+			aCells := drawnCells(g, a)
+			for _, b := range g.edges[i+1:] {
+				if a.path[0] == b.path[0] || a.path[len(a.path)-1] == b.path[len(b.path)-1] {
+					// Edges from or to the same spot branch off one line.
+					continue
+				}
+				for c, vertical := range drawnCells(g, b) {
+					if aVertical, ok := aCells[c]; ok && aVertical == vertical {
+						t.Errorf("%q: edges %s -> %s and %s -> %s both run along %v", mermaid, a.from.name, a.to.name, b.from.name, b.to.name, c)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// Edges that come in from opposite sides of a line and leave it to opposite
+// sides the other way round would cross if they were put in lanes of their
+// own, so they share the line instead.
+func TestEdgesThatMustCrossShareALine(t *testing.T) {
+	g := mapLayeredGraph(t, "graph LR\nA & B --> C & D", "grid")
+	for _, e := range g.edges {
+		if e.laneOffsets != nil {
+			t.Errorf("edge %s -> %s was moved into a lane: %v", e.from.name, e.to.name, e.laneOffsets)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package graph
+
+import "strings"
+
+// nodeShape is the outline a flowchart node is drawn with. Every shape fits
+// the same three-by-three grid cell as a rectangle, so layout and edge routing
+// don't need to know about it; shapes with slanted or inset sides only ask
+// for some extra width (and the cylinder for an extra row) to keep the label
+// clear of their border.
+type nodeShape int
+
+const (
+	shapeRect         nodeShape = iota // A[text]
+	shapeRound                         // A(text)
+	shapeStadium                       // A([text])
+	shapeSubroutine                    // A[[text]]
+	shapeCylinder                      // A[(text)]
+	shapeCircle                        // A((text))
+	shapeDoubleCircle                  // A(((text)))
+	shapeRhombus                       // A{text}
+	shapeHexagon                       // A{{text}}
+	shapeLeanRight                     // A[/text/]
+	shapeLeanLeft                      // A[\text\]
+	shapeTrapezoid                     // A[/text\]
+	shapeInvTrapezoid                  // A[\text/]
+	shapeAsymmetric                    // A>text]
+)
+
+// nodeShapeDelimiters lists the bracket pairs that wrap a node's label and the
+// shape each pair selects. Longer openers come first so that `((` isn't read
+// as `(`.
+var nodeShapeDelimiters = []struct {
+	open, close string
+	shape       nodeShape
+}{
+	{"(((", ")))", shapeDoubleCircle},
+	{"((", "))", shapeCircle},
+	{"([", "])", shapeStadium},
+	{"[[", "]]", shapeSubroutine},
+	{"[(", ")]", shapeCylinder},
+	{"{{", "}}", shapeHexagon},
+	{"[/", "/]", shapeLeanRight},
+	{`[\`, `\]`, shapeLeanLeft},
+	{"[/", `\]`, shapeTrapezoid},
+	{`[\`, "/]", shapeInvTrapezoid},
+	{"[", "]", shapeRect},
+	{"(", ")", shapeRound},
+	{"{", "}", shapeRhombus},
+	{">", "]", shapeAsymmetric},
+}
+
+// nodeShapeNames maps the names accepted by `A@{ shape: ... }` to the shape
+// they draw, including mermaid's aliases for each.
+var nodeShapeNames = map[string]nodeShape{
+	"rect": shapeRect, "rectangle": shapeRect, "proc": shapeRect, "process": shapeRect,
+	"rounded": shapeRound, "event": shapeRound,
+	"stadium": shapeStadium, "pill": shapeStadium, "terminal": shapeStadium,
+	"subroutine": shapeSubroutine, "subprocess": shapeSubroutine, "fr-rect": shapeSubroutine, "framed-rectangle": shapeSubroutine,
+	"cyl": shapeCylinder, "cylinder": shapeCylinder, "database": shapeCylinder, "db": shapeCylinder,
+	"circle": shapeCircle, "circ": shapeCircle,
+	"dbl-circ": shapeDoubleCircle, "double-circle": shapeDoubleCircle,
+	"diam": shapeRhombus, "diamond": shapeRhombus, "decision": shapeRhombus, "question": shapeRhombus, "rhombus": shapeRhombus,
+	"hex": shapeHexagon, "hexagon": shapeHexagon, "prepare": shapeHexagon,
+	"lean-r": shapeLeanRight, "lean-right": shapeLeanRight, "in-out": shapeLeanRight,
+	"lean-l": shapeLeanLeft, "lean-left": shapeLeanLeft, "out-in": shapeLeanLeft,
+	"trap-b": shapeTrapezoid, "trapezoid": shapeTrapezoid, "priority": shapeTrapezoid, "trapezoid-bottom": shapeTrapezoid,
+	"trap-t": shapeInvTrapezoid, "inv-trapezoid": shapeInvTrapezoid, "manual": shapeInvTrapezoid, "trapezoid-top": shapeInvTrapezoid,
+	"odd": shapeAsymmetric,
+}
+
+// lookupNodeShape resolves a `shape:` value, reporting false for shapes the
+// renderer doesn't know.
+func lookupNodeShape(name string) (nodeShape, bool) {
+	s, ok := nodeShapeNames[strings.ToLower(strings.TrimSpace(name))]
+	return s, ok
+}
+
+// extraHeight is the number of rows the shape adds above its label.
+func (s nodeShape) extraHeight() int {
+	if s == shapeCylinder {
+		return 1 // the rim below the top edge
+	}
+	return 0
+}
+
+// extraWidth is the number of columns the shape adds to a node's content
+// column so the label keeps its padding away from slanted or doubled sides
+// and, for shapes that narrow towards the top or bottom, so that edge is still
+// drawn.
+func (s nodeShape) extraWidth(label graphLabel, padding int) int {
+	h := label.contentHeight() + 2*padding + 1 + s.extraHeight()
+	m := h / 2
+	// Distance from the middle row to the outermost label line.
+	spread := label.height() - 1
+	// Width of a plain box holding the label.
+	base := label.width + 2*padding + 1
+	switch s {
+	case shapeSubroutine, shapeCircle, shapeDoubleCircle:
+		return 2
+	case shapeRhombus:
+		// Wide enough for the sides to meet in a point, /\ on top and \/
+		// below, when the label allows.
+		return Max(4*spread, 4*m+1-base)
+	case shapeHexagon:
+		return Max(2*spread, 2*m+2-base)
+	case shapeLeanRight, shapeLeanLeft:
+		return h
+	case shapeTrapezoid, shapeInvTrapezoid:
+		return Max(2*(h-m+spread), 2*h+2-base)
+	case shapeAsymmetric:
+		return 2 * m
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// The SVG output keeps the text drawing's character grid: every cell is
+// svgCellWidth x svgCellHeight units and lines run through cell centres, so
+// the picture lines up with the terminal version cell for cell.
+const (
+	svgCellWidth  = 9
+	svgCellHeight = 18
+	svgFontSize   = 15
+)
+
+// svgX and svgY are the centre of the cell at column x and row y.
+func svgX(x int) float64 { return (float64(x) + 0.5) * svgCellWidth }
+func svgY(y int) float64 { return (float64(y) + 0.5) * svgCellHeight }
+
+type svgWriter struct {
+	b strings.Builder
+}
+
+func (w *svgWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteByte('\n')
+}
+
+// open starts a document for a width x height cell canvas, with rows title
+// rows reserved above it.
+func (w *svgWriter) open(width, height int, title string) {
+	titleRows := 0
+	if title != "" {
+		titleRows = 2 // the title and the blank line the text output puts below it
+	}
+	w.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="%d">`,
+		width*svgCellWidth, (height+titleRows)*svgCellHeight, width*svgCellWidth, (height+titleRows)*svgCellHeight, svgFontSize)
+	w.printf(`<rect width="100%%" height="100%%" fill="white"/>`)
+	if title != "" {
+		w.text(0, 0, title, "")
+		w.printf(`<g transform="translate(0 %d)">`, titleRows*svgCellHeight)
+	} else {
+		w.printf(`<g>`)
+	}
+}
+
+func (w *svgWriter) close() string {
+	w.printf(`</g>`)
+	w.printf(`</svg>`)
+	return w.b.String()
+}
+
+// text writes s starting at cell (x, y), stretched to exactly the cells it
+// covers in the text drawing so wide runes and font metrics can't shift it.
+func (w *svgWriter) text(x, y int, s, fill string) {
+	width := runewidth.StringWidth(s)
+	if width == 0 {
+		return
+	}
+	if fill == "" {
+		fill = "black"
+	}
+	w.printf(`<text x="%d" y="%g" fill="%s" dominant-baseline="central" textLength="%d" lengthAdjust="spacingAndGlyphs" xml:space="preserve">%s</text>`,
+		x*svgCellWidth, svgY(y), html.EscapeString(fill), width*svgCellWidth, html.EscapeString(s))
+}
+
+// TextSVG wraps a text drawing in an SVG, one text element per line, for
+// diagrams that have no SVG renderer of their own.
+func TextSVG(drawing string) string {
+	lines := strings.Split(strings.TrimRight(drawing, "\n"), "\n")
+	width := 0
+	for _, l := range lines {
+		width = Max(width, runewidth.StringWidth(l))
+	}
+	w := &svgWriter{}
+	w.open(width, len(lines), "")
+	for y, l := range lines {
+		w.text(0, y, strings.TrimRight(l, " "), "")
+	}
+	return w.close()
+}
+
+// graphSVG draws the laid out graph g, whose text drawing is width x height
+// cells, as SVG.
+func graphSVG(g *graph, width, height int, title string) string {
+	w := &svgWriter{}
+	w.open(width, height, title)
+	// Edge heads take the colour of their edge, so every stroke colour in use
+	// gets its own set of markers.
+	markers := map[string]string{"black": ""}
+	colours := []string{"black"}
+	for _, e := range g.edges {
+		if stroke := edgeColour(e); markers[stroke] == "" && stroke != "black" {
+			markers[stroke] = fmt.Sprintf("-%d", len(colours))
+			colours = append(colours, stroke)
+		}
+	}
+	w.printf(`<defs>`)
+	for _, colour := range colours {
+		suffix, paint := markers[colour], html.EscapeString(colour)
+		w.printf(`<marker id="arrow%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="%s"/></marker>`, suffix, paint)
+		w.printf(`<marker id="circle%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><circle cx="5" cy="5" r="4" fill="none" stroke="%s" stroke-width="2"/></marker>`, suffix, paint)
+		w.printf(`<marker id="cross%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><path d="M0,0 L10,10 M0,10 L10,0" stroke="%s" stroke-width="2"/></marker>`, suffix, paint)
+	}
+	w.printf(`</defs>`)
+
+	for _, sg := range g.sortSubgraphsByDepth() {
+		if len(sg.nodes) == 0 {
+			continue
+		}
+		w.printf(`<rect x="%g" y="%g" width="%g" height="%g" fill="none" stroke="black"/>`,
+			svgX(sg.minX), svgY(sg.minY), svgX(sg.maxX)-svgX(sg.minX), svgY(sg.maxY)-svgY(sg.minY))
+		for lineIdx, line := range sg.label.lines {
+			x := Max(sg.minX+(sg.maxX-sg.minX)/2-runewidth.StringWidth(line)/2, sg.minX+1)
+			w.text(x, sg.minY+1+lineIdx*(graphLabelLineGap+1), line, "")
+		}
+	}
+
+	for _, e := range g.edges {
+		writeEdgeSVG(w, g, e, markers[edgeColour(e)])
+	}
+
+	for _, n := range g.nodes {
+		writeNodeSVG(w, g, n)
+	}
+
+	// Edge labels go last so they sit on top of the lines they interrupt.
+	for _, e := range g.edges {
+		if e.labelPlacement == nil {
+			continue
+		}
+		for i, line := range e.labelPlacement.lines {
+			start := e.labelPlacement.starts[i]
+			w.printf(`<rect x="%d" y="%d" width="%d" height="%d" fill="white"/>`,
+				start.x*svgCellWidth, start.y*svgCellHeight, runewidth.StringWidth(line)*svgCellWidth, svgCellHeight)
+			w.text(start.x, start.y, line, e.styles["color"])
+		}
+	}
+	// The legend of labels that didn't fit ends the drawing.
+	for i, entry := range g.labelLegend {
+		w.text(0, height-len(g.labelLegend)+i, entry, "")
+	}
+	return w.close()
+}
+
+// edgeColour is the colour e is drawn in.
+func edgeColour(e *edge) string {
+	if stroke := e.styles["stroke"]; stroke != "" {
+		return stroke
+	}
+	return "black"
+}
+
+// writeEdgeSVG draws e, ending it in the markers whose ids end in
+// markerSuffix.
+func writeEdgeSVG(w *svgWriter, g *graph, e *edge, markerSuffix string) {
+	if len(e.path) < 2 {
+		return
+	}
+	line := g.edgeDrawingLine(e)
+	// Slanted and inset shapes sit inside their bounding box on the attach
+	// row; pull the ends in to meet their outline like the text drawing does.
+	if e.fromSubgraph == nil {
+		line[0] = shapeAttachPoint(g, e.from, line[0])
+	}
+	if e.toSubgraph == nil {
+		line[len(line)-1] = shapeAttachPoint(g, e.to, line[len(line)-1])
+	}
+
+	points := make([]string, len(line))
+	for i, c := range line {
+		points[i] = fmt.Sprintf("%g,%g", svgX(c.x), svgY(c.y))
+	}
+	attrs := ""
+	switch e.stroke {
+	case strokeDotted:
+		attrs += ` stroke-dasharray="3 3"`
+	case strokeThick:
+		attrs += ` stroke-width="3"`
+	}
+	marker := map[edgeHead]string{headArrow: "arrow", headCircle: "circle", headCross: "cross"}[e.head]
+	if marker != "" {
+		attrs += fmt.Sprintf(` marker-end="url(#%s%s)"`, marker, markerSuffix)
+		if e.isBidirectional {
+			attrs += fmt.Sprintf(` marker-start="url(#%s%s)"`, marker, markerSuffix)
+		}
+	}
+	w.printf(`<polyline points="%s" fill="none" stroke="%s"%s/>`, strings.Join(points, " "), html.EscapeString(edgeColour(e)), attrs)
+}
+
+// shapeAttachPoint moves an edge end at c, on the left or right side of n's
+// bounding box, onto n's outline.
+func shapeAttachPoint(g *graph, n *node, c drawingCoord) drawingCoord {
+	wCells, hCells, m := nodeShapeSize(g, n)
+	if c.y != n.drawingCoord.y+m {
+		return c
+	}
+	left, _, right, _ := shapeRow(n.shape, m, wCells, hCells, m, unicodeShapeGlyphs)
+	switch c.x {
+	case n.drawingCoord.x:
+		c.x += left
+	case n.drawingCoord.x + wCells:
+		c.x -= wCells - right
+	}
+	return c
+}
+
+// nodeShapeSize is the size of n's bounding box, less one (the coordinate of
+// its right and bottom borders), and the row edges attach to on its sides.
+func nodeShapeSize(g *graph, n *node) (int, int, int) {
+	w := g.columnWidth[n.gridCoord.x] + g.columnWidth[n.gridCoord.x+1]
+	h := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]
+	m := g.rowHeight[n.gridCoord.y] + g.rowHeight[n.gridCoord.y+1]/2
+	return w, h, m
+}
+
+func writeNodeSVG(w *svgWriter, g *graph, n *node) {
+	wCells, hCells, m := nodeShapeSize(g, n)
+	ox, oy := n.drawingCoord.x, n.drawingCoord.y
+	x0, y0 := svgX(ox), svgY(oy)
+	x1, y1 := svgX(ox+wCells), svgY(oy+hCells)
+	width, height := x1-x0, y1-y0
+
+	fill := n.styleClass.styles["fill"]
+	if fill == "" {
+		fill = "white"
+	}
+	stroke := n.styleClass.styles["stroke"]
+	if stroke == "" {
+		stroke = "black"
+	}
+	paint := fmt.Sprintf(`fill="%s" stroke="%s"`, html.EscapeString(fill), html.EscapeString(stroke))
+
+	switch n.shape {
+	case shapeRect:
+		w.printf(`<rect x="%g" y="%g" width="%g" height="%g" %s/>`, x0, y0, width, height, paint)
+	case shapeRound:
+		w.printf(`<rect x="%g" y="%g" width="%g" height="%g" rx="%d" %s/>`, x0, y0, width, height, svgCellWidth, paint)
+	case shapeStadium:
+		w.printf(`<rect x="%g" y="%g" width="%g" height="%g" rx="%g" %s/>`, x0, y0, width, height, height/2, paint)
+	case shapeSubroutine:
+		w.printf(`<rect x="%g" y="%g" width="%g" height="%g" %s/>`, x0, y0, width, height, paint)
+		for _, x := range []float64{svgX(ox + 1), svgX(ox + wCells - 1)} {
+			w.printf(`<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s"/>`, x, y0, x, y1, html.EscapeString(stroke))
+		}
+	case shapeCylinder:
+		// The top ellipse spans the top border and the rim row below it.
+		rx, ry := width/2, (svgY(oy+1)-y0)/2
+		w.printf(`<path d="M%g,%g a%g,%g 0 0 0 %g,0 a%g,%g 0 0 0 %g,0 V%g a%g,%g 0 0 0 %g,0 V%g" %s/>`,
+			x0, y0+ry, rx, ry, width, rx, ry, -width, y1-ry, rx, ry, width, y0+ry, paint)
+	case shapeCircle, shapeDoubleCircle:
+		w.printf(`<ellipse cx="%g" cy="%g" rx="%g" ry="%g" %s/>`, x0+width/2, y0+height/2, width/2, height/2, paint)
+		if n.shape == shapeDoubleCircle {
+			w.printf(`<ellipse cx="%g" cy="%g" rx="%g" ry="%g" fill="none" stroke="%s"/>`,
+				x0+width/2, y0+height/2, width/2-svgCellWidth/2, height/2-svgCellHeight/4, html.EscapeString(stroke))
+		}
+	default:
+		// Polygonal shapes: the outline's corners are where its borders sit
+		// on the top, attach and bottom rows of the text drawing.
+		var left, right []string
+		top, bottom := shapeSpan(n.shape, wCells, hCells, m)
+		for _, y := range []int{top, m, bottom} {
+			l, _, r, _ := shapeRow(n.shape, y, wCells, hCells, m, unicodeShapeGlyphs)
+			l = Max(0, Min(l, wCells))
+			r = Max(l, Min(r, wCells))
+			left = append([]string{fmt.Sprintf("%g,%g", svgX(ox+l), svgY(oy+y))}, left...)
+			right = append(right, fmt.Sprintf("%g,%g", svgX(ox+r), svgY(oy+y)))
+		}
+		w.printf(`<polygon points="%s" %s/>`, strings.Join(append(right, left...), " "), paint)
+	}
+
+	for lineIdx, start := range n.labelLineStarts(*g) {
+		w.text(ox+start.x, oy+start.y, n.label.lines[lineIdx], n.styleClass.styles["color"])
+	}
+}
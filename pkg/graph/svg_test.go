@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// svgElements parses an SVG document and counts its elements by name.
+func svgElements(t *testing.T, svg string) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("output is not well-formed XML: %v\n%s", err, svg)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+	return counts
+}
+
+func TestRenderSVGDrawsGraphShapes(t *testing.T) {
+	gd, err := Parse("graph LR\nA[Start] -->|go| B{Choice}\nB -.-> C((Done))\nB ==> D[(Store)]\nC <--> A")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	out, err := RenderSVG(gd, diagram.DefaultConfig(), "")
+	if err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+
+	counts := svgElements(t, out)
+	if counts["polyline"] != 4 {
+		t.Errorf("got %d edge polylines, want 4", counts["polyline"])
+	}
+	if counts["polygon"] != 1 || counts["ellipse"] != 1 || counts["path"] < 1 {
+		t.Errorf("missing node shapes: %v", counts)
+	}
+	for _, want := range []string{`stroke-dasharray`, `stroke-width="3"`, `marker-start="url(#arrow)"`, `>go</text>`, `>Choice</text>`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderSVGDrawsCircleHeadsHollowInTheEdgeColour(t *testing.T) {
+	gd, err := Parse("graph LR\nA --o B\nB --o C\nlinkStyle 1 stroke:red")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	out, err := RenderSVG(gd, diagram.DefaultConfig(), "")
+	if err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`<marker id="circle" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><circle cx="5" cy="5" r="4" fill="none" stroke="black"`,
+		`<marker id="circle-1" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><circle cx="5" cy="5" r="4" fill="none" stroke="red"`,
+		`stroke="black" marker-end="url(#circle)"`,
+		`stroke="red" marker-end="url(#circle-1)"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s\n%s", want, out)
+		}
+	}
+}
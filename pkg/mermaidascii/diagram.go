@@ -0,0 +1,337 @@
+package mermaidascii
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/class"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/er"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/gantt"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/gitgraph"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/graph"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/mindmap"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/pie"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/sequence"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/state"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/timeline"
+)
+
+// DiagramFactory returns an unparsed diagram of the type input declares.
+// Anything that isn't recognised as another type is treated as a graph.
+func DiagramFactory(input string) (diagram.Diagram, error) {
+	input = strings.TrimSpace(input)
+
+	if sequence.IsSequenceDiagram(input) {
+		return &SequenceDiagram{}, nil
+	}
+
+	if er.IsErDiagram(input) {
+		return &ErDiagram{}, nil
+	}
+
+	if state.IsStateDiagram(input) {
+		return &StateDiagram{}, nil
+	}
+
+	if class.IsClassDiagram(input) {
+		return &ClassDiagram{}, nil
+	}
+
+	if gantt.IsGanttChart(input) {
+		return &GanttChart{}, nil
+	}
+
+	if pie.IsPieChart(input) {
+		return &PieChart{}, nil
+	}
+
+	if gitgraph.IsGitGraph(input) {
+		return &GitGraph{}, nil
+	}
+
+	if mindmap.IsMindmap(input) {
+		return &Mindmap{}, nil
+	}
+
+	if timeline.IsTimeline(input) {
+		return &Timeline{}, nil
+	}
+
+	lines := strings.Split(input, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "%%") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "graph ") || strings.HasPrefix(trimmed, "flowchart ") {
+			return &GraphDiagram{}, nil
+		}
+		if !strings.HasPrefix(trimmed, "%%") {
+			return &GraphDiagram{}, nil
+		}
+	}
+
+	return &GraphDiagram{}, nil
+}
+
+type SequenceDiagram struct {
+	parsed *sequence.SequenceDiagram
+}
+
+func (sd *SequenceDiagram) Parse(input string) error {
+	parsed, err := sequence.Parse(input)
+	if err != nil {
+		return err
+	}
+	sd.parsed = parsed
+	return nil
+}
+
+func (sd *SequenceDiagram) Render(config *diagram.Config) (string, error) {
+	if sd.parsed == nil {
+		return "", fmt.Errorf("sequence diagram not parsed: call Parse() before Render()")
+	}
+	return sequence.Render(sd.parsed, config)
+}
+
+func (sd *SequenceDiagram) Layout(config *diagram.Config) (*diagram.Layout, error) {
+	if sd.parsed == nil {
+		return nil, fmt.Errorf("sequence diagram not parsed: call Parse() before Layout()")
+	}
+	return sequence.Layout(sd.parsed, config)
+}
+
+func (sd *SequenceDiagram) Type() string {
+	return "sequence"
+}
+
+// GraphDiagram adapts the graph package to the Diagram interface.
+type GraphDiagram struct {
+	parsed *graph.GraphDiagram
+}
+
+func (gd *GraphDiagram) Parse(input string) error {
+	parsed, err := graph.Parse(input)
+	if err != nil {
+		return err
+	}
+	gd.parsed = parsed
+	return nil
+}
+
+func (gd *GraphDiagram) Render(config *diagram.Config) (string, error) {
+	if gd.parsed == nil {
+		return "", fmt.Errorf("graph diagram not parsed: call Parse() before Render()")
+	}
+	return graph.Render(gd.parsed, config)
+}
+
+func (gd *GraphDiagram) Layout(config *diagram.Config) (*diagram.Layout, error) {
+	if gd.parsed == nil {
+		return nil, fmt.Errorf("graph diagram not parsed: call Parse() before Layout()")
+	}
+	return graph.Layout(gd.parsed, config)
+}
+
+func (gd *GraphDiagram) renderSVG(config *diagram.Config, title string) (string, error) {
+	if gd.parsed == nil {
+		return "", fmt.Errorf("graph diagram not parsed: call Parse() before Render()")
+	}
+	return graph.RenderSVG(gd.parsed, config, title)
+}
+
+func (gd *GraphDiagram) Type() string {
+	return "graph"
+}
+
+// ErDiagram adapts the er package to the Diagram interface.
+type ErDiagram struct {
+	parsed *er.ErDiagram
+}
+
+func (d *ErDiagram) Parse(input string) error {
+	parsed, err := er.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *ErDiagram) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("er diagram not parsed: call Parse() before Render()")
+	}
+	return er.Render(d.parsed, config.UseAscii), nil
+}
+
+func (d *ErDiagram) Layout(config *diagram.Config) (*diagram.Layout, error) {
+	if d.parsed == nil {
+		return nil, fmt.Errorf("er diagram not parsed: call Parse() before Layout()")
+	}
+	return er.Layout(d.parsed, config.UseAscii), nil
+}
+
+func (d *ErDiagram) Type() string { return "er" }
+
+// StateDiagram adapts the state package to the Diagram interface.
+type StateDiagram struct {
+	parsed *state.StateDiagram
+}
+
+func (d *StateDiagram) Parse(input string) error {
+	parsed, err := state.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *StateDiagram) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("state diagram not parsed: call Parse() before Render()")
+	}
+	return state.Render(d.parsed, config)
+}
+
+func (d *StateDiagram) Type() string { return "state" }
+
+// ClassDiagram adapts the class package to the Diagram interface.
+type ClassDiagram struct {
+	parsed *class.ClassDiagram
+}
+
+func (d *ClassDiagram) Parse(input string) error {
+	parsed, err := class.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *ClassDiagram) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("class diagram not parsed: call Parse() before Render()")
+	}
+	return class.Render(d.parsed, config)
+}
+
+func (d *ClassDiagram) Type() string { return "class" }
+
+// GanttChart adapts the gantt package to the Diagram interface.
+type GanttChart struct {
+	parsed *gantt.GanttChart
+}
+
+func (d *GanttChart) Parse(input string) error {
+	parsed, err := gantt.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *GanttChart) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("gantt chart not parsed: call Parse() before Render()")
+	}
+	return gantt.Render(d.parsed, config)
+}
+
+func (d *GanttChart) Type() string { return "gantt" }
+
+// PieChart adapts the pie package to the Diagram interface.
+type PieChart struct {
+	parsed *pie.PieChart
+}
+
+func (d *PieChart) Parse(input string) error {
+	parsed, err := pie.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *PieChart) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("pie chart not parsed: call Parse() before Render()")
+	}
+	return pie.Render(d.parsed, config)
+}
+
+func (d *PieChart) Type() string { return "pie" }
+
+// GitGraph adapts the gitgraph package to the Diagram interface.
+type GitGraph struct {
+	parsed *gitgraph.GitGraph
+}
+
+func (d *GitGraph) Parse(input string) error {
+	parsed, err := gitgraph.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *GitGraph) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("gitGraph not parsed: call Parse() before Render()")
+	}
+	return gitgraph.Render(d.parsed, config)
+}
+
+func (d *GitGraph) Type() string { return "gitGraph" }
+
+// Mindmap adapts the mindmap package to the Diagram interface.
+type Mindmap struct {
+	parsed *mindmap.Mindmap
+}
+
+func (d *Mindmap) Parse(input string) error {
+	parsed, err := mindmap.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *Mindmap) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("mindmap not parsed: call Parse() before Render()")
+	}
+	return mindmap.Render(d.parsed, config)
+}
+
+func (d *Mindmap) Type() string { return "mindmap" }
+
+// Timeline adapts the timeline package to the Diagram interface.
+type Timeline struct {
+	parsed *timeline.Timeline
+}
+
+func (d *Timeline) Parse(input string) error {
+	parsed, err := timeline.Parse(input)
+	if err != nil {
+		return err
+	}
+	d.parsed = parsed
+	return nil
+}
+
+func (d *Timeline) Render(config *diagram.Config) (string, error) {
+	if d.parsed == nil {
+		return "", fmt.Errorf("timeline not parsed: call Parse() before Render()")
+	}
+	return timeline.Render(d.parsed, config)
+}
+
+func (d *Timeline) Type() string { return "timeline" }
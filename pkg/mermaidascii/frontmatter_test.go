@@ -1,6 +1,7 @@
-package cmd
+package mermaidascii
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -45,7 +46,7 @@ func TestRenderWithFrontmatter(t *testing.T) {
 			if diag.Type() != c.wantType {
 				t.Errorf("detected %q, want %q", diag.Type(), c.wantType)
 			}
-			out, err := RenderDiagram(c.input, diagram.DefaultConfig())
+			out, err := Render(context.Background(), c.input, diagram.DefaultConfig())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -61,7 +62,7 @@ func TestRenderWithFrontmatter(t *testing.T) {
 // TestRenderTitleAboveDiagram checks the title sits on the first line,
 // separated from the diagram by a blank line.
 func TestRenderTitleAboveDiagram(t *testing.T) {
-	out, err := RenderDiagram("---\ntitle: My title\n---\ngraph LR\nA-->B", diagram.DefaultConfig())
+	out, err := Render(context.Background(), "---\ntitle: My title\n---\ngraph LR\nA-->B", diagram.DefaultConfig())
 	if err != nil {
 		t.Fatal(err)
 	}
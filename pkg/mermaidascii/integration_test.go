@@ -1,6 +1,7 @@
-package cmd
+package mermaidascii
 
 import (
+	"context"
 	"github.com/AlexanderGrooff/mermaid-ascii/pkg/sequence"
 	"strings"
 	"testing"
@@ -65,7 +66,7 @@ func TestSequenceDiagramIntegration(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			config := diagram.NewTestConfig(false, "cli") // Unicode, CLI style
 
-			output, err := RenderDiagram(tt.input, config)
+			output, err := Render(context.Background(), tt.input, config)
 
 			if tt.wantNoError && err != nil {
 				t.Errorf("Unexpected error: %v", err)
@@ -99,7 +100,7 @@ func TestSequenceDiagramIntegration_ASCII(t *testing.T) {
 
 	config := diagram.NewTestConfig(true, "cli") // ASCII, CLI style
 
-	output, err := RenderDiagram(input, config)
+	output, err := Render(context.Background(), input, config)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -144,7 +145,7 @@ func TestSequenceDiagramIntegration_ErrorHandling(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config := diagram.NewTestConfig(false, "cli") // Unicode, CLI style
-			_, err := RenderDiagram(tt.input, config)
+			_, err := Render(context.Background(), tt.input, config)
 
 			if tt.shouldError && err == nil {
 				t.Error("Expected error but got none")
@@ -176,6 +177,59 @@ func TestDiagramFactoryIntegration(t *testing.T) {
     A-->B`,
 			expectedType: "graph",
 		},
+		{
+			name: "state diagram",
+			input: `stateDiagram-v2
+    [*] --> Idle
+    Idle --> [*]`,
+			expectedType: "state",
+		},
+		{
+			name: "class diagram",
+			input: `classDiagram
+    Animal <|-- Duck`,
+			expectedType: "class",
+		},
+		{
+			name: "gantt chart",
+			input: `gantt
+    A : a, 2024-01-01, 3d
+    B : after a, 2d`,
+			expectedType: "gantt",
+		},
+		{
+			name: "pie chart",
+			input: `pie title Pets
+    "Dogs" : 386
+    "Cats" : 85`,
+			expectedType: "pie",
+		},
+		{
+			name: "git graph",
+			input: `gitGraph
+    commit
+    branch develop
+    commit
+    checkout main
+    merge develop`,
+			expectedType: "gitGraph",
+		},
+		{
+			name: "mindmap",
+			input: `mindmap
+  root((Ideas))
+    First
+    Second`,
+			expectedType: "mindmap",
+		},
+		{
+			name: "timeline",
+			input: `timeline
+    title Releases
+    2023 : 1.0
+    2024 : 2.0 : 2.1`,
+			expectedType: "timeline",
+		},
 	}
 
 	for _, tt := range tests {
@@ -222,7 +276,7 @@ func BenchmarkSequenceDiagramRendering(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := RenderDiagram(input, config)
+		_, err := Render(context.Background(), input, config)
 		if err != nil {
 			b.Fatalf("Render error: %v", err)
 		}
@@ -266,7 +320,7 @@ func TestRenderConfig_Defaults(t *testing.T) {
     A->>B: Test`
 
 	testConfig := diagram.NewTestConfig(false, "cli")
-	output, err := RenderDiagram(input, testConfig)
+	output, err := Render(context.Background(), input, testConfig)
 	if err != nil {
 		t.Errorf("Default config failed to render: %v", err)
 	}
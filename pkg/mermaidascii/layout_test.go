@@ -0,0 +1,37 @@
+package mermaidascii
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+func TestRenderJSONFormat(t *testing.T) {
+	config := diagram.DefaultConfig()
+	config.OutputFormat = "json"
+	out, err := Render(context.Background(), "---\ntitle: Flow\n---\ngraph LR\nA --> B", config)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	var layout diagram.Layout
+	if err := json.Unmarshal([]byte(out), &layout); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if layout.Type != "graph" || layout.Title != "Flow" {
+		t.Errorf("type, title = %q, %q; want graph, Flow", layout.Type, layout.Title)
+	}
+	if len(layout.Nodes) != 2 || len(layout.Edges) != 1 {
+		t.Errorf("got %d nodes and %d edges, want 2 and 1", len(layout.Nodes), len(layout.Edges))
+	}
+}
+
+func TestLayoutRejectsUnsupportedDiagrams(t *testing.T) {
+	_, err := Layout(context.Background(), "classDiagram\nA --> B", nil)
+	if err == nil || !strings.Contains(err.Error(), "class") {
+		t.Fatalf("Layout() error = %v, want an unsupported class diagram error", err)
+	}
+}
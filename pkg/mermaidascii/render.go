@@ -0,0 +1,137 @@
+// Package mermaidascii renders mermaid diagrams of any supported type as text,
+// SVG or a JSON description of their layout. It holds no global state, so its
+// functions can be called concurrently with different configs.
+package mermaidascii
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/graph"
+)
+
+// svgDiagram is implemented by diagrams that can draw themselves as SVG with
+// real lines and shapes. Every other diagram has its text drawing wrapped in
+// an SVG instead.
+type svgDiagram interface {
+	renderSVG(config *diagram.Config, title string) (string, error)
+}
+
+// Render detects the type of the mermaid diagram in input and renders it in
+// config's output format. A nil config renders with diagram.DefaultConfig().
+// Rendering stops early with ctx's error once ctx is done.
+func Render(ctx context.Context, input string, config *diagram.Config) (string, error) {
+	if config == nil {
+		config = diagram.DefaultConfig()
+	}
+
+	if config.OutputFormat == "json" {
+		layout, err := Layout(ctx, input, config)
+		if err != nil {
+			return "", err
+		}
+		out, err := json.MarshalIndent(layout, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s layout: %w", layout.Type, err)
+		}
+		return string(out) + "\n", nil
+	}
+
+	// YAML frontmatter carries a title and theme config; the config has no
+	// ASCII meaning, but the title is printed above the diagram like mermaid
+	// does. Stripped here once so type detection and parsing never see it.
+	source := input
+	input, title := diagram.StripFrontmatter(input)
+
+	diag, err := parseDiagram(ctx, input, frontmatterLines(source, input))
+	if err != nil {
+		return "", err
+	}
+
+	svg := config.OutputFormat == "svg" || config.StyleType == "svg"
+	if sd, ok := diag.(svgDiagram); ok && svg {
+		output, err := sd.renderSVG(config, title)
+		if err != nil {
+			return "", fmt.Errorf("failed to render %s diagram: %w", diag.Type(), err)
+		}
+		return output, nil
+	}
+
+	output, err := diag.Render(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s diagram: %w", diag.Type(), err)
+	}
+
+	if title != "" {
+		output = title + "\n\n" + output
+	}
+	if svg {
+		return graph.TextSVG(output), nil
+	}
+	return output, nil
+}
+
+// Layout lays the diagram out like Render would and returns where its parts
+// ended up instead of the drawing. Only graph, sequence and ER diagrams report
+// their layout.
+func Layout(ctx context.Context, input string, config *diagram.Config) (*diagram.Layout, error) {
+	if config == nil {
+		config = diagram.DefaultConfig()
+	}
+
+	source := input
+	input, title := diagram.StripFrontmatter(input)
+
+	diag, err := parseDiagram(ctx, input, frontmatterLines(source, input))
+	if err != nil {
+		return nil, err
+	}
+
+	ld, ok := diag.(diagram.LayoutDiagram)
+	if !ok {
+		return nil, fmt.Errorf("layout output is not supported for %s diagrams", diag.Type())
+	}
+	layout, err := ld.Layout(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lay out %s diagram: %w", diag.Type(), err)
+	}
+	layout.Title = title
+	return layout, nil
+}
+
+// frontmatterLines counts the lines of frontmatter StripFrontmatter took off
+// the front of source to leave rest.
+func frontmatterLines(source, rest string) int {
+	return strings.Count(source[:len(source)-len(rest)], "\n")
+}
+
+// parseDiagram detects the diagram type of input and parses it. It checks
+// ctx before and after parsing, the points where giving up saves work. input
+// came after skipped lines of frontmatter, which errors at a line of it
+// count in, so they point at the source as given.
+func parseDiagram(ctx context.Context, input string, skipped int) (diagram.Diagram, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	diag, err := DiagramFactory(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect diagram type: %w", err)
+	}
+
+	if err := diag.Parse(input); err != nil {
+		var lineErr *diagram.LineError
+		if errors.As(err, &lineErr) {
+			lineErr.Line += skipped
+		}
+		return nil, fmt.Errorf("failed to parse %s diagram: %w", diag.Type(), err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return diag, nil
+}
@@ -1,6 +1,7 @@
-package cmd
+package mermaidascii
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -18,9 +19,9 @@ func TestRenderGraphHandlesLongChainWithoutPanic(t *testing.T) {
 		fmt.Fprintf(&b, "N%d --> N%d\n", i, i+1)
 	}
 
-	output, err := RenderDiagram(b.String(), config)
+	output, err := Render(context.Background(), b.String(), config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if !strings.Contains(output, "N30") {
@@ -30,9 +31,9 @@ func TestRenderGraphHandlesLongChainWithoutPanic(t *testing.T) {
 
 func TestRenderGraphKeepsDisplayWidthForWideNodeLabels(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph LR\nA[\"中A\"] --> B", config)
+	output, err := Render(context.Background(), "graph LR\nA[\"中A\"] --> B", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	assertUniformDisplayWidth(t, output)
@@ -40,9 +41,9 @@ func TestRenderGraphKeepsDisplayWidthForWideNodeLabels(t *testing.T) {
 
 func TestRenderGraphKeepsDisplayWidthForWideSubgraphTitles(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph LR\nsubgraph sg [数据库]\nA --> B\nend", config)
+	output, err := Render(context.Background(), "graph LR\nsubgraph sg [数据库]\nA --> B\nend", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	assertUniformDisplayWidth(t, output)
@@ -50,9 +51,9 @@ func TestRenderGraphKeepsDisplayWidthForWideSubgraphTitles(t *testing.T) {
 
 func TestRenderGraphKeepsExplicitTargetLabelAfterBareReference(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph TD\nA[\"Foo\"] --> B[\"Bar\"]\nB --> C[\"Baz\"]", config)
+	output, err := Render(context.Background(), "graph TD\nA[\"Foo\"] --> B[\"Bar\"]\nB --> C[\"Baz\"]", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if !strings.Contains(output, "Bar") {
@@ -65,9 +66,9 @@ func TestRenderGraphKeepsExplicitTargetLabelAfterBareReference(t *testing.T) {
 
 func TestRenderGraphKeepsStandaloneSubgraphLabelWhenReferencedLater(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph TD\nsubgraph one\n    A[\"VcpuManager\"]\nend\nA --> B", config)
+	output, err := Render(context.Background(), "graph TD\nsubgraph one\n    A[\"VcpuManager\"]\nend\nA --> B", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if !strings.Contains(output, "VcpuManager") {
@@ -80,9 +81,9 @@ func TestRenderGraphKeepsStandaloneSubgraphLabelWhenReferencedLater(t *testing.T
 
 func TestRenderGraphSupportsLiteralNewlineInNodeLabel(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph LR\nA[\"line1\nline2\"] --> B", config)
+	output, err := Render(context.Background(), "graph LR\nA[\"line1\nline2\"] --> B", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if !strings.Contains(output, "line1") || !strings.Contains(output, "line2") {
@@ -95,9 +96,9 @@ func TestRenderGraphSupportsLiteralNewlineInNodeLabel(t *testing.T) {
 
 func TestRenderGraphSeparatesDuplicateEdgeLabels(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph LR\nA -->|miss| B\nA -->|hit| B", config)
+	output, err := Render(context.Background(), "graph LR\nA -->|miss| B\nA -->|hit| B", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if strings.Contains(output, "mhit") {
@@ -124,9 +125,9 @@ func TestRenderGraphSeparatesDuplicateEdgeLabels(t *testing.T) {
 
 func TestRenderGraphSeparatesBidirectionalEdgeLabelsLR(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph LR\nA -->|workload exits| B\nB -->|run| A", config)
+	output, err := Render(context.Background(), "graph LR\nA -->|workload exits| B\nB -->|run| A", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if strings.Contains(output, "worklorunexits") {
@@ -153,9 +154,9 @@ func TestRenderGraphSeparatesBidirectionalEdgeLabelsLR(t *testing.T) {
 
 func TestRenderGraphSeparatesBidirectionalEdgeLabelsTD(t *testing.T) {
 	config := diagram.NewTestConfig(true, "cli")
-	output, err := RenderDiagram("graph TD\nA -->|forward| B\nB -->|back| A", config)
+	output, err := Render(context.Background(), "graph TD\nA -->|forward| B\nB -->|back| A", config)
 	if err != nil {
-		t.Fatalf("RenderDiagram() error = %v", err)
+		t.Fatalf("Render() error = %v", err)
 	}
 
 	if strings.Contains(output, "fbackrd") {
@@ -0,0 +1,94 @@
+package mermaidascii
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// TestRenderIsSafeForConcurrentUse renders the same inputs with different
+// configs from many goroutines at once; run with -race to catch shared state.
+func TestRenderIsSafeForConcurrentUse(t *testing.T) {
+	inputs := []string{
+		"graph LR\nsubgraph one\nA -->|label| B\nend\nB --> C{Choice}\nclassDef hot color:#f00\nC:::hot",
+		"graph TD\nA --> B\nA --> C\nB --> D\nC --> D",
+		"sequenceDiagram\nAlice->>Bob: Hello\nBob-->>Alice: Hi",
+	}
+	configs := []*diagram.Config{diagram.DefaultConfig(), diagram.NewTestConfig(true, "html")}
+	for i := range configs {
+		configs[i].PaddingBetweenX = 2 + 3*i
+	}
+
+	want := map[string]string{}
+	for _, input := range inputs {
+		for i, config := range configs {
+			out, err := Render(context.Background(), input, config)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			want[fmt.Sprint(i, input)] = out
+		}
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		for _, input := range inputs {
+			for i, config := range configs {
+				wg.Add(1)
+				go func(i int, input string, config *diagram.Config) {
+					defer wg.Done()
+					out, err := Render(context.Background(), input, config)
+					if err != nil {
+						t.Errorf("Render() error = %v", err)
+						return
+					}
+					if out != want[fmt.Sprint(i, input)] {
+						t.Errorf("concurrent render of %q differs:\n%s", input, out)
+					}
+				}(i, input, config)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+func TestRenderStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Render(ctx, "graph LR\nA --> B", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render() error = %v, want context.Canceled", err)
+	}
+	if _, err := Layout(ctx, "graph LR\nA --> B", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Layout() error = %v, want context.Canceled", err)
+	}
+}
+
+// TestRenderErrorsPointAtSourceLines checks that parse errors report the
+// line of the input as given, counting blank lines, comments and
+// frontmatter.
+func TestRenderErrorsPointAtSourceLines(t *testing.T) {
+	for _, tc := range []struct {
+		name, input string
+		line        int
+	}{
+		{"sequence after blank lines", "\nsequenceDiagram\n\n%% note\nAlice->>Bob: Hi\n  what is this", 6},
+		{"gantt", "gantt\n    section Work\n    Work : after nowhere, 1d", 3},
+		{"graph header after frontmatter", "---\ntitle: T\n---\n\ngraph sideways\nA --> B", 5},
+		{"pie after frontmatter", "---\ntitle: T\n---\npie\n  \"Dogs\" : -3", 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Render(context.Background(), tc.input, nil)
+			var lineErr *diagram.LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("Render() error = %v, want a *diagram.LineError", err)
+			}
+			if lineErr.Line != tc.line {
+				t.Errorf("error is at line %d, want %d: %v", lineErr.Line, tc.line, err)
+			}
+		})
+	}
+}
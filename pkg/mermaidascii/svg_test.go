@@ -0,0 +1,76 @@
+package mermaidascii
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// svgElements parses an SVG document and counts its elements by name.
+func svgElements(t *testing.T, svg string) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("output is not well-formed XML: %v\n%s", err, svg)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+	return counts
+}
+
+func TestRenderSVGStyleTypeMatchesOutputFormat(t *testing.T) {
+	input := "graph TD\nclassDef hot fill:#fdd,color:#900\nA:::hot --> B"
+	byFormat := diagram.DefaultConfig()
+	byFormat.OutputFormat = "svg"
+	byStyle := diagram.DefaultConfig()
+	byStyle.StyleType = "svg"
+
+	a, err := Render(context.Background(), input, byFormat)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	b, err := Render(context.Background(), input, byStyle)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if a != b {
+		t.Errorf("StyleType svg and OutputFormat svg render differently:\n%s\n---\n%s", a, b)
+	}
+	if !strings.Contains(a, `fill="#fdd"`) || !strings.Contains(a, `fill="#900"`) || strings.Contains(a, "\x1b[") {
+		t.Errorf("classDef colours should become SVG paint:\n%s", a)
+	}
+}
+
+func TestRenderSVGWrapsOtherDiagramsAsText(t *testing.T) {
+	config := diagram.DefaultConfig()
+	config.OutputFormat = "svg"
+	input := "sequenceDiagram\nAlice->>Bob: Hi <there> & bye"
+	out, err := Render(context.Background(), input, config)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	text, err := Render(context.Background(), input, diagram.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	counts := svgElements(t, out)
+	if want := len(strings.Split(strings.TrimRight(text, "\n"), "\n")); counts["text"] != want {
+		t.Errorf("got %d text rows, want %d", counts["text"], want)
+	}
+	if !strings.Contains(out, "Hi &lt;there&gt; &amp; bye") {
+		t.Errorf("message label should be escaped in the SVG:\n%s", out)
+	}
+}
@@ -0,0 +1,32 @@
+package mindmap
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		p, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(p, config)
+	}
+}
+
+// TestMindmapRendering tests all mindmap golden files with Unicode charset.
+func TestMindmapRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "mindmap", renderGolden(false))
+}
+
+// TestMindmapRendering_ASCII tests mindmap golden files with ASCII charset.
+func TestMindmapRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "mindmap-ascii", renderGolden(true))
+}
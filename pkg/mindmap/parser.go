@@ -0,0 +1,183 @@
+// Package mindmap parses mermaid mindmaps and renders them as ASCII: a tree
+// growing left to right from its root, each node joined to its children by
+// connector lines.
+package mindmap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const mindmapKeyword = "mindmap"
+
+// Shape is how a node is outlined; in ASCII it's kept as the brackets the
+// node was written with.
+type Shape int
+
+const (
+	ShapeDefault Shape = iota // text
+	ShapeSquare               // [text]
+	ShapeRounded              // (text)
+	ShapeCircle               // ((text))
+	ShapeBang                 // ))text((
+	ShapeCloud                // )text(
+	ShapeHexagon              // {{text}}
+)
+
+// shapeBrackets maps each shape to the brackets that open and close it.
+var shapeBrackets = map[Shape][2]string{
+	ShapeSquare:  {"[", "]"},
+	ShapeRounded: {"(", ")"},
+	ShapeCircle:  {"((", "))"},
+	ShapeBang:    {"))", "(("},
+	ShapeCloud:   {")", "("},
+	ShapeHexagon: {"{{", "}}"},
+}
+
+// Node is one idea of the mindmap.
+type Node struct {
+	ID       string
+	Text     string
+	Shape    Shape
+	Children []*Node
+	indent   int
+}
+
+// Label is the node's text within its shape's brackets.
+func (n *Node) Label() string {
+	b := shapeBrackets[n.Shape]
+	return b[0] + n.Text + b[1]
+}
+
+// Mindmap is a parsed mindmap: a tree under a single root, which is nil for
+// an empty mindmap.
+type Mindmap struct {
+	Root *Node
+}
+
+var (
+	// headerRegex matches the diagram declaration.
+	headerRegex = regexp.MustCompile(`^mindmap\s*$`)
+
+	// decorationRegex matches the icon and class lines that decorate the
+	// node before them; neither has an ASCII meaning.
+	decorationRegex = regexp.MustCompile(`^(::icon\(.*\)|:::.*)$`)
+
+	// shapedNodeRegex matches `id[text]` and the other shapes: an id
+	// followed straight away by an opening bracket, the text and the
+	// closing bracket.
+	shapedNodeRegex = regexp.MustCompile(`^([^\s\[\](){}]*)(\(\(|\)\)|\{\{|\(|\)|\[)(.*?)(\)\)|\(\(|\}\}|\)|\(|\])$`)
+
+	// breakRegex matches a <br> line break; nodes are drawn on a single row,
+	// so the lines are joined with a space.
+	breakRegex = regexp.MustCompile(`(?i)\s*<br\s*/?>\s*`)
+)
+
+// IsMindmap reports whether the input's first meaningful line declares a
+// mindmap.
+func IsMindmap(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// Parse parses a mindmap. Each node's parent is the closest node above it
+// that is indented less; the first node is the root, and no other node may
+// be indented as little as it is.
+func Parse(input string) (*Mindmap, error) {
+	if !IsMindmap(input) {
+		return nil, fmt.Errorf("expected %q keyword", mindmapKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	m := &Mindmap{}
+	var path []*Node // the last node seen at each depth
+	seenKeyword := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			seenKeyword = true
+			continue
+		}
+		if decorationRegex.MatchString(line) {
+			if len(path) == 0 {
+				return nil, diagram.LineErrorf(i+1, "%q decorates no node", line)
+			}
+			continue
+		}
+
+		n, err := parseNode(line)
+		if err != nil {
+			return nil, diagram.LineErrorf(i+1, "%w", err)
+		}
+		n.indent = len(raw) - len(strings.TrimLeft(raw, " \t"))
+		if m.Root == nil {
+			m.Root = n
+			path = []*Node{n}
+			continue
+		}
+		if n.indent <= m.Root.indent {
+			return nil, diagram.LineErrorf(i+1, "%q is not indented under the root, and there can be only one root", line)
+		}
+		for path[len(path)-1].indent >= n.indent {
+			path = path[:len(path)-1]
+		}
+		parent := path[len(path)-1]
+		parent.Children = append(parent.Children, n)
+		path = append(path, n)
+	}
+	return m, nil
+}
+
+// parseNode reads a node line: text on its own, or an id with the text in
+// a shape's brackets. Quotes and markdown backticks around the text are
+// dropped, and line breaks become spaces.
+func parseNode(line string) (*Node, error) {
+	// An icon or class written on the node's own line is dropped too.
+	if idx := strings.Index(line, ":::"); idx > 0 {
+		line = strings.TrimSpace(line[:idx])
+	}
+	if idx := strings.Index(line, "::icon("); idx > 0 {
+		line = strings.TrimSpace(line[:idx])
+	}
+
+	n := &Node{Text: line}
+	if m := shapedNodeRegex.FindStringSubmatch(line); m != nil {
+		shape := ShapeDefault
+		for s, b := range shapeBrackets {
+			if b[0] == m[2] && b[1] == m[4] {
+				shape = s
+			}
+		}
+		if shape == ShapeDefault {
+			return nil, fmt.Errorf("node %q opens with %q but closes with %q", line, m[2], m[4])
+		}
+		n.ID, n.Text, n.Shape = m[1], m[3], shape
+	}
+	n.Text = strings.TrimSpace(n.Text)
+	if len(n.Text) >= 2 && strings.HasPrefix(n.Text, `"`) && strings.HasSuffix(n.Text, `"`) {
+		n.Text = n.Text[1 : len(n.Text)-1]
+	}
+	if len(n.Text) >= 2 && strings.HasPrefix(n.Text, "`") && strings.HasSuffix(n.Text, "`") {
+		n.Text = n.Text[1 : len(n.Text)-1]
+	}
+	n.Text = breakRegex.ReplaceAllString(n.Text, " ")
+	if n.ID == "" {
+		n.ID = n.Text
+	}
+	return n, nil
+}
@@ -0,0 +1,123 @@
+package mindmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMindmap(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"mindmap\n root", true},
+		{"%% leading comment\nmindmap", true},
+		{"mindmaps\n root", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsMindmap(c.in); got != c.want {
+			t.Errorf("IsMindmap(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	m, err := Parse(`mindmap
+  root
+    a
+      a1
+        a1x
+      a2
+    b
+   c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// describe writes a subtree as text(children...).
+	var describe func(n *Node) string
+	describe = func(n *Node) string {
+		s := n.Text
+		if len(n.Children) > 0 {
+			var children []string
+			for _, c := range n.Children {
+				children = append(children, describe(c))
+			}
+			s += "(" + strings.Join(children, " ") + ")"
+		}
+		return s
+	}
+	if got, want := describe(m.Root), "root(a(a1(a1x) a2) b c)"; got != want {
+		t.Errorf("tree = %s, want %s", got, want)
+	}
+}
+
+func TestParseNodes(t *testing.T) {
+	for _, c := range []struct {
+		in        string
+		id, text  string
+		shape     Shape
+		wantLabel string
+	}{
+		{"plain text", "plain text", "plain text", ShapeDefault, "plain text"},
+		{"id[Square]", "id", "Square", ShapeSquare, "[Square]"},
+		{"id(Rounded)", "id", "Rounded", ShapeRounded, "(Rounded)"},
+		{"id((Circle))", "id", "Circle", ShapeCircle, "((Circle))"},
+		{"id))Bang((", "id", "Bang", ShapeBang, "))Bang(("},
+		{"id)Cloud(", "id", "Cloud", ShapeCloud, ")Cloud("},
+		{"id{{Hexagon}}", "id", "Hexagon", ShapeHexagon, "{{Hexagon}}"},
+		{`id["quoted text"]`, "id", "quoted text", ShapeSquare, "[quoted text]"},
+		{"id[\"`**md**`\"]", "id", "**md**", ShapeSquare, "[**md**]"},
+		{"two<br/>lines", "two lines", "two lines", ShapeDefault, "two lines"},
+		{"id[Icon] ::icon(fa fa-book)", "id", "Icon", ShapeSquare, "[Icon]"},
+		{"Classy :::urgent large", "Classy", "Classy", ShapeDefault, "Classy"},
+	} {
+		m, err := Parse("mindmap\n " + c.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.in, err)
+			continue
+		}
+		n := m.Root
+		if n.ID != c.id || n.Text != c.text || n.Shape != c.shape || n.Label() != c.wantLabel {
+			t.Errorf("%q parsed as id %q, text %q, shape %d, label %q; want %q, %q, %d, %q",
+				c.in, n.ID, n.Text, n.Shape, n.Label(), c.id, c.text, c.shape, c.wantLabel)
+		}
+	}
+}
+
+func TestParseDecorationLines(t *testing.T) {
+	m, err := Parse("mindmap\n root\n  a\n  ::icon(fa fa-book)\n  :::urgent\n  b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Root.Children) != 2 {
+		t.Errorf("root has %d children, want 2: icons and classes aren't nodes", len(m.Root.Children))
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	m, err := Parse("mindmap\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Root != nil {
+		t.Errorf("root = %+v, want none", m.Root)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"mindmap\n root\n other", "only one root"},
+		{"mindmap\n  root\n   a\n other", "only one root"},
+		{"mindmap\n root\n  a(bad]", `opens with "(" but closes with "]"`},
+		{"mindmap\n ::icon(fa fa-book)", "decorates no node"},
+		{"graph TD\n A-->B", `expected "mindmap" keyword`},
+	} {
+		_, err := Parse(c.in)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", c.in, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package mindmap
+
+import (
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a mindmap's connectors are drawn with
+// (Unicode by default, ASCII when useAscii), by the arms of each glyph: up,
+// right, down and left.
+type glyphs map[[4]bool]rune
+
+var unicodeGlyphs = glyphs{
+	{false, true, false, true}: '─', {true, false, true, false}: '│',
+	{false, true, true, false}: '┌', {true, true, false, false}: '└',
+	{true, true, true, false}: '├', {false, true, true, true}: '┬',
+	{true, true, false, true}: '┴', {true, true, true, true}: '┼',
+	{false, false, true, true}: '┐', {true, false, false, true}: '┘',
+	{true, false, true, true}: '┤',
+}
+
+var asciiGlyphs = glyphs{
+	{false, true, false, true}: '-', {true, false, true, false}: '|',
+}
+
+// glyph returns the glyph with the given arms, which ASCII draws as '+'
+// wherever lines meet.
+func (g glyphs) glyph(arms [4]bool) rune {
+	if r, ok := g[arms]; ok {
+		return r
+	}
+	return '+'
+}
+
+// Gaps around a node's connector: a blank column after its label, at least
+// one column of line before the junction its children hang off, and a line
+// and a blank column from the junction to each child.
+const (
+	labelGap    = 1
+	minLineRun  = 1
+	childIndent = 3
+)
+
+// Render draws the mindmap as a tree growing left to right. Every node is
+// on the same row as one of its children, the middle one, with its
+// children's subtrees stacked above and below it and a blank row between
+// subtrees of more than one row. Children start at the same column,
+// joined to their parent through a vertical line right after its label.
+func Render(m *Mindmap, config *diagram.Config) (string, error) {
+	gl := unicodeGlyphs
+	if config.UseAscii {
+		gl = asciiGlyphs
+	}
+	if m.Root == nil {
+		return "", nil
+	}
+	r := &renderer{glyphs: gl}
+	r.place(m.Root, 0, 0)
+	var b strings.Builder
+	for _, line := range r.lines {
+		b.WriteString(strings.TrimRight(strings.ReplaceAll(string(line), "\x00", ""), " "))
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+type renderer struct {
+	glyphs glyphs
+	lines  [][]rune
+}
+
+// height is how many rows n's subtree takes up.
+func height(n *Node) int {
+	if len(n.Children) == 0 {
+		return 1
+	}
+	h := 0
+	for i, c := range n.Children {
+		if i > 0 && (height(n.Children[i-1]) > 1 || height(c) > 1) {
+			h++
+		}
+		h += height(c)
+	}
+	return h
+}
+
+// place draws n's subtree with its top row at top and n's label starting at
+// column x, and returns the row n is on.
+func (r *renderer) place(n *Node, x, top int) int {
+	if len(n.Children) == 0 {
+		r.write(x, top, n.Label())
+		return top
+	}
+
+	junction := x + runewidth.StringWidth(n.Label()) + labelGap + minLineRun
+	rows := make([]int, len(n.Children))
+	y := top
+	for i, c := range n.Children {
+		if i > 0 && (height(n.Children[i-1]) > 1 || height(c) > 1) {
+			y++
+		}
+		rows[i] = r.place(c, junction+childIndent, y)
+		y += height(c)
+	}
+	row := rows[(len(rows)-1)/2]
+
+	r.write(x, row, n.Label())
+	for c := x + runewidth.StringWidth(n.Label()) + labelGap; c < junction; c++ {
+		r.set(c, row, r.glyphs.glyph([4]bool{false, true, false, true}))
+	}
+	first, last := rows[0], rows[len(rows)-1]
+	childRows := map[int]bool{}
+	for _, cr := range rows {
+		childRows[cr] = true
+	}
+	for y := first; y <= last; y++ {
+		arms := [4]bool{y > first, childRows[y], y < last, y == row}
+		r.set(junction, y, r.glyphs.glyph(arms))
+		if childRows[y] {
+			for c := junction + 1; c < junction+childIndent-1; c++ {
+				r.set(c, y, r.glyphs.glyph([4]bool{false, true, false, true}))
+			}
+		}
+	}
+	return row
+}
+
+// write writes s on row y from column x on, a cell per column it takes up.
+// The lines hold a rune per column, so a wide rune is followed by nothing
+// in the column it spills into.
+func (r *renderer) write(x, y int, s string) {
+	for _, c := range s {
+		w := max(runewidth.RuneWidth(c), 1)
+		r.set(x, y, c)
+		for i := 1; i < w; i++ {
+			r.set(x+i, y, 0)
+		}
+		x += w
+	}
+}
+
+func (r *renderer) set(x, y int, c rune) {
+	for len(r.lines) <= y {
+		r.lines = append(r.lines, nil)
+	}
+	for len(r.lines[y]) <= x {
+		r.lines[y] = append(r.lines[y], ' ')
+	}
+	r.lines[y][x] = c
+}
@@ -0,0 +1,32 @@
+package pie
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		p, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(p, config)
+	}
+}
+
+// TestPieChartRendering tests all pie chart golden files with Unicode charset.
+func TestPieChartRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "pie", renderGolden(false))
+}
+
+// TestPieChartRendering_ASCII tests pie chart golden files with ASCII charset.
+func TestPieChartRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "pie-ascii", renderGolden(true))
+}
@@ -0,0 +1,116 @@
+// Package pie parses mermaid pie charts and renders them as ASCII: a
+// horizontal bar per slice, as long as its share of the whole, labelled
+// with its name and percentage.
+package pie
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const pieKeyword = "pie"
+
+// Slice is one labelled value of the chart.
+type Slice struct {
+	Label string
+	Value float64
+}
+
+// PieChart is a parsed pie chart. Slices are kept in the order they're
+// written; ShowData adds each slice's value beside its percentage.
+type PieChart struct {
+	Title    string
+	ShowData bool
+	Slices   []*Slice
+}
+
+var (
+	// headerRegex matches the diagram declaration, which may carry the
+	// showData flag and the title.
+	headerRegex = regexp.MustCompile(`^pie(\s+showData)?(?:\s+title\s+(.*))?\s*$`)
+
+	// titleRegex matches a title on a line of its own.
+	titleRegex = regexp.MustCompile(`^title\s+(.+)$`)
+
+	// ignoredLineRegex matches accessibility statements, which carry no
+	// ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(accTitle|accDescr)\b`)
+
+	// sliceRegex matches `"Label" : 42.5`.
+	sliceRegex = regexp.MustCompile(`^"([^"]*)"\s*:\s*(\S+)$`)
+)
+
+// IsPieChart reports whether the input's first meaningful line declares a
+// pie chart.
+func IsPieChart(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// Parse parses a pie chart into its slices. As in mermaid, a label written
+// twice keeps its first value.
+func Parse(input string) (*PieChart, error) {
+	if !IsPieChart(input) {
+		return nil, fmt.Errorf("expected %q keyword", pieKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	p := &PieChart{}
+	seen := map[string]bool{}
+	seenKeyword := false
+	total := 0.0
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			m := headerRegex.FindStringSubmatch(line)
+			p.ShowData = m[1] != ""
+			p.Title = strings.TrimSpace(m[2])
+			seenKeyword = true
+			continue
+		}
+		if ignoredLineRegex.MatchString(line) {
+			continue
+		}
+		if m := titleRegex.FindStringSubmatch(line); m != nil {
+			p.Title = strings.TrimSpace(m[1])
+			continue
+		}
+		if m := sliceRegex.FindStringSubmatch(line); m != nil {
+			value, err := strconv.ParseFloat(m[2], 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, diagram.LineErrorf(i+1, "invalid value %q for %q", m[2], m[1])
+			}
+			if value < 0 {
+				return nil, diagram.LineErrorf(i+1, "value of %q must not be negative", m[1])
+			}
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				// Shares are worked out from the total, which has to stay finite.
+				if total += value; math.IsInf(total, 0) {
+					return nil, diagram.LineErrorf(i+1, "value of %q makes the total too large", m[1])
+				}
+				p.Slices = append(p.Slices, &Slice{Label: m[1], Value: value})
+			}
+			continue
+		}
+		return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
+	}
+	return p, nil
+}
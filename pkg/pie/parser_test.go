@@ -0,0 +1,119 @@
+package pie
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+func TestIsPieChart(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"pie\n \"A\" : 1", true},
+		{"pie showData title Pets", true},
+		{"%% leading comment\npie title Pets", true},
+		{"pieChart\n \"A\" : 1", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsPieChart(c.in); got != c.want {
+			t.Errorf("IsPieChart(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseHeaderAndSlices(t *testing.T) {
+	for _, c := range []struct {
+		in       string
+		title    string
+		showData bool
+	}{
+		{"pie\n \"A\" : 1", "", false},
+		{"pie title Pets\n \"A\" : 1", "Pets", false},
+		{"pie showData\n title Pets\n \"A\" : 1", "Pets", true},
+		{"pie showData title Pets\n accTitle: pets\n \"A\" : 1", "Pets", true},
+	} {
+		p, err := Parse(c.in)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", c.in, err)
+		}
+		if p.Title != c.title || p.ShowData != c.showData {
+			t.Errorf("Parse(%q) = title %q, showData %v; want %q, %v", c.in, p.Title, p.ShowData, c.title, c.showData)
+		}
+	}
+
+	p, err := Parse("pie\n \"Dogs\" : 386\n \"Cats\": 85.5\n \"Dogs\" : 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Slices) != 2 || p.Slices[0].Value != 386 || p.Slices[1].Label != "Cats" || p.Slices[1].Value != 85.5 {
+		t.Errorf("slices = %+v, %+v; want Dogs 386 then Cats 85.5", p.Slices[0], p.Slices[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"pie\n \"A\" : lots", "invalid value"},
+		{"pie\n \"A\" : NaN", "invalid value"},
+		{"pie\n \"A\" : -3", "must not be negative"},
+		{"pie\n A : 3", "invalid syntax"},
+		{"pie\n \"A\" : 1e308\n \"B\" : 1e308", "line 3: value of \"B\" makes the total too large"},
+	} {
+		_, err := Parse(c.in)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", c.in, err, c.want)
+		}
+	}
+}
+
+func TestBarsAreProportional(t *testing.T) {
+	p, err := Parse("pie\n \"A\" : 3\n \"B\" : 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := diagram.DefaultConfig()
+	config.UseAscii = true
+	out, err := Render(p, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out, "\n")
+	if got := strings.Count(lines[0], "#"); got != barWidth*3/4 {
+		t.Errorf("A's bar is %d columns, want %d:\n%s", got, barWidth*3/4, out)
+	}
+	if got := strings.Count(lines[1], "#"); got != barWidth/4 {
+		t.Errorf("B's bar is %d columns, want %d:\n%s", got, barWidth/4, out)
+	}
+	if !strings.HasSuffix(lines[0], "75.0%") || !strings.HasSuffix(lines[1], "25.0%") {
+		t.Errorf("want the percentages at the end of the lines:\n%s", out)
+	}
+}
+
+func TestRenderWithoutValues(t *testing.T) {
+	p, err := Parse("pie\n \"A\" : 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Render(p, diagram.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(out), "0.0%") || strings.Contains(out, "█") {
+		t.Errorf("want an empty bar at 0%%:\n%s", out)
+	}
+}
+
+func TestParseKeepsPercentSignsInLabels(t *testing.T) {
+	p, err := Parse("pie\n \"100%% sure\" : 3 %% a comment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Slices) != 1 || p.Slices[0].Label != "100%% sure" || p.Slices[0].Value != 3 {
+		t.Errorf("slices = %+v, want one \"100%%%% sure\" slice of 3", p.Slices)
+	}
+}
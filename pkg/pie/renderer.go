@@ -0,0 +1,84 @@
+package pie
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// barWidth is how many columns a slice that is the whole pie spans.
+const barWidth = 40
+
+// eighths are the Unicode blocks that fill one to seven eighths of a
+// column, for the part of a bar that doesn't fill a whole one.
+var eighths = []rune("▏▎▍▌▋▊▉")
+
+// Render draws the chart as a bar per slice, under its title: the slice's
+// label, a bar as long as its share of the total, and that share as a
+// percentage, followed by the value itself when ShowData is set. Unicode
+// bars are drawn to an eighth of a column, ASCII ones with '#' to the
+// nearest column.
+func Render(p *PieChart, config *diagram.Config) (string, error) {
+	total := 0.0
+	labelWidth := 0
+	for _, s := range p.Slices {
+		total += s.Value
+		labelWidth = max(labelWidth, runewidth.StringWidth(s.Label))
+	}
+
+	percents := make([]string, len(p.Slices))
+	values := make([]string, len(p.Slices))
+	percentWidth, valueWidth := 0, 0
+	for i, s := range p.Slices {
+		share := 0.0
+		if total > 0 {
+			share = s.Value / total
+		}
+		percents[i] = fmt.Sprintf("%.1f%%", share*100)
+		values[i] = "[" + strconv.FormatFloat(s.Value, 'f', -1, 64) + "]"
+		percentWidth = max(percentWidth, len(percents[i]))
+		valueWidth = max(valueWidth, len(values[i]))
+	}
+
+	var lines []string
+	if p.Title != "" {
+		total := labelWidth + 2 + barWidth + 2 + percentWidth
+		if p.ShowData {
+			total += 2 + valueWidth
+		}
+		pad := max(0, (total-runewidth.StringWidth(p.Title))/2)
+		lines = append(lines, strings.Repeat(" ", pad)+p.Title, "")
+	}
+	for i, s := range p.Slices {
+		share := 0.0
+		if total > 0 {
+			share = s.Value / total
+		}
+		line := s.Label + strings.Repeat(" ", labelWidth-runewidth.StringWidth(s.Label)) + "  " +
+			bar(share, config.UseAscii) + "  " +
+			strings.Repeat(" ", percentWidth-len(percents[i])) + percents[i]
+		if p.ShowData {
+			line += "  " + strings.Repeat(" ", valueWidth-len(values[i])) + values[i]
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// bar draws a share of the whole pie, padded to barWidth columns.
+func bar(share float64, useAscii bool) string {
+	if useAscii {
+		n := int(math.Round(share * barWidth))
+		return strings.Repeat("#", n) + strings.Repeat(" ", barWidth-n)
+	}
+	n := int(math.Round(share * barWidth * 8))
+	b := strings.Repeat("█", n/8)
+	if n%8 != 0 {
+		b += string(eighths[n%8-1])
+	}
+	return b + strings.Repeat(" ", barWidth-(n+7)/8)
+}
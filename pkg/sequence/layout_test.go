@@ -0,0 +1,78 @@
+package sequence
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+// TestLayoutMatchesRender checks that participant boxes, lifelines and message
+// rows reported by Layout line up with what Render draws, including inside
+// fragments and participant-group boxes that push the body down.
+func TestLayoutMatchesRender(t *testing.T) {
+	input := `sequenceDiagram
+box Group
+participant A
+end
+participant B
+A->>B: hello
+loop every minute
+B->>B: self
+alt ok
+A->>B: again
+else fail
+B-->>A: nope
+end
+end
+B-->>A: done`
+	sd, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := diagram.DefaultConfig()
+	text, err := Render(sd, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout, err := Layout(sd, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows [][]rune
+	for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		rows = append(rows, []rune(l))
+	}
+	at := func(x, y int) rune {
+		if y < 0 || y >= len(rows) || x < 0 || x >= len(rows[y]) {
+			return ' '
+		}
+		return rows[y][x]
+	}
+	if layout.Height != len(rows) {
+		t.Errorf("height = %d, render has %d rows", layout.Height, len(rows))
+	}
+
+	for _, p := range layout.Participants {
+		if got := at(p.Box.X, p.Box.Y); got != Unicode.TopLeft {
+			t.Errorf("participant %s top-left = %q", p.ID, got)
+		}
+		if got := at(p.Lifeline, p.Box.Y+p.Box.Height-1); got != Unicode.TeeDown {
+			t.Errorf("participant %s lifeline column %d doesn't leave its box (%q)", p.ID, p.Lifeline, got)
+		}
+	}
+
+	if len(layout.Messages) != 5 {
+		t.Fatalf("got %d messages, want 5", len(layout.Messages))
+	}
+	for _, m := range layout.Messages {
+		start := m.Points[0]
+		if got := at(start.X, start.Y); got != Unicode.TeeRight && got != Unicode.TeeLeft {
+			t.Errorf("message %s->%s %q starts on %q at %v, want a tee", m.From, m.To, m.Label, got, start)
+		}
+		if !strings.Contains(string(rows[m.Row-1]), m.Label) {
+			t.Errorf("message %q: label not on the row above row %d", m.Label, m.Row)
+		}
+	}
+}
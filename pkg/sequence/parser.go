@@ -375,13 +375,20 @@ func hasSequenceKeyword(line string) bool {
 }
 
 func Parse(input string) (*SequenceDiagram, error) {
-	input = strings.TrimSpace(input)
-	if input == "" {
+	if strings.TrimSpace(input) == "" {
 		return nil, fmt.Errorf("empty input")
 	}
 
-	rawLines := diagram.SplitLines(input)
-	lines := diagram.RemoveComments(rawLines)
+	// lines holds the lines left once comments and blank lines are dropped,
+	// and lineNumbers the line of the input each of them is on.
+	var lines []string
+	var lineNumbers []int
+	for i, raw := range diagram.SplitLines(input) {
+		if cleaned := diagram.RemoveComments([]string{raw}); len(cleaned) == 1 {
+			lines = append(lines, cleaned[0])
+			lineNumbers = append(lineNumbers, i+1)
+		}
+	}
 	if len(lines) == 0 {
 		return nil, fmt.Errorf("no content found")
 	}
@@ -389,7 +396,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 	if !hasSequenceKeyword(strings.TrimSpace(lines[0])) {
 		return nil, fmt.Errorf("expected %q keyword", SequenceDiagramKeyword)
 	}
-	lines = lines[1:]
+	lines, lineNumbers = lines[1:], lineNumbers[1:]
 
 	sd := &SequenceDiagram{
 		Participants: []*Participant{},
@@ -428,14 +435,14 @@ func Parse(input string) (*SequenceDiagram, error) {
 				continue
 			}
 			if boxStartRegex.MatchString(trimmed) {
-				return nil, fmt.Errorf("line %d: boxes cannot nest", i+2)
+				return nil, diagram.LineErrorf(lineNumbers[i], "boxes cannot nest")
 			}
 			p, matched, err := sd.parseParticipant(trimmed, participantMap)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: %w", i+2, err)
+				return nil, diagram.LineErrorf(lineNumbers[i], "%w", err)
 			}
 			if !matched {
-				return nil, fmt.Errorf("line %d: only participant declarations are allowed inside a box: %q", i+2, trimmed)
+				return nil, diagram.LineErrorf(lineNumbers[i], "only participant declarations are allowed inside a box: %q", trimmed)
 			}
 			if openBox.First == -1 {
 				openBox.First = p.Index
@@ -477,7 +484,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 				}
 			}
 			if len(parts) == 0 {
-				return nil, fmt.Errorf("line %d: note without a participant", i+2)
+				return nil, diagram.LineErrorf(lineNumbers[i], "note without a participant")
 			}
 			// Mermaid allows an optional wrap:/nowrap: prefix on note text;
 			// wrapping is irrelevant for single-line ASCII, so just strip it.
@@ -496,7 +503,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 		}
 
 		if _, matched, err := sd.parseParticipant(trimmed, participantMap); err != nil {
-			return nil, fmt.Errorf("line %d: %w", i+2, err)
+			return nil, diagram.LineErrorf(lineNumbers[i], "%w", err)
 		} else if matched {
 			continue
 		}
@@ -506,7 +513,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 		// only bare openers like "loop retry" fall through to the checks below.
 		msgIdx := len(sd.Events)
 		if matched, err := sd.parseMessageEvent(trimmed, participantMap, active); err != nil {
-			return nil, fmt.Errorf("line %d: %w", i+2, err)
+			return nil, diagram.LineErrorf(lineNumbers[i], "%w", err)
 		} else if matched {
 			// A create/destroy statement binds to this, the next message, which
 			// must involve its participant: mermaid requires a created
@@ -516,7 +523,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 			if p := pendingCreate; p != nil {
 				pendingCreate = nil
 				if msg.To != p {
-					return nil, fmt.Errorf("line %d: the created participant %q must receive the message that creates it", createLine, p.ID)
+					return nil, diagram.LineErrorf(createLine, "the created participant %q must receive the message that creates it", p.ID)
 				}
 				// The lifeline starts at this message, so the event precedes it.
 				sd.Events = append(sd.Events, Event{})
@@ -526,7 +533,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 			if p := pendingDestroy; p != nil {
 				pendingDestroy = nil
 				if msg.From != p && msg.To != p {
-					return nil, fmt.Errorf("line %d: the destroyed participant %q is not involved in the following message", destroyLine, p.ID)
+					return nil, diagram.LineErrorf(destroyLine, "the destroyed participant %q is not involved in the following message", p.ID)
 				}
 				// The lifeline ends after this message.
 				sd.Events = append(sd.Events, Event{Kind: EventDestroy, Participant: p})
@@ -540,13 +547,13 @@ func Parse(input string) (*SequenceDiagram, error) {
 			// mermaid rejects creating an id that already exists, even one only
 			// implied by an earlier message, and points at AS aliases instead.
 			if name, ok := parseName(nameBeforeAlias(m[2])); ok && participantMap[name] != nil {
-				return nil, fmt.Errorf("line %d: cannot create participant %q: the id already exists, use an \"as\" alias for a distinct participant", i+2, name)
+				return nil, diagram.LineErrorf(lineNumbers[i], "cannot create participant %q: the id already exists, use an \"as\" alias for a distinct participant", name)
 			}
 			p, err := sd.declareParticipant(m[2], participantMap)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: %w", i+2, err)
+				return nil, diagram.LineErrorf(lineNumbers[i], "%w", err)
 			}
-			pendingCreate, createLine = p, i+2
+			pendingCreate, createLine = p, lineNumbers[i]
 			sd.Created = append(sd.Created, p)
 			continue
 		}
@@ -555,13 +562,13 @@ func Parse(input string) (*SequenceDiagram, error) {
 		if m := destroyRegex.FindStringSubmatch(trimmed); m != nil {
 			name, nameOK := parseName(m[1])
 			if !nameOK {
-				return nil, fmt.Errorf("line %d: invalid participant name %q", i+2, m[1])
+				return nil, diagram.LineErrorf(lineNumbers[i], "invalid participant name %q", m[1])
 			}
 			p, exists := participantMap[name]
 			if !exists {
-				return nil, fmt.Errorf("line %d: cannot destroy unknown participant %q", i+2, name)
+				return nil, diagram.LineErrorf(lineNumbers[i], "cannot destroy unknown participant %q", name)
 			}
-			pendingDestroy, destroyLine = p, i+2
+			pendingDestroy, destroyLine = p, lineNumbers[i]
 			continue
 		}
 
@@ -570,7 +577,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 		if m := activationRegex.FindStringSubmatch(trimmed); m != nil {
 			name, nameOK := parseName(m[2])
 			if !nameOK {
-				return nil, fmt.Errorf("line %d: invalid participant name %q", i+2, m[2])
+				return nil, diagram.LineErrorf(lineNumbers[i], "invalid participant name %q", m[2])
 			}
 			p := sd.getParticipant(name, participantMap)
 			if strings.EqualFold(m[1], "activate") {
@@ -578,7 +585,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 				sd.Events = append(sd.Events, Event{Kind: EventActivate, Participant: p})
 			} else {
 				if active[p] == 0 {
-					return nil, fmt.Errorf("line %d: trying to deactivate an inactive participant %q", i+2, p.ID)
+					return nil, diagram.LineErrorf(lineNumbers[i], "trying to deactivate an inactive participant %q", p.ID)
 				}
 				active[p]--
 				sd.Events = append(sd.Events, Event{Kind: EventDeactivate, Participant: p})
@@ -608,7 +615,7 @@ func Parse(input string) (*SequenceDiagram, error) {
 		if match := fragmentDividerRegex.FindStringSubmatch(trimmed); match != nil {
 			want := dividerKeywords[strings.ToLower(match[1])]
 			if len(openFragments) == 0 || openFragments[len(openFragments)-1] != want {
-				return nil, fmt.Errorf("line %d: %q outside a matching %s block", i+2, trimmed, want)
+				return nil, diagram.LineErrorf(lineNumbers[i], "%q outside a matching %s block", trimmed, want)
 			}
 			sd.Events = append(sd.Events, Event{
 				Kind:     EventFragmentDivider,
@@ -620,21 +627,21 @@ func Parse(input string) (*SequenceDiagram, error) {
 		// "end" closes the most recently opened fragment.
 		if fragmentEndRegex.MatchString(trimmed) {
 			if len(openFragments) == 0 {
-				return nil, fmt.Errorf("line %d: %q without a matching fragment opener", i+2, trimmed)
+				return nil, diagram.LineErrorf(lineNumbers[i], "%q without a matching fragment opener", trimmed)
 			}
 			sd.Events = append(sd.Events, Event{Kind: EventFragmentEnd})
 			openFragments = openFragments[:len(openFragments)-1]
 			continue
 		}
 
-		return nil, fmt.Errorf("line %d: invalid syntax: %q", i+2, trimmed)
+		return nil, diagram.LineErrorf(lineNumbers[i], "invalid syntax: %q", trimmed)
 	}
 
 	if p := pendingCreate; p != nil {
-		return nil, fmt.Errorf("line %d: the created participant %q must be followed by a message involving it", createLine, p.ID)
+		return nil, diagram.LineErrorf(createLine, "the created participant %q must be followed by a message involving it", p.ID)
 	}
 	if p := pendingDestroy; p != nil {
-		return nil, fmt.Errorf("line %d: the destroyed participant %q must be followed by a message involving it", destroyLine, p.ID)
+		return nil, diagram.LineErrorf(destroyLine, "the destroyed participant %q must be followed by a message involving it", p.ID)
 	}
 	if openBox != nil {
 		return nil, fmt.Errorf("unclosed box: missing \"end\"")
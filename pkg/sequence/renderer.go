@@ -28,6 +28,29 @@ type diagramLayout struct {
 	totalWidth         int
 	messageSpacing     int
 	selfMessageWidth   int
+
+	// messageRows, when non-nil, collects the line each message's arrow is
+	// drawn on (the top of the loop for self-messages). renderEvents records
+	// rows relative to the lines it returns; whoever places those lines below
+	// others shifts them with shiftMessageRows.
+	messageRows map[*Message]int
+	// headerRow is the line the participant boxes start on.
+	headerRow int
+}
+
+// shiftMessageRows moves the recorded rows of every message among events down
+// by n lines.
+func (l *diagramLayout) shiftMessageRows(events []Event, n int) {
+	if l.messageRows == nil {
+		return
+	}
+	for _, ev := range events {
+		if ev.Kind == EventMessage {
+			if _, ok := l.messageRows[ev.Message]; ok {
+				l.messageRows[ev.Message] += n
+			}
+		}
+	}
 }
 
 func calculateLayout(sd *SequenceDiagram, config *diagram.Config) *diagramLayout {
@@ -81,8 +104,57 @@ func calculateLayout(sd *SequenceDiagram, config *diagram.Config) *diagramLayout
 }
 
 func Render(sd *SequenceDiagram, config *diagram.Config) (string, error) {
+	lines, _, err := render(sd, config, false)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// Layout renders the diagram the same way Render does and reports where the
+// participant boxes, lifelines and message arrows ended up.
+func Layout(sd *SequenceDiagram, config *diagram.Config) (*diagram.Layout, error) {
+	lines, layout, err := render(sd, config, true)
+	if err != nil {
+		return nil, err
+	}
+	out := &diagram.Layout{Type: "sequence", Height: len(lines)}
+	for _, l := range lines {
+		out.Width = max(out.Width, runewidth.StringWidth(l))
+	}
+	for i, p := range sd.Participants {
+		boxWidth := layout.participantWidths[i] + boxBorderWidth
+		center := layout.participantCenters[i]
+		out.Participants = append(out.Participants, diagram.Participant{
+			ID:       p.ID,
+			Label:    p.Label,
+			Box:      diagram.Rect{X: center - boxWidth/2, Y: layout.headerRow, Width: boxWidth, Height: 3},
+			Lifeline: center,
+		})
+	}
+	for _, msg := range sd.Messages {
+		row, ok := layout.messageRows[msg]
+		if !ok {
+			continue
+		}
+		from, to := layout.participantCenters[msg.From.Index], layout.participantCenters[msg.To.Index]
+		m := diagram.Message{From: msg.From.ID, To: msg.To.ID, Label: msg.Label, Row: row}
+		if msg.From == msg.To {
+			right := from + layout.selfMessageWidth - 1
+			m.Points = []diagram.Point{{X: from, Y: row}, {X: right, Y: row}, {X: right, Y: row + 2}, {X: from, Y: row + 2}}
+		} else {
+			m.Points = []diagram.Point{{X: from, Y: row}, {X: to, Y: row}}
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out, nil
+}
+
+// render draws the diagram line by line. With trackRows set, the returned
+// layout also records which line every message landed on.
+func render(sd *SequenceDiagram, config *diagram.Config, trackRows bool) ([]string, *diagramLayout, error) {
 	if sd == nil || len(sd.Participants) == 0 {
-		return "", fmt.Errorf("no participants")
+		return nil, nil, fmt.Errorf("no participants")
 	}
 	if config == nil {
 		config = diagram.DefaultConfig()
@@ -94,6 +166,9 @@ func Render(sd *SequenceDiagram, config *diagram.Config) (string, error) {
 	}
 
 	layout := calculateLayout(sd, config)
+	if trackRows {
+		layout.messageRows = map[*Message]int{}
+	}
 
 	// Fall back to a message-only body for diagrams built without an event
 	// stream (e.g. constructed by hand rather than via Parse).
@@ -151,7 +226,9 @@ func Render(sd *SequenceDiagram, config *diagram.Config) (string, error) {
 	// at the end of the diagram still marks the closing lifeline row, the way
 	// mermaid runs its activation box to the bottom.
 	act := newLifelineState(sd)
+	body := len(lines)
 	lines = append(lines, renderEvents(events, layout, chars, act)...)
+	layout.shiftMessageRows(events, body)
 
 	lines = append(lines, buildLifeline(layout, chars, act))
 
@@ -165,9 +242,11 @@ func Render(sd *SequenceDiagram, config *diagram.Config) (string, error) {
 		}
 		lines = append([]string{boxBorder(spans, chars, true)}, lines...)
 		lines = append(lines, boxBorder(spans, chars, false))
+		layout.headerRow = 1
+		layout.shiftMessageRows(events, 1)
 	}
 
-	return strings.Join(lines, "\n") + "\n", nil
+	return lines, layout, nil
 }
 
 // boxSpan is a participant group's on-canvas extent: its border columns and
@@ -347,7 +426,9 @@ func renderEvents(events []Event, layout *diagramLayout, chars BoxChars, act *li
 		ev := events[i]
 		if ev.Kind == EventFragmentStart {
 			end := matchingFragmentEnd(events, i)
+			base := len(lines)
 			lines = append(lines, wrapFragment(ev.Fragment, events[i+1:end], layout, chars, act)...)
+			layout.shiftMessageRows(events[i+1:end], base)
 			i = end + 1
 			continue
 		}
@@ -391,8 +472,14 @@ func renderEvents(events []Event, layout *diagramLayout, chars BoxChars, act *li
 		}
 		if msg.From == msg.To {
 			emit(renderSelfMessage(msg, layout, chars, act)...)
+			if layout.messageRows != nil {
+				layout.messageRows[msg] = len(lines) - 3 // the loop is three lines tall
+			}
 		} else {
 			emit(renderMessage(msg, layout, chars, act)...)
+			if layout.messageRows != nil {
+				layout.messageRows[msg] = len(lines) - 1
+			}
 		}
 		i++
 	}
@@ -687,7 +774,10 @@ func wrapFragment(frag *Fragment, inner []Event, layout *diagramLayout, chars Bo
 			dividerAt[len(body)] = dividerLabels[i-1]
 			body = append(body, "") // placeholder for the divider line
 		}
+		base := len(body)
 		body = append(body, renderEvents(sec, layout, chars, act)...)
+		// The frame's top border goes above the body.
+		layout.shiftMessageRows(sec, base+1)
 	}
 	// A trailing lifeline gives breathing room above the bottom border. It is
 	// part of the body, so it carries any activation still open here.
@@ -0,0 +1,32 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		d, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(d, config)
+	}
+}
+
+// TestStateDiagramRendering tests all state diagram golden files with Unicode charset.
+func TestStateDiagramRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "state", renderGolden(false))
+}
+
+// TestStateDiagramRendering_ASCII tests state diagram golden files with ASCII charset.
+func TestStateDiagramRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "state-ascii", renderGolden(true))
+}
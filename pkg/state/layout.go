@@ -0,0 +1,766 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// item is one state of the region being laid out.
+type item struct {
+	s         *State
+	b         *block
+	idx       int // declaration order
+	rank, pos int // rank row, and position within it
+	x, y      int // top-left of the block
+	outs, ins []*route
+}
+
+// centerOffset is the column, relative to the block, a lone transition
+// attaches to.
+func (it *item) centerOffset() int {
+	if it.b.attach >= 0 {
+		return it.b.nx + it.b.attach
+	}
+	return it.b.nx + it.b.nw/2
+}
+
+// right is the first column past the block.
+func (it *item) right() int { return it.x + it.b.w }
+
+// route is the path one transition takes through the region. Adjacent routes
+// drop from the source into the gutter below it and straight on into a target
+// in the next rank. All others (back edges, self-loops, same-rank and
+// rank-skipping transitions) detour along a vertical trunk right of every
+// state: down into the gutter below the source, along the trunk, and in
+// through the gutter above the target.
+type route struct {
+	e        *edge
+	from, to *item
+	back     bool // reversed while ranking, to break a cycle
+	adjacent bool
+	sx, tx   int // attach columns on the source's bottom and target's top face
+	// sxo and txo are sx and tx relative to their block, fixed before the
+	// blocks are placed so placement can line attach columns up.
+	sxo, txo int
+	trunk    int
+	runs     []*run
+}
+
+// run is a horizontal piece of a route inside one gutter. above/below are
+// columns where the route's vertical pieces occupy the gutter above or below
+// the run's lane; lane allocation keeps a piece ending at a column from
+// overlapping another route's piece starting there.
+type run struct {
+	r      *route
+	gutter int
+	x0, x1 int // endpoints in path order
+	lane   int
+	above  []int
+	below  []int
+	// label is drawn on the lane row starting at lx; the run reserves room
+	// for it so no other run shares those cells.
+	label string
+	lx    int
+}
+
+func (rn *run) span() (int, int) {
+	lo, hi := min(rn.x0, rn.x1), max(rn.x0, rn.x1)
+	if rn.label != "" {
+		lo = min(lo, rn.lx)
+		hi = max(hi, rn.lx+runewidth.StringWidth(rn.label)-1)
+	}
+	return lo, hi
+}
+
+// gutter is the band of rows between two ranks. Its first row is a spacer
+// under the rank above (the gutter above the top rank has none), then one row
+// per lane, then the arrowhead row.
+type gutter struct {
+	used   bool
+	lanes  int
+	top    int
+	spacer int // rows above the first lane; none above the top rank
+}
+
+func (gt *gutter) height(inner bool) int {
+	if !gt.used {
+		if inner {
+			return 1
+		}
+		return 0
+	}
+	return gt.spacer + gt.lanes + 1
+}
+
+func (gt *gutter) laneY(lane int) int { return gt.top + gt.spacer + lane }
+func (gt *gutter) arrowY() int        { return gt.top + gt.spacer + gt.lanes }
+
+const (
+	// itemGap is the horizontal space between neighbouring states in a rank.
+	itemGap = 4
+	// trunkGap separates the trunk columns from the states and each other.
+	trunkGap = 2
+)
+
+// regionLayout positions the states of one region.
+type regionLayout struct {
+	r       *renderer
+	items   []*item
+	byState map[*State]*item
+	routes  []*route
+	ranks   [][]*item
+	gutters map[int]*gutter // keyed by the rank above; -1 is above rank 0
+}
+
+// renderRegion lays out and draws one region, returning its lines.
+func (r *renderer) renderRegion(region *Region) []string {
+	l := &regionLayout{r: r, byState: map[*State]*item{}, gutters: map[int]*gutter{}}
+	for i, s := range region.States {
+		it := &item{s: s, idx: i}
+		l.items = append(l.items, it)
+		l.byState[s] = it
+	}
+	for _, e := range r.byRegion[region] {
+		rt := &route{e: e, from: l.byState[e.from], to: l.byState[e.to]}
+		l.routes = append(l.routes, rt)
+		rt.from.outs = append(rt.from.outs, rt)
+		rt.to.ins = append(rt.to.ins, rt)
+	}
+	for _, it := range l.items {
+		faces := max(len(it.outs), len(it.ins))
+		it.b = r.renderState(it.s, 2*faces+3)
+	}
+
+	l.assignRanks()
+	l.orderRanks()
+	l.assignSlots()
+	l.placeX()
+	for _, rt := range l.routes {
+		rt.sx, rt.tx = rt.from.x+rt.sxo, rt.to.x+rt.txo
+	}
+	l.planRuns()
+	l.placeY()
+	return l.draw()
+}
+
+// assignRanks breaks cycles with a depth-first search in declaration order,
+// then ranks every state by its longest path from a source.
+func (l *regionLayout) assignRanks() {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := map[*item]int{}
+	var visit func(it *item)
+	visit = func(it *item) {
+		state[it] = active
+		for _, rt := range it.outs {
+			switch state[rt.to] {
+			case unvisited:
+				visit(rt.to)
+			case active:
+				rt.back = true
+			}
+		}
+		state[it] = done
+	}
+	for _, it := range l.items {
+		if state[it] == unvisited && len(it.ins) == 0 {
+			visit(it)
+		}
+	}
+	for _, it := range l.items {
+		if state[it] == unvisited {
+			visit(it)
+		}
+	}
+
+	// Longest path over the remaining DAG, in topological order.
+	indeg := map[*item]int{}
+	for _, rt := range l.routes {
+		if !rt.back && rt.from != rt.to {
+			indeg[rt.to]++
+		}
+	}
+	var queue []*item
+	for _, it := range l.items {
+		if indeg[it] == 0 {
+			queue = append(queue, it)
+		}
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		for _, rt := range it.outs {
+			if rt.back || rt.from == rt.to {
+				continue
+			}
+			rt.to.rank = max(rt.to.rank, it.rank+1)
+			if indeg[rt.to]--; indeg[rt.to] == 0 {
+				queue = append(queue, rt.to)
+			}
+		}
+	}
+
+	for _, it := range l.items {
+		for len(l.ranks) <= it.rank {
+			l.ranks = append(l.ranks, nil)
+		}
+		l.ranks[it.rank] = append(l.ranks[it.rank], it)
+	}
+	for _, rt := range l.routes {
+		rt.adjacent = !rt.back && rt.from != rt.to && rt.to.rank == rt.from.rank+1
+	}
+}
+
+// orderRanks orders each rank by the barycenter of its predecessors in the
+// rank above, which untangles most crossings between adjacent ranks.
+func (l *regionLayout) orderRanks() {
+	for _, rank := range l.ranks {
+		for i, it := range rank {
+			it.pos = i
+		}
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, rank := range l.ranks[min(1, len(l.ranks)):] {
+			key := map[*item]float64{}
+			for _, it := range rank {
+				sum, n := 0, 0
+				for _, rt := range it.ins {
+					if rt.adjacent {
+						sum += rt.from.pos
+						n++
+					}
+				}
+				key[it] = float64(it.pos)
+				if n > 0 {
+					key[it] = float64(sum) / float64(n)
+				}
+			}
+			sort.SliceStable(rank, func(i, j int) bool { return key[rank[i]] < key[rank[j]] })
+			for i, it := range rank {
+				it.pos = i
+			}
+		}
+	}
+}
+
+// placeX packs each rank left to right, first pulling every state under its
+// predecessors and then nudging parents right over their successors, so that
+// single-file chains line up into straight vertical transitions.
+func (l *regionLayout) placeX() {
+	for _, rank := range l.ranks {
+		next := 0
+		for _, it := range rank {
+			x := next
+			if want, ok := averageX(it.ins, func(rt *route) int { return rt.from.x + rt.sxo - rt.txo }); ok {
+				x = max(x, want)
+			}
+			it.x = x
+			next = it.right() + itemGap
+		}
+	}
+	for r := len(l.ranks) - 2; r >= 0; r-- {
+		rank := l.ranks[r]
+		for i := len(rank) - 1; i >= 0; i-- {
+			it := rank[i]
+			want, ok := averageX(it.outs, func(rt *route) int { return rt.to.x + rt.txo - rt.sxo })
+			if !ok || want <= it.x {
+				continue
+			}
+			shift := want - it.x
+			if i+1 < len(rank) {
+				shift = min(shift, rank[i+1].x-itemGap-it.right())
+			}
+			it.x += max(shift, 0)
+		}
+	}
+}
+
+// averageX averages the block position each adjacent route in rts asks for.
+func averageX(rts []*route, want func(*route) int) (int, bool) {
+	sum, n := 0, 0
+	for _, rt := range rts {
+		if rt.adjacent {
+			sum += want(rt)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / n, true
+}
+
+// assignSlots hands out attach columns along each state's faces, ordered by
+// where the far end sits in its rank so neighbouring routes don't cross on
+// the way out.
+func (l *regionLayout) assignSlots() {
+	farPos := func(rt *route, far *item) int {
+		if !rt.adjacent {
+			return 1 << 30 // detours leave toward the trunks on the right
+		}
+		return far.pos
+	}
+	for _, it := range l.items {
+		outs := append([]*route(nil), it.outs...)
+		sort.SliceStable(outs, func(i, j int) bool { return farPos(outs[i], outs[i].to) < farPos(outs[j], outs[j].to) })
+		for i, rt := range outs {
+			rt.sxo = it.slot(i, len(outs))
+		}
+		ins := append([]*route(nil), it.ins...)
+		sort.SliceStable(ins, func(i, j int) bool { return farPos(ins[i], ins[i].from) < farPos(ins[j], ins[j].from) })
+		for i, rt := range ins {
+			rt.txo = it.slot(i, len(ins))
+		}
+	}
+}
+
+// slot is the column, relative to the block, of attach point i of n along
+// the state's face.
+func (it *item) slot(i, n int) int {
+	if it.b.attach >= 0 || n == 1 {
+		return it.centerOffset()
+	}
+	lo, width := it.b.nx, it.b.nw
+	if it.b.box {
+		lo, width = lo+1, width-2 // keep clear of the corners
+	}
+	return lo + width*(2*i+1)/(2*n)
+}
+
+// planRuns splits every route into horizontal runs, places trunks, and
+// allocates a lane per run within each gutter.
+func (l *regionLayout) planRuns() {
+	l.gutters[-1] = &gutter{}
+	for k := 0; k < len(l.ranks); k++ {
+		l.gutters[k] = &gutter{spacer: 1}
+	}
+	byGutter := map[int][]*run{}
+	add := func(rn *run) {
+		rn.r.runs = append(rn.r.runs, rn)
+		byGutter[rn.gutter] = append(byGutter[rn.gutter], rn)
+	}
+
+	// Adjacent routes first: their labels only depend on the states, and
+	// the trunks are kept clear of them.
+	right := 0
+	for _, it := range l.items {
+		right = max(right, it.right())
+	}
+	var detours []*route
+	for _, rt := range l.routes {
+		if !rt.adjacent {
+			detours = append(detours, rt)
+			continue
+		}
+		l.gutters[rt.from.rank].used = true
+		label := rt.e.t.Label
+		if rt.sx == rt.tx && label == "" {
+			continue // a straight drop needs no lane
+		}
+		rn := &run{r: rt, gutter: rt.from.rank, x0: rt.sx, x1: rt.tx, above: []int{rt.sx}, below: []int{rt.tx}, label: label}
+		rn.placeLabel(nil)
+		if label != "" {
+			right = max(right, rn.lx+runewidth.StringWidth(label))
+		}
+		add(rn)
+	}
+
+	// Shorter detours take the inner trunks so longer ones wrap around
+	// them. A labelled detour's trunk leaves room to centre its label on
+	// the run into the target.
+	sort.SliceStable(detours, func(i, j int) bool {
+		return abs(detours[i].to.rank-detours[i].from.rank) < abs(detours[j].to.rank-detours[j].from.rank)
+	})
+	trunk := right
+	for _, rt := range detours {
+		trunk += trunkGap
+		if rt.e.t.Label != "" {
+			trunk = max(trunk, rt.tx+runewidth.StringWidth(rt.e.t.Label)+3)
+		}
+		rt.trunk = trunk
+	}
+
+	// through lists, per gutter, the trunks crossing it without a run there.
+	through := map[int][]int{}
+	for _, rt := range detours {
+		below, above := rt.from.rank, rt.to.rank-1
+		l.gutters[below].used = true
+		l.gutters[above].used = true
+		for k := min(below, above) + 1; k < max(below, above); k++ {
+			through[k] = append(through[k], rt.trunk)
+		}
+	}
+	for _, rt := range detours {
+		below, above := rt.from.rank, rt.to.rank-1
+		first := &run{r: rt, gutter: below, x0: rt.sx, x1: rt.trunk, above: []int{rt.sx}}
+		second := &run{r: rt, gutter: above, x0: rt.trunk, x1: rt.tx, below: []int{rt.tx}, label: rt.e.t.Label}
+		if above > below { // the trunk descends from the first gutter to the second
+			first.below = append(first.below, rt.trunk)
+			second.above = append(second.above, rt.trunk)
+		} else {
+			first.above = append(first.above, rt.trunk)
+			second.below = append(second.below, rt.trunk)
+		}
+		second.placeLabel(through[above])
+		add(first)
+		add(second)
+	}
+
+	for k, runs := range byGutter {
+		l.gutters[k].lanes = allocateLanes(runs)
+	}
+}
+
+// placeLabel positions a run's label: centred on the stretch nearest the
+// target that fits it between corners and the blocked columns of lines
+// crossing the run, otherwise just past the corner at the run's far end.
+func (rn *run) placeLabel(blocked []int) {
+	if rn.label == "" {
+		return
+	}
+	lw := runewidth.StringWidth(rn.label)
+	lo, hi := min(rn.x0, rn.x1), max(rn.x0, rn.x1)
+	stops := []int{lo, hi}
+	for _, x := range blocked {
+		if x > lo && x < hi {
+			stops = append(stops, x)
+		}
+	}
+	sort.Ints(stops)
+	if rn.x1 < rn.x0 { // search from the target end
+		sort.Sort(sort.Reverse(sort.IntSlice(stops)))
+	}
+	for i := 0; i+1 < len(stops); i++ {
+		a, b := min(stops[i], stops[i+1]), max(stops[i], stops[i+1])
+		if b-a-1 >= lw+2 {
+			rn.lx = a + 1 + (b-a-1-lw)/2
+			return
+		}
+	}
+	free := func(lx int) bool {
+		for _, x := range blocked {
+			if x >= lx-1 && x <= lx+lw {
+				return false
+			}
+		}
+		return lx >= 0
+	}
+	switch {
+	case rn.x1 >= rn.x0 && free(rn.x1+2):
+		rn.lx = rn.x1 + 2
+	case rn.x1 < rn.x0 && free(rn.x1-1-lw):
+		rn.lx = rn.x1 - 1 - lw
+	default:
+		rn.lx = max(rn.x0, rn.x1) + 2
+	}
+}
+
+// allocateLanes gives each run the lowest lane where it neither touches
+// another run nor lets vertical pieces sharing a column overlap, returning
+// the number of lanes used. Runs are placed greedily; when that strands a run
+// with no lane satisfying the vertical ordering, the labelled runs are
+// retried in reverse order and the better outcome kept.
+func allocateLanes(runs []*run) int {
+	// Labelled runs go last: a label can sit below lines that only pass
+	// through, but lines must not pass through a label above them.
+	sort.SliceStable(runs, func(i, j int) bool {
+		if li, lj := runs[i].label != "", runs[j].label != ""; li != lj {
+			return lj
+		}
+		a, _ := runs[i].span()
+		b, _ := runs[j].span()
+		return a < b
+	})
+	lanes, misses := assignLanes(runs)
+	if misses == 0 {
+		return lanes
+	}
+	alt := append([]*run(nil), runs...)
+	first := sort.Search(len(alt), func(i int) bool { return alt[i].label != "" })
+	for i, j := first, len(alt)-1; i < j; i, j = i+1, j-1 {
+		alt[i], alt[j] = alt[j], alt[i]
+	}
+	if altLanes, altMisses := assignLanes(alt); altMisses < misses {
+		return altLanes
+	}
+	lanes, _ = assignLanes(runs)
+	return lanes
+}
+
+// assignLanes places runs in order, each on the lowest lane that fits. A run
+// for which no lane satisfies the vertical ordering takes the first lane free
+// of other runs, and counts as a miss.
+func assignLanes(runs []*run) (lanes, misses int) {
+	var placed []*run
+	fits := func(rn *run, lane int, ordered bool) bool {
+		lo, hi := rn.span()
+		for _, o := range placed {
+			olo, ohi := o.span()
+			if o.lane == lane && lo <= ohi+1 && olo <= hi+1 {
+				return false
+			}
+			if !ordered {
+				continue
+			}
+			// o's piece above its lane must end before rn's piece below
+			// starts at a shared column, and vice versa.
+			if sharesColumn(o.above, rn.below) && o.lane >= lane {
+				return false
+			}
+			if sharesColumn(o.below, rn.above) && o.lane <= lane {
+				return false
+			}
+			// Vertical pieces may cross other runs, but not their labels.
+			if crossesLabel(o.crossing(lane), rn) || crossesLabel(rn.crossingFrom(lane, o.lane), o) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, rn := range runs {
+		rn.lane = -1
+		for lane := 0; lane <= len(runs); lane++ {
+			if fits(rn, lane, true) {
+				rn.lane = lane
+				break
+			}
+		}
+		if rn.lane < 0 {
+			misses++
+		}
+		for lane := 0; rn.lane < 0; lane++ {
+			if fits(rn, lane, false) {
+				rn.lane = lane
+			}
+		}
+		placed = append(placed, rn)
+		lanes = max(lanes, rn.lane+1)
+	}
+	return lanes, misses
+}
+
+// crossing lists the columns where the run's vertical pieces pass through
+// lane, given the run sits on its own lane.
+func (rn *run) crossing(lane int) []int {
+	return rn.crossingFrom(rn.lane, lane)
+}
+
+// crossingFrom lists the columns where the run's vertical pieces would pass
+// through lane if the run sat on own.
+func (rn *run) crossingFrom(own, lane int) []int {
+	switch {
+	case lane < own:
+		return rn.above
+	case lane > own:
+		return rn.below
+	}
+	return nil
+}
+
+// crossesLabel reports whether any of the columns runs through rn's label
+// or the blank cell either side of it.
+func crossesLabel(cols []int, rn *run) bool {
+	if rn.label == "" {
+		return false
+	}
+	lw := runewidth.StringWidth(rn.label)
+	for _, x := range cols {
+		if x >= rn.lx-1 && x <= rn.lx+lw {
+			return true
+		}
+	}
+	return false
+}
+
+func sharesColumn(a, b []int) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// placeY stacks gutters and ranks top to bottom. States in a rank share their
+// top row.
+func (l *regionLayout) placeY() {
+	y := 0
+	for k := -1; k < len(l.ranks); k++ {
+		gt := l.gutters[k]
+		gt.top = y
+		y += gt.height(k >= 0 && k < len(l.ranks)-1)
+		if k+1 < len(l.ranks) {
+			h := 0
+			for _, it := range l.ranks[k+1] {
+				it.y = y
+				h = max(h, it.b.h)
+			}
+			y += h
+		}
+	}
+}
+
+// draw stamps the states, then overlays every route's line, arrowhead and
+// label.
+func (l *regionLayout) draw() []string {
+	c := &diagram.Canvas{}
+	for _, it := range l.items {
+		c.Stamp(it.x, it.y, it.b.lines)
+	}
+
+	o := overlay{}
+	for _, rt := range l.routes {
+		o.polyline(l.path(rt))
+	}
+	o.composite(c, l.r.g)
+
+	g := l.r.g
+	for _, rt := range l.routes {
+		if rt.from.b.box {
+			c.Set(rt.sx, rt.from.y+rt.from.b.nh-1, g.teeD)
+		}
+		c.Set(rt.tx, l.gutters[rt.to.rank-1].arrowY(), g.arrow)
+		for _, rn := range rt.runs {
+			if rn.label != "" {
+				writeLabel(c, o, rn.label, rn.lx, l.gutters[rn.gutter].laneY(rn.lane))
+			}
+		}
+	}
+	return c.Lines()
+}
+
+// path lists the corners of a route's orthogonal line, from the source's
+// bottom border to the arrowhead row above the target.
+func (l *regionLayout) path(rt *route) [][2]int {
+	sy := rt.from.y + rt.from.b.nh - 1
+	arrowY := l.gutters[rt.to.rank-1].arrowY()
+	if rt.adjacent {
+		if len(rt.runs) == 0 {
+			return [][2]int{{rt.sx, sy}, {rt.tx, arrowY}}
+		}
+		y := l.gutters[rt.runs[0].gutter].laneY(rt.runs[0].lane)
+		return [][2]int{{rt.sx, sy}, {rt.sx, y}, {rt.tx, y}, {rt.tx, arrowY}}
+	}
+	y1 := l.gutters[rt.runs[0].gutter].laneY(rt.runs[0].lane)
+	y2 := l.gutters[rt.runs[1].gutter].laneY(rt.runs[1].lane)
+	return [][2]int{{rt.sx, sy}, {rt.sx, y1}, {rt.trunk, y1}, {rt.trunk, y2}, {rt.tx, y2}, {rt.tx, arrowY}}
+}
+
+// dir bits mark which neighbours a line cell links to; the glyph for a cell
+// is chosen from the union of its bits, so crossings become ┼ and corners
+// └┐┌┘.
+const (
+	dN uint8 = 1 << iota
+	dS
+	dE
+	dW
+)
+
+// overlay accumulates line bits per cell, kept off the canvas so junction
+// glyphs are computed once every route is known.
+type overlay map[[2]int]uint8
+
+// polyline sets line bits along an axis-aligned poly-line through pts.
+func (o overlay) polyline(pts [][2]int) {
+	for i := 0; i+1 < len(pts); i++ {
+		a, b := pts[i], pts[i+1]
+		dx, dy := sign(b[0]-a[0]), sign(b[1]-a[1])
+		x, y := a[0], a[1]
+		for x != b[0] || y != b[1] {
+			o[[2]int{x, y}] |= bit(dx, dy)
+			x += dx
+			y += dy
+			o[[2]int{x, y}] |= bit(-dx, -dy)
+		}
+	}
+}
+
+// bit is the direction bit for a unit step.
+func bit(dx, dy int) uint8 {
+	switch {
+	case dx > 0:
+		return dE
+	case dx < 0:
+		return dW
+	case dy > 0:
+		return dS
+	default:
+		return dN
+	}
+}
+
+// composite draws the overlay onto blank canvas cells, leaving states intact.
+func (o overlay) composite(c *diagram.Canvas, g glyphs) {
+	for p, bits := range o {
+		if c.At(p[0], p[1]) == ' ' {
+			c.Set(p[0], p[1], glyphFor(bits, g))
+		}
+	}
+}
+
+// glyphFor maps a set of direction bits to a box-drawing rune.
+func glyphFor(bits uint8, g glyphs) rune {
+	switch bits {
+	case dN | dE:
+		return g.bl
+	case dN | dW:
+		return g.br
+	case dS | dE:
+		return g.tl
+	case dS | dW:
+		return g.tr
+	case dN | dS | dE:
+		return g.teeR
+	case dN | dS | dW:
+		return g.teeL
+	case dN | dE | dW:
+		return g.teeU
+	case dS | dE | dW:
+		return g.teeD
+	case dN | dS | dE | dW:
+		return g.cross
+	case dE, dW, dE | dW:
+		return g.h
+	default: // dN, dS, dN | dS
+		return g.v
+	}
+}
+
+// writeLabel writes a label from x, advancing by display width. A space over
+// a crossing vertical line is not stamped, so word gaps never punch holes in
+// other routes.
+func writeLabel(c *diagram.Canvas, o overlay, s string, x, y int) {
+	for _, r := range s {
+		if r != ' ' || o[[2]int{x, y}]&(dN|dS) == 0 {
+			c.Set(x, y, r)
+			if runewidth.RuneWidth(r) == 2 {
+				c.Set(x+1, y, 0)
+			}
+		}
+		x += runewidth.RuneWidth(r)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	if n > 0 {
+		return 1
+	}
+	if n < 0 {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,315 @@
+// Package state parses and renders mermaid state diagrams (stateDiagram and
+// stateDiagram-v2) as ASCII: states are boxes laid out in top-down ranks and
+// transitions are routed orthogonally between them.
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const (
+	stateKeyword   = "stateDiagram"
+	stateKeywordV2 = "stateDiagram-v2"
+)
+
+// Kind distinguishes regular states from the pseudostates mermaid draws with
+// their own glyphs.
+type Kind int
+
+const (
+	KindState  Kind = iota // a regular (possibly composite) state
+	KindStart              // [*] used as a transition source
+	KindEnd                // [*] used as a transition target
+	KindChoice             // state X <<choice>>
+	KindFork               // state X <<fork>>
+	KindJoin               // state X <<join>>
+)
+
+// State is one node of the diagram. Label is the name shown in the box (the
+// id, unless `state "Label" as ID` gave it another one); Descriptions are
+// the `ID : text` lines attached to it.
+type State struct {
+	ID           string
+	Label        string
+	Descriptions []string
+	Kind         Kind
+	// Regions is the body of a composite state. A single region is an
+	// ordinary nested diagram; several are concurrent regions (separated by
+	// `--` in the source).
+	Regions []*Region
+	region  *Region // the region the state was declared in
+}
+
+// IsComposite reports whether the state has a nested body.
+func (s *State) IsComposite() bool { return len(s.Regions) > 0 }
+
+// Region is a list of sibling states: the top level of the diagram or one
+// (concurrent) region of a composite state. Every region has its own [*]
+// start and end pseudostates.
+type Region struct {
+	States []*State
+	Parent *State // nil for the top level
+	start  *State
+	end    *State
+}
+
+// Transition is an arrow between two states with an optional label.
+type Transition struct {
+	From, To *State
+	Label    string
+}
+
+// NoteSide is the side of its state a note is drawn on.
+type NoteSide int
+
+const (
+	NoteRight NoteSide = iota // note right of X
+	NoteLeft                  // note left of X
+)
+
+// Note is a text box attached beside a state.
+type Note struct {
+	State *State
+	Side  NoteSide
+	Lines []string
+}
+
+// StateDiagram is a parsed state diagram. States are kept in first-seen order
+// within their region; a transition referencing an undeclared state creates
+// it in the region the transition appears in.
+type StateDiagram struct {
+	Root        *Region
+	Transitions []*Transition
+	Notes       []*Note
+	byID        map[string]*State
+}
+
+var (
+	// headerRegex matches the diagram declaration, with or without -v2.
+	headerRegex = regexp.MustCompile(`^stateDiagram(?:-v2)?\s*$`)
+
+	// directionRegex matches the layout directive; ranks are always laid out
+	// top-down, so it is accepted and ignored.
+	directionRegex = regexp.MustCompile(`(?i)^direction\s+(TB|TD|BT|LR|RL)$`)
+
+	// ignoredLineRegex matches styling and accessibility statements that
+	// carry no ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(classDef|class|style|accTitle|accDescr|hide empty description)\b`)
+
+	// noteInlineRegex matches a single-line note: `note right of X : text`.
+	noteInlineRegex = regexp.MustCompile(`(?i)^note\s+(left|right)\s+of\s+([^\s:]+)\s*:\s*(.*)$`)
+
+	// noteBlockRegex opens a multi-line note closed by `end note`.
+	noteBlockRegex = regexp.MustCompile(`(?i)^note\s+(left|right)\s+of\s+(\S+)\s*$`)
+
+	// noteEndRegex closes a multi-line note.
+	noteEndRegex = regexp.MustCompile(`(?i)^end\s+note$`)
+
+	// stateAliasRegex matches `state "Label" as ID`, optionally opening a
+	// composite body with a trailing `{`.
+	stateAliasRegex = regexp.MustCompile(`^state\s+"([^"]*)"\s+as\s+([^\s{]+)\s*(\{)?$`)
+
+	// stateSpecialRegex matches pseudostate declarations: `state X <<choice>>`.
+	stateSpecialRegex = regexp.MustCompile(`^state\s+([^\s{]+)\s*<<(choice|fork|join)>>$`)
+
+	// stateDeclRegex matches `state ID` and the composite opener `state ID {`.
+	stateDeclRegex = regexp.MustCompile(`^state\s+([^\s{"]+)\s*(\{)?$`)
+
+	// transitionRegex matches `A --> B` with an optional `: label`.
+	transitionRegex = regexp.MustCompile(`^(\S+)\s*-->\s*([^\s:]+)\s*(?::\s*(.*))?$`)
+
+	// descriptionRegex matches `ID : description`.
+	descriptionRegex = regexp.MustCompile(`^([^\s:]+)\s*:\s*(.*)$`)
+
+	// classShorthandRegex matches a `:::class` decoration on a state id.
+	classShorthandRegex = regexp.MustCompile(`:::[\w-]+`)
+)
+
+// IsStateDiagram reports whether the input's first meaningful line declares a
+// state diagram.
+func IsStateDiagram(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// Parse parses a stateDiagram into regions, transitions and notes.
+func Parse(input string) (*StateDiagram, error) {
+	if !IsStateDiagram(input) {
+		return nil, fmt.Errorf("expected %q or %q keyword", stateKeyword, stateKeywordV2)
+	}
+	// Comments are stripped in place so error messages keep the caller's line
+	// numbers.
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	d := &StateDiagram{Root: &Region{}, byID: map[string]*State{}}
+	// stack holds the regions currently open; the innermost is last.
+	stack := []*Region{d.Root}
+
+	seenKeyword := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if !seenKeyword { // the keyword line itself (verified above)
+			seenKeyword = true
+			continue
+		}
+		cur := stack[len(stack)-1]
+		line = strings.TrimSpace(classShorthandRegex.ReplaceAllString(line, ""))
+
+		switch {
+		case directionRegex.MatchString(line), ignoredLineRegex.MatchString(line):
+			continue
+
+		case line == "}":
+			if len(stack) == 1 {
+				return nil, diagram.LineErrorf(i+1, "unexpected '}'")
+			}
+			stack = stack[:len(stack)-1]
+			continue
+
+		case line == "--":
+			if cur.Parent == nil {
+				return nil, diagram.LineErrorf(i+1, "'--' is only allowed inside a composite state")
+			}
+			region := &Region{Parent: cur.Parent}
+			cur.Parent.Regions = append(cur.Parent.Regions, region)
+			stack[len(stack)-1] = region
+			continue
+		}
+
+		if m := noteInlineRegex.FindStringSubmatch(line); m != nil {
+			d.addNote(cur, m[1], m[2], []string{strings.TrimSpace(m[3])})
+			continue
+		}
+		if m := noteBlockRegex.FindStringSubmatch(line); m != nil {
+			var text []string
+			start := i
+			for i++; i < len(lines) && !noteEndRegex.MatchString(strings.TrimSpace(lines[i])); i++ {
+				text = append(text, strings.TrimSpace(lines[i]))
+			}
+			if i >= len(lines) {
+				return nil, diagram.LineErrorf(start+1, "unclosed note (missing 'end note')")
+			}
+			d.addNote(cur, m[1], m[2], text)
+			continue
+		}
+
+		if m := stateSpecialRegex.FindStringSubmatch(line); m != nil {
+			s := d.state(cur, m[1])
+			switch m[2] {
+			case "choice":
+				s.Kind = KindChoice
+			case "fork":
+				s.Kind = KindFork
+			default:
+				s.Kind = KindJoin
+			}
+			continue
+		}
+		if m := stateAliasRegex.FindStringSubmatch(line); m != nil {
+			s := d.state(cur, m[2])
+			s.Label = m[1]
+			if m[3] != "" {
+				stack = append(stack, s.openRegion())
+			}
+			continue
+		}
+		if m := stateDeclRegex.FindStringSubmatch(line); m != nil {
+			s := d.state(cur, m[1])
+			if m[2] != "" {
+				stack = append(stack, s.openRegion())
+			}
+			continue
+		}
+
+		if m := transitionRegex.FindStringSubmatch(line); m != nil {
+			from := d.endpoint(cur, m[1], true)
+			to := d.endpoint(cur, m[2], false)
+			d.Transitions = append(d.Transitions, &Transition{From: from, To: to, Label: strings.TrimSpace(m[3])})
+			continue
+		}
+		if m := descriptionRegex.FindStringSubmatch(line); m != nil && m[1] != "[*]" {
+			s := d.state(cur, m[1])
+			if desc := strings.TrimSpace(m[2]); desc != "" {
+				s.Descriptions = append(s.Descriptions, desc)
+			}
+			continue
+		}
+		// A bare id declares a state.
+		if !strings.ContainsAny(line, " \t{}") {
+			d.state(cur, line)
+			continue
+		}
+
+		return nil, diagram.LineErrorf(i+1, "invalid syntax: %q", line)
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed composite state %q (missing '}')", stack[len(stack)-1].Parent.ID)
+	}
+	return d, nil
+}
+
+// openRegion starts a body for a composite state. A state whose body is
+// split over several `state X { }` blocks keeps adding to the first region.
+func (s *State) openRegion() *Region {
+	if len(s.Regions) == 0 {
+		s.Regions = append(s.Regions, &Region{Parent: s})
+	}
+	return s.Regions[0]
+}
+
+// state returns the state with the given id, creating it in region r if it
+// hasn't been seen anywhere yet.
+func (d *StateDiagram) state(r *Region, id string) *State {
+	if s, ok := d.byID[id]; ok {
+		return s
+	}
+	s := &State{ID: id, Label: id, region: r}
+	d.byID[id] = s
+	r.States = append(r.States, s)
+	return s
+}
+
+// endpoint resolves a transition endpoint. [*] is the region's start
+// pseudostate when it is the source and its end pseudostate when it is the
+// target.
+func (d *StateDiagram) endpoint(r *Region, id string, source bool) *State {
+	if id != "[*]" {
+		return d.state(r, id)
+	}
+	if source {
+		if r.start == nil {
+			r.start = &State{ID: "[*]", Kind: KindStart, region: r}
+			r.States = append([]*State{r.start}, r.States...)
+		}
+		return r.start
+	}
+	if r.end == nil {
+		r.end = &State{ID: "[*]", Kind: KindEnd, region: r}
+		r.States = append(r.States, r.end)
+	}
+	return r.end
+}
+
+func (d *StateDiagram) addNote(r *Region, side, id string, text []string) {
+	n := &Note{State: d.state(r, id), Side: NoteRight, Lines: text}
+	if strings.EqualFold(side, "left") {
+		n.Side = NoteLeft
+	}
+	d.Notes = append(d.Notes, n)
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsStateDiagram(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"stateDiagram\n [*] --> A", true},
+		{"stateDiagram-v2\n [*] --> A", true},
+		{"%% leading comment\nstateDiagram-v2", true},
+		{"stateDiagramFoo\n A", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsStateDiagram(c.in); got != c.want {
+			t.Errorf("IsStateDiagram(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseStartAndEndArePerRegion(t *testing.T) {
+	d, err := Parse(`stateDiagram-v2
+    [*] --> A
+    state A {
+        [*] --> B
+        B --> [*]
+    }
+    A --> [*]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(d.Root.States); got != 3 {
+		t.Fatalf("root has %d states, want 3 ([*], A, [*])", got)
+	}
+	if d.Root.States[0].Kind != KindStart || d.Root.States[2].Kind != KindEnd {
+		t.Errorf("root start/end kinds wrong: %v, %v", d.Root.States[0].Kind, d.Root.States[2].Kind)
+	}
+	a := d.byID["A"]
+	if !a.IsComposite() || len(a.Regions[0].States) != 3 {
+		t.Fatalf("A should be composite with [*], B, [*]; got %+v", a.Regions)
+	}
+	inner := d.Transitions[1]
+	if inner.From == d.Transitions[0].From {
+		t.Error("inner [*] must not share the root start state")
+	}
+}
+
+func TestParseTransitionLabels(t *testing.T) {
+	d, err := Parse("stateDiagram-v2\n A --> B : go now\n B-->C\n C --> A:back")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"go now", "", "back"}
+	for i, tr := range d.Transitions {
+		if tr.Label != want[i] {
+			t.Errorf("transition %d label = %q, want %q", i, tr.Label, want[i])
+		}
+	}
+}
+
+func TestParseDeclarations(t *testing.T) {
+	d, err := Parse(`stateDiagram-v2
+    state "Long name" as L
+    L : first
+    L : second
+    state c <<choice>>
+    state f <<fork>>
+    state j <<join>>
+    Plain`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := d.byID["L"]
+	if l.Label != "Long name" || strings.Join(l.Descriptions, "|") != "first|second" {
+		t.Errorf("alias/descriptions wrong: %+v", l)
+	}
+	for id, kind := range map[string]Kind{"c": KindChoice, "f": KindFork, "j": KindJoin, "Plain": KindState} {
+		if got := d.byID[id].Kind; got != kind {
+			t.Errorf("%s kind = %v, want %v", id, got, kind)
+		}
+	}
+}
+
+func TestParseConcurrentRegions(t *testing.T) {
+	d, err := Parse(`stateDiagram-v2
+    state Active {
+        [*] --> A
+        --
+        [*] --> B
+        --
+        [*] --> C
+    }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(d.byID["Active"].Regions); got != 3 {
+		t.Errorf("Active has %d regions, want 3", got)
+	}
+}
+
+func TestParseNotes(t *testing.T) {
+	d, err := Parse(`stateDiagram-v2
+    note right of A : inline
+    note left of A
+        line one
+        line two
+    end note`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Notes) != 2 {
+		t.Fatalf("want 2 notes, got %d", len(d.Notes))
+	}
+	if d.Notes[0].Side != NoteRight || d.Notes[0].Lines[0] != "inline" {
+		t.Errorf("inline note wrong: %+v", d.Notes[0])
+	}
+	if d.Notes[1].Side != NoteLeft || strings.Join(d.Notes[1].Lines, "|") != "line one|line two" {
+		t.Errorf("block note wrong: %+v", d.Notes[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		name, in, want string
+	}{
+		{"missing keyword", "graph TD\n A-->B", "expected"},
+		{"stray brace", "stateDiagram-v2\n A --> B\n }", "line 3"},
+		{"divider outside composite", "stateDiagram-v2\n --", "line 2"},
+		{"unclosed composite", "stateDiagram-v2\n state A {\n B", "unclosed composite"},
+		{"unclosed note", "stateDiagram-v2\n note left of A\n text", "line 2: unclosed note"},
+		{"invalid syntax", "stateDiagram-v2\n A -> B", "line 2: invalid syntax"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse(c.in)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got error %v, want it to contain %q", err, c.want)
+			}
+		})
+	}
+}
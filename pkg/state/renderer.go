@@ -0,0 +1,285 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a diagram is drawn with (Unicode by default,
+// ASCII when useAscii).
+type glyphs struct {
+	h, v, tl, tr, bl, br, teeD, teeU, teeL, teeR, cross rune
+	rtl, rtr, rbl, rbr                                  rune // rounded corners of state boxes
+	dash                                                rune // region dividers and note connectors
+	arrow                                               rune // arrowhead entering a state from above
+	bar                                                 rune // fork/join bar
+	start, end, choice                                  string
+}
+
+var unicodeGlyphs = glyphs{
+	h: '─', v: '│', tl: '┌', tr: '┐', bl: '└', br: '┘',
+	teeD: '┬', teeU: '┴', teeL: '┤', teeR: '├', cross: '┼',
+	rtl: '╭', rtr: '╮', rbl: '╰', rbr: '╯',
+	dash: '┄', arrow: '▼', bar: '━',
+	start: "●", end: "◉", choice: "◇",
+}
+
+var asciiGlyphs = glyphs{
+	h: '-', v: '|', tl: '+', tr: '+', bl: '+', br: '+',
+	teeD: '+', teeU: '+', teeL: '+', teeR: '+', cross: '+',
+	rtl: '+', rtr: '+', rbl: '+', rbr: '+',
+	dash: '.', arrow: 'v', bar: '=',
+	start: "*", end: "(*)", choice: "<>",
+}
+
+// block is a pre-rendered state, positioned as a unit by the layout. The
+// state itself occupies columns [nx, nx+nw) and rows [0, nh) of the block;
+// the rest is taken up by attached notes.
+type block struct {
+	lines []string
+	w, h  int
+	nx    int
+	nw    int
+	nh    int // height of the state itself, excluding taller notes
+	// attach is the column (relative to nx) every transition of a
+	// single-glyph pseudostate connects to; -1 for states whose transitions
+	// each get their own slot along the face.
+	attach int
+	// box is true when the face rows are box borders, which get a tee where
+	// a transition leaves.
+	box bool
+}
+
+// Render draws the diagram top-down. Composite states are rendered
+// recursively as framed sub-diagrams.
+func Render(d *StateDiagram, config *diagram.Config) (string, error) {
+	if config == nil {
+		config = diagram.DefaultConfig()
+	}
+	g := unicodeGlyphs
+	if config.UseAscii {
+		g = asciiGlyphs
+	}
+	if d == nil || d.Root == nil || len(d.Root.States) == 0 {
+		return "", nil
+	}
+	r := &renderer{d: d, g: g, notes: map[*State][]*Note{}}
+	for _, n := range d.Notes {
+		r.notes[n.State] = append(r.notes[n.State], n)
+	}
+	r.assignTransitions()
+
+	c := &diagram.Canvas{}
+	c.Stamp(0, 0, r.renderRegion(d.Root))
+	return c.String(), nil
+}
+
+// renderer carries per-diagram state through the recursive region layout.
+type renderer struct {
+	d     *StateDiagram
+	g     glyphs
+	notes map[*State][]*Note
+	// byRegion holds each transition under the innermost region containing
+	// both endpoints, with the endpoints lifted to that region's states.
+	byRegion map[*Region][]*edge
+}
+
+// edge is a transition resolved to two states of the same region.
+type edge struct {
+	t        *Transition
+	from, to *State
+}
+
+// assignTransitions files every transition under the region it is drawn in.
+// A transition into or out of a composite state's body from outside is drawn
+// to the composite's frame, since the frame is what the outer layout sees.
+func (r *renderer) assignTransitions() {
+	r.byRegion = map[*Region][]*edge{}
+	for _, t := range r.d.Transitions {
+		fromPath, toPath := ancestry(t.From), ancestry(t.To)
+		i := 0
+		for i+1 < len(fromPath) && i+1 < len(toPath) && fromPath[i+1].region == toPath[i+1].region {
+			i++
+		}
+		region := fromPath[i].region
+		r.byRegion[region] = append(r.byRegion[region], &edge{t: t, from: fromPath[i], to: toPath[i]})
+	}
+}
+
+// ancestry lists the states enclosing s, outermost first, ending with s.
+func ancestry(s *State) []*State {
+	path := []*State{s}
+	for p := s.region.Parent; p != nil; p = p.region.Parent {
+		path = append([]*State{p}, path...)
+	}
+	return path
+}
+
+// renderState draws a single state as a block. minW is the least width of the
+// state's face, so every transition touching it gets its own attach column.
+func (r *renderer) renderState(s *State, minW int) *block {
+	g := r.g
+	var lines []string
+	b := &block{attach: -1}
+	switch s.Kind {
+	case KindStart:
+		lines, b.attach = []string{g.start}, runewidth.StringWidth(g.start)/2
+	case KindEnd:
+		lines, b.attach = []string{g.end}, runewidth.StringWidth(g.end)/2
+	case KindChoice:
+		lines, b.attach = []string{g.choice}, 0
+	case KindFork, KindJoin:
+		lines = []string{strings.Repeat(string(g.bar), max(5, minW))}
+	default:
+		if s.IsComposite() {
+			lines = r.renderComposite(s, minW)
+		} else {
+			lines = r.renderBox(s, minW)
+		}
+		b.box = true
+	}
+	b.nw, b.nh = blockWidth(lines), len(lines)
+	r.attachNotes(s, b, lines)
+	return b
+}
+
+// boxText returns the lines shown in a simple state's box: the title and,
+// below a divider, its descriptions. A lone description on an unlabelled
+// state replaces the title, as mermaid draws it.
+func boxText(s *State) (title string, body []string) {
+	if len(s.Descriptions) == 1 && s.Label == s.ID {
+		return s.Descriptions[0], nil
+	}
+	return s.Label, s.Descriptions
+}
+
+// renderBox draws a simple state as a rounded box.
+func (r *renderer) renderBox(s *State, minW int) []string {
+	g := r.g
+	title, body := boxText(s)
+	inner := max(runewidth.StringWidth(title)+2, minW-2)
+	for _, l := range body {
+		inner = max(inner, runewidth.StringWidth(l)+2)
+	}
+	lines := []string{
+		string(g.rtl) + strings.Repeat(string(g.h), inner) + string(g.rtr),
+		string(g.v) + center(title, inner) + string(g.v),
+	}
+	if len(body) > 0 {
+		lines = append(lines, string(g.teeR)+strings.Repeat(string(g.h), inner)+string(g.teeL))
+		for _, l := range body {
+			lines = append(lines, string(g.v)+" "+padRight(l, inner-1)+string(g.v))
+		}
+	}
+	return append(lines, string(g.rbl)+strings.Repeat(string(g.h), inner)+string(g.rbr))
+}
+
+// renderComposite draws a composite state as a titled frame around its
+// rendered regions, separated by dashed dividers when they are concurrent.
+func (r *renderer) renderComposite(s *State, minW int) []string {
+	g := r.g
+	title, _ := boxText(s)
+	var bodies [][]string
+	inner := max(runewidth.StringWidth(title)+2, minW-2)
+	for _, region := range s.Regions {
+		body := r.renderRegion(region)
+		bodies = append(bodies, body)
+		inner = max(inner, blockWidth(body)+4)
+	}
+
+	lines := []string{
+		string(g.rtl) + strings.Repeat(string(g.h), inner) + string(g.rtr),
+		string(g.v) + " " + padRight(title, inner-1) + string(g.v),
+		string(g.teeR) + strings.Repeat(string(g.h), inner) + string(g.teeL),
+	}
+	blank := string(g.v) + strings.Repeat(" ", inner) + string(g.v)
+	for i, body := range bodies {
+		if i > 0 {
+			lines = append(lines, string(g.v)+strings.Repeat(string(g.dash), inner)+string(g.v))
+		}
+		lines = append(lines, blank)
+		left := (inner - blockWidth(body)) / 2
+		for _, l := range body {
+			lines = append(lines, string(g.v)+strings.Repeat(" ", left)+padRight(l, inner-left)+string(g.v))
+		}
+		lines = append(lines, blank)
+	}
+	return append(lines, string(g.rbl)+strings.Repeat(string(g.h), inner)+string(g.rbr))
+}
+
+// attachNotes places the state's notes beside it, joined by a short dashed
+// connector, and records where the state sits within the combined block.
+func (r *renderer) attachNotes(s *State, b *block, lines []string) {
+	c := &diagram.Canvas{}
+	x := 0
+	connectorY := min(1, len(lines)-1)
+	for _, n := range r.notes[s] {
+		if n.Side != NoteLeft {
+			continue
+		}
+		note := r.renderNote(n)
+		c.Stamp(x, 0, note)
+		x += blockWidth(note)
+		c.Set(x, connectorY, r.g.dash)
+		c.Set(x+1, connectorY, r.g.dash)
+		x += 2
+	}
+	b.nx = x
+	c.Stamp(x, 0, lines)
+	x += b.nw
+	for _, n := range r.notes[s] {
+		if n.Side != NoteRight {
+			continue
+		}
+		c.Set(x, connectorY, r.g.dash)
+		c.Set(x+1, connectorY, r.g.dash)
+		x += 2
+		note := r.renderNote(n)
+		c.Stamp(x, 0, note)
+		x += blockWidth(note)
+	}
+	b.lines = c.Lines()
+	b.w = x
+	b.h = len(b.lines)
+}
+
+// renderNote draws a note as a square-cornered box of text.
+func (r *renderer) renderNote(n *Note) []string {
+	g := r.g
+	inner := 0
+	for _, l := range n.Lines {
+		inner = max(inner, runewidth.StringWidth(l)+2)
+	}
+	lines := []string{string(g.tl) + strings.Repeat(string(g.h), inner) + string(g.tr)}
+	for _, l := range n.Lines {
+		lines = append(lines, string(g.v)+" "+padRight(l, inner-1)+string(g.v))
+	}
+	return append(lines, string(g.bl)+strings.Repeat(string(g.h), inner)+string(g.br))
+}
+
+// center pads s with spaces on both sides to width w.
+func center(s string, w int) string {
+	pad := w - runewidth.StringWidth(s)
+	if pad <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+}
+
+// padRight pads s with trailing spaces to width w.
+func padRight(s string, w int) string {
+	if pad := w - runewidth.StringWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+func blockWidth(lines []string) int {
+	w := 0
+	for _, l := range lines {
+		w = max(w, runewidth.StringWidth(l))
+	}
+	return w
+}
@@ -0,0 +1,32 @@
+package timeline
+
+import (
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram/testutil"
+)
+
+// renderGolden parses a golden file's mermaid and renders it with the Unicode
+// or ASCII charset.
+func renderGolden(useAscii bool) func(string) (string, error) {
+	return func(mermaid string) (string, error) {
+		p, err := Parse(mermaid)
+		if err != nil {
+			return "", err
+		}
+		config := diagram.DefaultConfig()
+		config.UseAscii = useAscii
+		return Render(p, config)
+	}
+}
+
+// TestTimelineRendering tests all timeline golden files with Unicode charset.
+func TestTimelineRendering(t *testing.T) {
+	testutil.RunGoldenDir(t, "timeline", renderGolden(false))
+}
+
+// TestTimelineRendering_ASCII tests timeline golden files with ASCII charset.
+func TestTimelineRendering_ASCII(t *testing.T) {
+	testutil.RunGoldenDir(t, "timeline-ascii", renderGolden(true))
+}
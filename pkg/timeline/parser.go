@@ -0,0 +1,125 @@
+// Package timeline parses mermaid timelines and renders them as ASCII: a
+// horizontal axis with a marker per period, the period's events stacked in
+// boxes beneath it, and the periods wrapping onto further rows when they
+// don't fit the configured width.
+package timeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+)
+
+const timelineKeyword = "timeline"
+
+// Period is a point on the timeline, like a year, and what happened then.
+type Period struct {
+	Label  string
+	Events []string
+}
+
+// Section groups periods under a heading. Periods written before the first
+// section statement go in a section without a name.
+type Section struct {
+	Name    string
+	Periods []*Period
+}
+
+// Timeline is a parsed timeline.
+type Timeline struct {
+	Title    string
+	Sections []*Section
+}
+
+var (
+	// headerRegex matches the diagram declaration. Timelines may be declared
+	// left to right or top down, but are always drawn left to right.
+	headerRegex = regexp.MustCompile(`^timeline(?:\s+(LR|TD))?\s*$`)
+
+	// ignoredLineRegex matches accessibility statements, which carry no
+	// ASCII meaning.
+	ignoredLineRegex = regexp.MustCompile(`^(accTitle|accDescr)\b`)
+
+	// statementRegex matches the title and section statements.
+	statementRegex = regexp.MustCompile(`^(title|section)\s+(.+)$`)
+
+	// eventSeparatorRegex matches the colon before each event. A colon with
+	// no space after it, as in a time or a URL, is part of the text.
+	eventSeparatorRegex = regexp.MustCompile(`:(?:\s+|$)`)
+)
+
+// IsTimeline reports whether the input's first meaningful line declares a
+// timeline.
+func IsTimeline(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "%%") {
+			continue
+		}
+		return headerRegex.MatchString(t)
+	}
+	return false
+}
+
+// Parse parses a timeline. Each period is written as `period : event :
+// event`, and a line starting with a colon adds more events to the period
+// before it.
+func Parse(input string) (*Timeline, error) {
+	if !IsTimeline(input) {
+		return nil, fmt.Errorf("expected %q keyword", timelineKeyword)
+	}
+	lines := diagram.SplitLines(input)
+	for i, l := range lines {
+		lines[i] = diagram.StripComment(l)
+	}
+
+	tl := &Timeline{}
+	var section *Section
+	var period *Period
+	seenKeyword := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if !seenKeyword {
+			seenKeyword = true
+			continue
+		}
+		if ignoredLineRegex.MatchString(line) {
+			continue
+		}
+		if m := statementRegex.FindStringSubmatch(line); m != nil {
+			value := strings.TrimSpace(m[2])
+			switch m[1] {
+			case "title":
+				tl.Title = value
+			case "section":
+				section = &Section{Name: value}
+				tl.Sections = append(tl.Sections, section)
+				period = nil
+			}
+			continue
+		}
+
+		parts := eventSeparatorRegex.Split(line, -1)
+		if label := strings.TrimSpace(parts[0]); label != "" {
+			if section == nil {
+				section = &Section{}
+				tl.Sections = append(tl.Sections, section)
+			}
+			period = &Period{Label: label}
+			section.Periods = append(section.Periods, period)
+		} else if period == nil {
+			return nil, diagram.LineErrorf(i+1, "event %q comes before any period", line)
+		}
+		for _, event := range parts[1:] {
+			if event = strings.TrimSpace(event); event != "" {
+				period.Events = append(period.Events, event)
+			}
+		}
+	}
+	return tl, nil
+}
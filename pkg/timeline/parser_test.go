@@ -0,0 +1,102 @@
+package timeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+func TestIsTimeline(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"timeline\n 2024 : Launch", true},
+		{"timeline LR\n 2024", true},
+		{"%% leading comment\ntimeline", true},
+		{"timelines\n 2024", false},
+		{"graph TD\n A-->B", false},
+		{"", false},
+	} {
+		if got := IsTimeline(c.in); got != c.want {
+			t.Errorf("IsTimeline(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParsePeriodsAndEvents(t *testing.T) {
+	tl, err := Parse(`timeline
+    title History
+    accTitle: history
+    2001 : A
+    section Later
+    2002 : B : C
+         : D
+    2003
+    2004 : meet at 10:30 : see https://example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tl.Title != "History" {
+		t.Errorf("title = %q, want History", tl.Title)
+	}
+	var got []string
+	for _, s := range tl.Sections {
+		for _, p := range s.Periods {
+			got = append(got, s.Name+"/"+p.Label+": "+strings.Join(p.Events, ", "))
+		}
+	}
+	want := []string{
+		"/2001: A",
+		"Later/2002: B, C, D",
+		"Later/2003: ",
+		"Later/2004: meet at 10:30, see https://example.com",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("periods =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"timeline\n : orphan", "comes before any period"},
+		{"timeline\n section S\n : orphan", "comes before any period"},
+		{"graph TD\n A-->B", `expected "timeline" keyword`},
+	} {
+		_, err := Parse(c.in)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", c.in, err, c.want)
+		}
+	}
+}
+
+func TestRenderFitsWidth(t *testing.T) {
+	tl, err := Parse(`timeline
+    section A section name longer than the row is wide
+    2020 : An event far too long to fit on a single row of the timeline
+    2021 : Short
+    2022 : Supercalifragilisticexpialidocious`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := diagram.DefaultConfig()
+	config.TimelineWidth = 24
+	out, err := Render(tl, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if w := runewidth.StringWidth(line); w > config.TimelineWidth {
+			t.Errorf("line %q is %d columns wide, want at most %d:\n%s", line, w, config.TimelineWidth, out)
+		}
+	}
+	for _, word := range []string{"An event far", "Short", "Supercal"} {
+		if !strings.Contains(out, word) {
+			t.Errorf("output is missing %q:\n%s", word, out)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package timeline
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/AlexanderGrooff/mermaid-ascii/pkg/diagram"
+	"github.com/mattn/go-runewidth"
+)
+
+// glyphs is the character set a timeline is drawn with (Unicode by default,
+// ASCII when useAscii).
+type glyphs struct {
+	axis, marker, stem rune
+	// Event boxes: their corners, edges, and the joins where the line
+	// through a stack of boxes enters and leaves each of them.
+	topLeft, topRight, bottomLeft, bottomRight rune
+	horizontal, vertical, joinIn, joinOut      rune
+	// section is the line marking which periods a section spans.
+	section rune
+}
+
+var unicodeGlyphs = glyphs{
+	axis: '─', marker: '┬', stem: '│',
+	topLeft: '┌', topRight: '┐', bottomLeft: '└', bottomRight: '┘',
+	horizontal: '─', vertical: '│', joinIn: '┴', joinOut: '┬',
+	section: '─',
+}
+
+var asciiGlyphs = glyphs{
+	axis: '-', marker: '+', stem: '|',
+	topLeft: '+', topRight: '+', bottomLeft: '+', bottomRight: '+',
+	horizontal: '-', vertical: '|', joinIn: '+', joinOut: '+',
+	section: '-',
+}
+
+// Spacing between the columns periods are drawn in, and between an event's
+// text and the sides of its box.
+const (
+	columnGap  = 2
+	boxPadding = 1
+)
+
+// breakRegex matches a <br> line break in a period or event.
+var breakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// column is a period laid out: its label and events split into lines, and
+// the width of the column it's drawn in.
+type column struct {
+	section  *Section
+	label    []string
+	events   [][]string
+	boxWidth int
+	width    int
+}
+
+// Render draws the timeline: its title, then a row per run of periods that
+// fits in config.TimelineWidth columns. Each row has the names of the
+// sections its periods belong to, the periods' labels, the axis with a
+// marker under each of them, and a stack of event boxes hanging off each
+// marker. Text too wide for the row is wrapped.
+func Render(tl *Timeline, config *diagram.Config) (string, error) {
+	gl := unicodeGlyphs
+	if config.UseAscii {
+		gl = asciiGlyphs
+	}
+	width := config.TimelineWidth
+	if width <= 0 {
+		width = diagram.DefaultConfig().TimelineWidth
+	}
+
+	var columns []*column
+	named := false
+	for _, s := range tl.Sections {
+		named = named || s.Name != ""
+		first := len(columns)
+		for _, p := range s.Periods {
+			columns = append(columns, layoutPeriod(p, s, width))
+		}
+		// A section's periods are widened, up to the row's width, until its
+		// name fits over them.
+		if s.Name != "" && len(columns) > first {
+			span := (len(columns) - first - 1) * columnGap
+			for _, c := range columns[first:] {
+				span += c.width
+			}
+			last := columns[len(columns)-1]
+			last.width = max(last.width, min(width, last.width+runewidth.StringWidth(s.Name)-span))
+		}
+	}
+
+	c := &canvas{}
+	y := 0
+	totalWidth := 0
+	for len(columns) > 0 {
+		n, rowWidth := 1, columns[0].width
+		for n < len(columns) && rowWidth+columnGap+columns[n].width <= width {
+			rowWidth += columnGap + columns[n].width
+			n++
+		}
+		totalWidth = max(totalWidth, rowWidth)
+		if y > 0 {
+			y++
+		}
+		y = drawRow(c, columns[:n], y, named, gl)
+		columns = columns[n:]
+	}
+
+	var lines []string
+	if tl.Title != "" {
+		pad := max(0, (totalWidth-runewidth.StringWidth(tl.Title))/2)
+		lines = append(lines, strings.Repeat(" ", pad)+tl.Title, "")
+	}
+	for _, l := range c.lines {
+		lines = append(lines, strings.TrimRight(strings.ReplaceAll(string(l), "\x00", ""), " "))
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// layoutPeriod splits p's label and events into lines that fit in width
+// columns, boxes included, and works out how wide its column is.
+func layoutPeriod(p *Period, s *Section, width int) *column {
+	col := &column{section: s, label: wrap(p.Label, width), width: 1}
+	for _, l := range col.label {
+		col.width = max(col.width, runewidth.StringWidth(l))
+	}
+	for _, e := range p.Events {
+		lines := wrap(e, width-2-2*boxPadding)
+		for _, l := range lines {
+			col.boxWidth = max(col.boxWidth, runewidth.StringWidth(l)+2+2*boxPadding)
+		}
+		col.events = append(col.events, lines)
+	}
+	col.width = max(col.width, col.boxWidth)
+	return col
+}
+
+// drawRow draws a row of columns from line y down and returns the line
+// after it.
+func drawRow(c *canvas, columns []*column, y int, named bool, gl glyphs) int {
+	xs := make([]int, len(columns))
+	for i := 1; i < len(columns); i++ {
+		xs[i] = xs[i-1] + columns[i-1].width + columnGap
+	}
+	end := xs[len(xs)-1] + columns[len(columns)-1].width
+
+	if named {
+		for i := 0; i < len(columns); {
+			j := i + 1
+			for j < len(columns) && columns[j].section == columns[i].section {
+				j++
+			}
+			if name := columns[i].section.Name; name != "" {
+				span := xs[j-1] + columns[j-1].width - xs[i]
+				name = runewidth.Truncate(name, span, "…")
+				c.write(xs[i], y, name)
+				for x := xs[i] + runewidth.StringWidth(name) + 1; x < xs[i]+span; x++ {
+					c.set(x, y, gl.section)
+				}
+			}
+			i = j
+		}
+		y++
+	}
+
+	labelHeight := 0
+	for _, col := range columns {
+		labelHeight = max(labelHeight, len(col.label))
+	}
+	for i, col := range columns {
+		top := y + labelHeight - len(col.label)
+		for j, l := range col.label {
+			c.write(xs[i]+(col.width-runewidth.StringWidth(l))/2, top+j, l)
+		}
+	}
+	y += labelHeight
+
+	for x := 0; x < end; x++ {
+		c.set(x, y, gl.axis)
+	}
+	bottom := y + 1
+	for i, col := range columns {
+		mid := xs[i] + col.width/2
+		c.set(mid, y, gl.marker)
+		if len(col.events) == 0 {
+			continue
+		}
+		c.set(mid, y+1, gl.stem)
+		bottom = max(bottom, drawEvents(c, col, xs[i], mid, y+2, gl))
+	}
+	return bottom
+}
+
+// drawEvents draws col's events as a stack of boxes from line y down, each
+// joined to the one above it on column mid, and returns the line after
+// them.
+func drawEvents(c *canvas, col *column, x, mid, y int, gl glyphs) int {
+	left := x + (col.width-col.boxWidth)/2
+	right := left + col.boxWidth - 1
+	for i, lines := range col.events {
+		c.set(left, y, gl.topLeft)
+		for bx := left + 1; bx < right; bx++ {
+			c.set(bx, y, gl.horizontal)
+		}
+		c.set(right, y, gl.topRight)
+		c.set(mid, y, gl.joinIn)
+		y++
+
+		inner := col.boxWidth - 2
+		for _, l := range lines {
+			c.set(left, y, gl.vertical)
+			c.write(left+1+(inner-runewidth.StringWidth(l))/2, y, l)
+			c.set(right, y, gl.vertical)
+			y++
+		}
+
+		c.set(left, y, gl.bottomLeft)
+		for bx := left + 1; bx < right; bx++ {
+			c.set(bx, y, gl.horizontal)
+		}
+		c.set(right, y, gl.bottomRight)
+		if i < len(col.events)-1 {
+			c.set(mid, y, gl.joinOut)
+		}
+		y++
+	}
+	return y
+}
+
+// wrap splits text into lines at most width columns wide: at its <br>s, then
+// between words, and inside words too long for a line of their own.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, part := range breakRegex.Split(text, -1) {
+		line := ""
+		for _, word := range strings.Fields(part) {
+			for runewidth.StringWidth(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				head := runewidth.Truncate(word, width, "")
+				if head == "" {
+					head = string([]rune(word)[:1])
+				}
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			switch {
+			case word == "":
+			case line == "":
+				line = word
+			case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// canvas holds the lines drawn so far, a rune per column. A wide rune is
+// followed by NUL in the column it spills into.
+type canvas struct {
+	lines [][]rune
+}
+
+// write writes s on line y from column x on.
+func (c *canvas) write(x, y int, s string) {
+	for _, r := range s {
+		w := max(runewidth.RuneWidth(r), 1)
+		c.set(x, y, r)
+		for i := 1; i < w; i++ {
+			c.set(x+i, y, 0)
+		}
+		x += w
+	}
+}
+
+func (c *canvas) set(x, y int, r rune) {
+	for len(c.lines) <= y {
+		c.lines = append(c.lines, nil)
+	}
+	for len(c.lines[y]) <= x {
+		c.lines[y] = append(c.lines[y], ' ')
+	}
+	c.lines[y][x] = r
+}